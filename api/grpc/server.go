@@ -0,0 +1,223 @@
+// Package grpc serves the template, render, send, key and credential APIs over
+// gRPC. Messages are the JSON encoded request and response types of the REST
+// API, clients call with the "json" content subtype e.g
+//
+//	conn.Invoke(ctx, "/templatemanager.v1.RenderService/Render", req, &resp, grpc.CallContentSubtype("json"))
+//
+// and authenticate with the authorization (session token) or x-api-key metadata.
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"template-manager/internal/app/auth"
+	"template-manager/internal/app/credential"
+	"template-manager/internal/app/template"
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+)
+
+const (
+	authorizationHeader = "authorization"
+	apiKeyHeader        = "x-api-key"
+	ifMatchHeader       = "if-match"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec encodes messages as JSON so the shared types can be used without protobuf
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+type SessionManager interface {
+	Verify(ctx context.Context, token string) (*entity.Session, error)
+}
+
+type server struct {
+	logger        *slog.Logger
+	sessions      SessionManager
+	authApp       *auth.App
+	templateApp   *template.App
+	credentialApp *credential.Credential
+}
+
+func New(
+	logger *slog.Logger,
+	sessions SessionManager,
+	authApp *auth.App,
+	templateApp *template.App,
+	credentialApp *credential.Credential,
+) *server {
+	return &server{
+		logger:        logger,
+		sessions:      sessions,
+		authApp:       authApp,
+		templateApp:   templateApp,
+		credentialApp: credentialApp,
+	}
+}
+
+func (s *server) Listen(port string) error {
+	lis, err := net.Listen("tcp", port)
+	if err != nil {
+		return err
+	}
+
+	srv := gogrpc.NewServer(gogrpc.ChainUnaryInterceptor(s.recoverPanics, s.authenticate))
+	for _, desc := range services {
+		srv.RegisterService(desc, s)
+	}
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	s.logger.Info("grpc server listening", "addr", lis.Addr().String())
+	return srv.Serve(lis)
+}
+
+type (
+	accountIDKey struct{}
+	apiKeyKey    struct{}
+)
+
+// accountID returns the account the request was authenticated as
+func accountID(ctx context.Context) string {
+	id, _ := ctx.Value(accountIDKey{}).(string)
+	return id
+}
+
+// testKeyID returns the id of the test API key the request was authenticated
+// with, empty for live keys and sessions
+func testKeyID(ctx context.Context) string {
+	if key, ok := ctx.Value(apiKeyKey{}).(*entity.Key); ok && key.Test {
+		return key.ID
+	}
+	return ""
+}
+
+// readsDrafts reports whether the request may render and send versions that
+// aren't published, sessions always may
+func readsDrafts(ctx context.Context) bool {
+	key, ok := ctx.Value(apiKeyKey{}).(*entity.Key)
+	return !ok || key.Scopes.ReadsDrafts()
+}
+
+// authenticate resolves the account of the request from its session token or
+// API key, the health checks and the stats are public. API keys need the
+// scope of the method, see methodScopes.
+func (s *server) authenticate(ctx context.Context, req any, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (any, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if token := first(md, authorizationHeader); token != "" {
+		sess, err := s.sessions.Verify(ctx, token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid session")
+		}
+		return handler(context.WithValue(ctx, accountIDKey{}, sess.AccountID), req)
+	}
+	if secret := first(md, apiKeyHeader); secret != "" {
+		var ip string
+		if p, ok := peer.FromContext(ctx); ok {
+			ip, _, _ = net.SplitHostPort(p.Addr.String())
+		}
+		key, err := s.authApp.VerifyAccessKey(ctx, secret, ip)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		scope, ok := methodScopes[info.FullMethod]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "api keys can't call "+info.FullMethod)
+		}
+		if !key.Scopes.Has(scope) {
+			return nil, status.Error(codes.PermissionDenied, "api key is missing the "+string(scope)+" scope")
+		}
+		ctx = context.WithValue(ctx, accountIDKey{}, key.AccountID)
+		return handler(context.WithValue(ctx, apiKeyKey{}, key), req)
+	}
+	return nil, status.Error(codes.Unauthenticated, "missing authorization or x-api-key metadata")
+}
+
+// recoverPanics turns panics of handlers into internal errors instead of stopping the server
+func (s *server) recoverPanics(ctx context.Context, req any, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.ErrorContext(ctx, "grpc handler panicked", "method", info.FullMethod, "panic", r)
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+// statusError maps the errors of the app layer to gRPC status codes, the way
+// the REST API maps them to 400, 412 and 422
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var validationErrs validation.Errors
+	var stale *template.PreconditionFailedError
+	switch {
+	case errors.As(err, &validationErrs):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &stale):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, template.ErrUnverifiedAccount), errors.Is(err, entity.ErrDraftScope):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// stats is public, the usage of an account is only served by the REST API
+func (s *server) stats(context.Context) (*shared.ServerStats, error) {
+	return &shared.ServerStats{
+		GRPC:    true,
+		Version: shared.APIVersion,
+		Open:    false,
+		Cache:   s.templateApp.CacheStats(),
+	}, nil
+}
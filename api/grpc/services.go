@@ -0,0 +1,327 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"gorm.io/gorm"
+
+	"template-manager/internal/app/template"
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository/util"
+)
+
+const servicePrefix = "templatemanager.v1."
+
+// Empty is the message of calls without a request or response
+type Empty struct{}
+
+type ListKeysResponse struct {
+	Keys []entity.Key `json:"keys"`
+}
+
+type StaleKeysResponse struct {
+	Keys []shared.StaleKey `json:"keys"`
+}
+
+type ListCredentialsResponse struct {
+	Credentials []entity.Credential `json:"credentials"`
+}
+
+type CredentialRequest struct {
+	ID string `json:"id"`
+}
+
+// publicMethods don't need a session or API key
+var publicMethods = map[string]bool{
+	"/" + servicePrefix + "SystemService/Stats": true,
+	"/grpc.health.v1.Health/Check":              true,
+}
+
+// methodScopes are the scopes API keys need to call a method, the methods
+// missing from it can only be called with a session
+var methodScopes = map[string]entity.KeyScope{
+	"/" + servicePrefix + "TemplateService/GetUploadURL":       entity.KeyScopeTemplatesWrite,
+	"/" + servicePrefix + "TemplateService/CreateTemplate":     entity.KeyScopeTemplatesWrite,
+	"/" + servicePrefix + "TemplateService/GetTemplate":        entity.KeyScopeTemplatesRead,
+	"/" + servicePrefix + "TemplateService/ListTemplates":      entity.KeyScopeTemplatesRead,
+	"/" + servicePrefix + "TemplateService/UpdateTemplate":     entity.KeyScopeTemplatesWrite,
+	"/" + servicePrefix + "TemplateService/EditTemplate":       entity.KeyScopeTemplatesWrite,
+	"/" + servicePrefix + "TemplateService/DeleteTemplate":     entity.KeyScopeTemplatesWrite,
+	"/" + servicePrefix + "RenderService/Render":               entity.KeyScopeRender,
+	"/" + servicePrefix + "SendService/Send":                   entity.KeyScopeSend,
+	"/" + servicePrefix + "CredentialService/CreateCredential": entity.KeyScopeCredentialsManage,
+	"/" + servicePrefix + "CredentialService/ListCredentials":  entity.KeyScopeCredentialsManage,
+	"/" + servicePrefix + "CredentialService/GetCredential":    entity.KeyScopeCredentialsManage,
+	"/" + servicePrefix + "CredentialService/UpdateCredential": entity.KeyScopeCredentialsManage,
+	"/" + servicePrefix + "CredentialService/DeleteCredential": entity.KeyScopeCredentialsManage,
+}
+
+var services = []*gogrpc.ServiceDesc{
+	service("TemplateService",
+		method("GetUploadURL", (*server).getUploadURL),
+		method("CreateTemplate", (*server).createTemplate),
+		method("GetTemplate", (*server).getTemplate),
+		method("ListTemplates", (*server).listTemplates),
+		method("UpdateTemplate", (*server).updateTemplate),
+		method("EditTemplate", (*server).editTemplate),
+		method("DeleteTemplate", (*server).deleteTemplate),
+	),
+	service("RenderService",
+		method("Render", (*server).render),
+	),
+	service("SendService",
+		method("Send", (*server).send),
+	),
+	service("KeyService",
+		method("CreateKey", (*server).createKey),
+		method("ListKeys", (*server).listKeys),
+		method("DeleteKey", (*server).deleteKey),
+		method("RotateKey", (*server).rotateKey),
+		method("StaleKeys", (*server).staleKeys),
+	),
+	service("CredentialService",
+		method("CreateCredential", (*server).createCredential),
+		method("ListCredentials", (*server).listCredentials),
+		method("GetCredential", (*server).getCredential),
+		method("UpdateCredential", (*server).updateCredential),
+		method("DeleteCredential", (*server).deleteCredential),
+	),
+	service("SystemService",
+		method("Stats", (*server).systemStats),
+	),
+}
+
+// service describes a gRPC service without generated code, the methods
+// decode their requests with the codec of the call
+func service(name string, methods ...func(service string) gogrpc.MethodDesc) *gogrpc.ServiceDesc {
+	desc := &gogrpc.ServiceDesc{
+		ServiceName: servicePrefix + name,
+		HandlerType: (*any)(nil),
+		Metadata:    "template-manager",
+	}
+	for _, m := range methods {
+		desc.Methods = append(desc.Methods, m(desc.ServiceName))
+	}
+	return desc
+}
+
+func method[Req, Resp any](name string, call func(s *server, ctx context.Context, req *Req) (*Resp, error)) func(service string) gogrpc.MethodDesc {
+	return func(service string) gogrpc.MethodDesc {
+		fullMethod := "/" + service + "/" + name
+		return gogrpc.MethodDesc{
+			MethodName: name,
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor gogrpc.UnaryServerInterceptor) (any, error) {
+				req := new(Req)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					resp, err := call(srv.(*server), ctx, req.(*Req))
+					if err != nil {
+						return nil, statusError(err)
+					}
+					return resp, nil
+				}
+				if interceptor == nil {
+					return handler(ctx, req)
+				}
+				return interceptor(ctx, req, &gogrpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}, handler)
+			},
+		}
+	}
+}
+
+func (s *server) getUploadURL(ctx context.Context, req *shared.GetUploadURLRequest) (*shared.UploadURLResponse, error) {
+	req.AccountID = accountID(ctx)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.templateApp.GetUploadURL(ctx, *req)
+}
+
+func (s *server) createTemplate(ctx context.Context, req *shared.CreateTemplateRequest) (*Empty, error) {
+	req.AccountID = accountID(ctx)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, s.templateApp.Create(ctx, *req)
+}
+
+// getTemplate sends the ETag of the template in the etag header, it is the
+// if-match of updates
+func (s *server) getTemplate(ctx context.Context, req *shared.GetTemplateRequest) (*entity.Template, error) {
+	req.AccountID = accountID(ctx)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	found, err := s.templateApp.Get(ctx, *req)
+	if err != nil {
+		return nil, err
+	}
+	_ = gogrpc.SetHeader(ctx, metadata.Pairs("etag", found.ETag()))
+	return found, nil
+}
+
+func (s *server) listTemplates(ctx context.Context, req *shared.ListTemplatesRequest) (*util.PaginationT[[]entity.Template], error) {
+	req.AccountID = accountID(ctx)
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 10
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.templateApp.List(ctx, *req)
+}
+
+func (s *server) updateTemplate(ctx context.Context, req *shared.UpdateTemplateRequest) (*Empty, error) {
+	return s.writeTemplate(ctx, req, s.templateApp.Update)
+}
+
+func (s *server) editTemplate(ctx context.Context, req *shared.UpdateTemplateRequest) (*Empty, error) {
+	return s.writeTemplate(ctx, req, s.templateApp.Edit)
+}
+
+// writeTemplate takes the ETag the client last saw from the if-match header,
+// stale writes fail with FailedPrecondition and the current ETag in the etag trailer
+func (s *server) writeTemplate(ctx context.Context, req *shared.UpdateTemplateRequest, write func(context.Context, shared.UpdateTemplateRequest) error) (*Empty, error) {
+	req.AccountID = accountID(ctx)
+	md, _ := metadata.FromIncomingContext(ctx)
+	req.IfMatch = first(md, ifMatchHeader)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := write(ctx, *req)
+	var stale *template.PreconditionFailedError
+	if errors.As(err, &stale) {
+		_ = gogrpc.SetTrailer(ctx, metadata.Pairs("etag", stale.Current.ETag()))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *server) deleteTemplate(ctx context.Context, req *shared.DeleteTemplateRequest) (*Empty, error) {
+	req.AccountID = accountID(ctx)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, s.templateApp.Delete(ctx, *req)
+}
+
+func (s *server) render(ctx context.Context, req *shared.RenderTemplateRequest) (*shared.RenderTemplateResponse, error) {
+	req.AccountID = accountID(ctx)
+	if req.Draft && !readsDrafts(ctx) {
+		return nil, entity.ErrDraftScope
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.templateApp.Render(ctx, *req)
+}
+
+func (s *server) send(ctx context.Context, req *shared.SendTemplateRequest) (*shared.SendTemplateResponse, error) {
+	req.AccountID = accountID(ctx)
+	req.TestKeyID = testKeyID(ctx)
+	if req.Draft && !readsDrafts(ctx) {
+		return nil, entity.ErrDraftScope
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.templateApp.Send(ctx, *req)
+}
+
+// createKey returns the key with its secret, the only time it is available
+func (s *server) createKey(ctx context.Context, req *shared.CreateAccessKeyRequest) (*entity.Key, error) {
+	req.AccountID = accountID(ctx)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.authApp.CreateAccessKey(ctx, *req)
+}
+
+func (s *server) listKeys(ctx context.Context, req *shared.ListAccessKeysRequest) (*ListKeysResponse, error) {
+	req.AccountID = accountID(ctx)
+	keys, err := s.authApp.ListAccessKeys(ctx, *req)
+	if err != nil {
+		return nil, err
+	}
+	return &ListKeysResponse{Keys: keys}, nil
+}
+
+// rotateKey returns the key with its new secret, the only time it is available
+func (s *server) rotateKey(ctx context.Context, req *shared.RotateAccessKeyRequest) (*entity.Key, error) {
+	req.AccountID = accountID(ctx)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.authApp.RotateAccessKey(ctx, *req)
+}
+
+func (s *server) staleKeys(ctx context.Context, req *shared.StaleAccessKeysRequest) (*StaleKeysResponse, error) {
+	req.AccountID = accountID(ctx)
+	if req.Days == 0 {
+		req.Days = shared.DefaultStaleKeyDays
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	keys, err := s.authApp.StaleAccessKeys(ctx, *req)
+	if err != nil {
+		return nil, err
+	}
+	return &StaleKeysResponse{Keys: keys}, nil
+}
+
+func (s *server) deleteKey(ctx context.Context, req *shared.DeleteAccessKeyRequest) (*Empty, error) {
+	req.AccountID = accountID(ctx)
+	return &Empty{}, s.authApp.DeleteAccessKey(ctx, *req)
+}
+
+func (s *server) createCredential(ctx context.Context, req *shared.CredentialInput) (*Empty, error) {
+	return &Empty{}, s.credentialApp.Create(ctx, accountID(ctx), req)
+}
+
+func (s *server) listCredentials(ctx context.Context, _ *Empty) (*ListCredentialsResponse, error) {
+	credentials, err := s.credentialApp.GetByAccountID(ctx, accountID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &ListCredentialsResponse{Credentials: credentials}, nil
+}
+
+func (s *server) getCredential(ctx context.Context, req *CredentialRequest) (*entity.Credential, error) {
+	found, err := s.credentialApp.GetByID(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	// credentials are looked up by id alone, other accounts must not see them
+	if found.AccountID != accountID(ctx) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return found, nil
+}
+
+func (s *server) updateCredential(ctx context.Context, req *shared.CredentialInput) (*Empty, error) {
+	return &Empty{}, s.credentialApp.Update(ctx, accountID(ctx), req)
+}
+
+func (s *server) deleteCredential(ctx context.Context, req *CredentialRequest) (*Empty, error) {
+	if _, err := s.getCredential(ctx, req); err != nil {
+		return nil, err
+	}
+	return &Empty{}, s.credentialApp.Delete(ctx, req.ID)
+}
+
+func (s *server) systemStats(ctx context.Context, _ *Empty) (*shared.ServerStats, error) {
+	return s.stats(ctx)
+}
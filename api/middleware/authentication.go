@@ -2,7 +2,10 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
+	"strings"
 
 	fiber "github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -10,13 +13,18 @@ import (
 	"template-manager/internal/entity"
 )
 
+// APIKeyHeader is the header requests authenticated by an API key send it in
+const APIKeyHeader = "X-API-Key"
+
 type Auth struct {
 	sess SessionManager
+	keys KeyVerifier
 }
 
-func NewAuth(sess SessionManager) *Auth {
+func NewAuth(sess SessionManager, keys KeyVerifier) *Auth {
 	return &Auth{
 		sess: sess,
+		keys: keys,
 	}
 }
 
@@ -24,69 +32,142 @@ type SessionManager interface {
 	Verify(ctx context.Context, token string) (*entity.Session, error)
 }
 
+type KeyVerifier interface {
+	VerifyAccessKey(ctx context.Context, secret, ip string) (*entity.Key, error)
+}
+
 var unauthenticatedRoutes = map[string]bool{
-	"/stats":                    true,
-	"/health":                   true,
-	"/api/users/signup":         true,
-	"/api/users/login":          true,
-	"/api/users/reset-password": true,
+	"/stats":                             true,
+	"/health":                            true,
+	"/api/users/signup":                  true,
+	"/api/users/login":                   true,
+	"/api/users/verify":                  true,
+	"/api/users/verify/resend":           true,
+	"/api/users/reset-password":          true,
+	"/api/users/reset-password/complete": true,
+	"/api/openapi.json":                  true,
+	"/api/docs":                          true,
+}
+
+// files under the storage path are authorized by the signature in their url
+var unauthenticatedPrefixes = []string{
+	"/storage/",
+}
+
+func isUnauthenticated(path string) bool {
+	if unauthenticatedRoutes[path] {
+		return true
+	}
+	for _, prefix := range unauthenticatedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func (a *Auth) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		if _, ok := unauthenticatedRoutes[r.URL.Path]; ok {
+		if isUnauthenticated(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// extract token from header
-		token := r.Header.Get("Authorization")
-		if token == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-
-		// verify token
-		sess, err := a.sess.Verify(r.Context(), token)
+		accountID, key, err := a.authenticate(r.Context(), r.Header.Get(APIKeyHeader), r.Header.Get("Authorization"), clientIP(r))
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
 		// set account id in context
-		ctx := context.WithValue(r.Context(), "account_id", sess.AccountID)
+		ctx := context.WithValue(r.Context(), "account_id", accountID)
+		if key != nil {
+			ctx = context.WithValue(ctx, "api_key", key)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
 func (a *Auth) FiberAuthMiddleware(c *fiber.Ctx) error {
 
-	path := c.Path()
-	if _, ok := unauthenticatedRoutes[path]; ok {
+	if isUnauthenticated(c.Path()) {
 		return c.Next()
 	}
 
-	// extract token from header
-	token := c.Get("Authorization")
-	if token == "" {
-		return c.SendStatus(http.StatusUnauthorized)
-	}
-
-	// verify token
-	sess, err := a.sess.Verify(c.Context(), token)
+	accountID, key, err := a.authenticate(c.Context(), c.Get(APIKeyHeader), c.Get("Authorization"), c.IP())
 	if err != nil {
 		return c.SendStatus(http.StatusUnauthorized)
 	}
 
 	// set account id in context
-	ctx := context.WithValue(c.Context(), "account_id", sess.AccountID)
+	ctx := context.WithValue(c.Context(), "account_id", accountID)
 	c.SetUserContext(ctx)
-	c.Context().SetUserValue("account_id", sess.AccountID)
+	c.Context().SetUserValue("account_id", accountID)
+	if key != nil {
+		c.Locals("api_key", key)
+	}
+
+	return c.Next()
+}
 
+// RequireScope only lets API keys with the scope through, sessions can call
+// every route of their account
+func (a *Auth) RequireScope(scope entity.KeyScope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := c.Locals("api_key").(*entity.Key)
+		if ok && !key.Scopes.Has(scope) {
+			return forbidden(c, "api key is missing the "+string(scope)+" scope")
+		}
+		return c.Next()
+	}
+}
+
+// RequireSession rejects API keys, e.g keys must not create other keys
+func (a *Auth) RequireSession(c *fiber.Ctx) error {
+	if _, ok := c.Locals("api_key").(*entity.Key); ok {
+		return forbidden(c, "api keys can't call this route, login instead")
+	}
 	return c.Next()
 }
 
+func forbidden(c *fiber.Ctx, message string) error {
+	c.Status(fiber.StatusForbidden)
+	return c.JSON(fiber.Map{
+		"status":  false,
+		"message": message,
+	})
+}
+
+// authenticate returns the account of an API key or else of a session token,
+// the key is nil for sessions
+func (a *Auth) authenticate(ctx context.Context, apiKey, token, ip string) (string, *entity.Key, error) {
+	if apiKey != "" {
+		key, err := a.keys.VerifyAccessKey(ctx, apiKey, ip)
+		if err != nil {
+			return "", nil, err
+		}
+		return key.AccountID, key, nil
+	}
+
+	if token == "" {
+		return "", nil, errors.New("missing authorization")
+	}
+	sess, err := a.sess.Verify(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	return sess.AccountID, nil, nil
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func (a *Auth) CorsMiddleware(c *fiber.Ctx) error {
 	origin := c.Get("Origin")
 	return cors.New(cors.Config{
@@ -94,5 +175,6 @@ func (a *Auth) CorsMiddleware(c *fiber.Ctx) error {
 		AllowOrigins:     origin,
 		AllowCredentials: true,
 		AllowMethods:     "*",
+		ExposeHeaders:    "ETag",
 	})(c)
 }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	fiber "github.com/gofiber/fiber/v2"
+
+	"template-manager/internal/entity"
+)
+
+type fakeSessions map[string]*entity.Session
+
+func (f fakeSessions) Verify(_ context.Context, token string) (*entity.Session, error) {
+	if sess, ok := f[token]; ok {
+		return sess, nil
+	}
+	return nil, errors.New("invalid session")
+}
+
+type fakeKeys map[string]*entity.Key
+
+func (f fakeKeys) VerifyAccessKey(_ context.Context, secret, _ string) (*entity.Key, error) {
+	if key, ok := f[secret]; ok {
+		return key, nil
+	}
+	return nil, errors.New("invalid api key")
+}
+
+func TestScopes(t *testing.T) {
+	auth := NewAuth(
+		fakeSessions{"session-token": {AccountID: "acc"}},
+		fakeKeys{
+			"reader": {ID: "k1", AccountID: "acc", Scopes: entity.KeyScopeSet{entity.KeyScopeTemplatesRead}},
+			"sender": {ID: "k2", AccountID: "acc", Scopes: entity.KeyScopeSet{entity.KeyScopeRender, entity.KeyScopeSend}},
+		},
+	)
+	app := fiber.New()
+	app.Use(auth.FiberAuthMiddleware)
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/api/templates", auth.RequireScope(entity.KeyScopeTemplatesRead), ok)
+	app.Post("/api/templates", auth.RequireScope(entity.KeyScopeTemplatesWrite), ok)
+	app.Post("/api/keys", auth.RequireSession, ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		token  string
+		status int
+	}{
+		{"key with the scope", http.MethodGet, "/api/templates", "reader", "", fiber.StatusOK},
+		{"key without the scope", http.MethodGet, "/api/templates", "sender", "", fiber.StatusForbidden},
+		{"read scope doesn't write", http.MethodPost, "/api/templates", "reader", "", fiber.StatusForbidden},
+		{"session has every scope", http.MethodPost, "/api/templates", "", "session-token", fiber.StatusOK},
+		{"keys can't call session routes", http.MethodPost, "/api/keys", "reader", "", fiber.StatusForbidden},
+		{"session routes", http.MethodPost, "/api/keys", "", "session-token", fiber.StatusOK},
+		{"unknown key", http.MethodGet, "/api/templates", "unknown", "", fiber.StatusUnauthorized},
+		{"no credentials", http.MethodGet, "/api/templates", "", "", fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			if tt.token != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.token)
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
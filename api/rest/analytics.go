@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+const (
+	analyticsDateLayout  = "2006-01-02"
+	defaultAnalyticsDays = 30
+)
+
+func (s *server) AnalyticsSummary(c *fiber.Ctx) error {
+	req, err := analyticsRequest(c)
+	if err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	summary, err := s.analyticsApp.Summary(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "analytics summary retrieved successfully", summary)
+}
+
+func (s *server) DailyAnalytics(c *fiber.Ctx) error {
+	req, err := analyticsRequest(c)
+	if err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	stats, err := s.analyticsApp.Daily(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "daily analytics retrieved successfully", stats)
+}
+
+func (s *server) TopTemplates(c *fiber.Ctx) error {
+	req, err := analyticsRequest(c)
+	if err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	stats, err := s.analyticsApp.Top(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "top templates retrieved successfully", stats)
+}
+
+func (s *server) UnusedTemplates(c *fiber.Ctx) error {
+	req, err := analyticsRequest(c)
+	if err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	templates, err := s.analyticsApp.Unused(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "unused templates retrieved successfully", templates)
+}
+
+// analyticsRequest reads the from/to dates (inclusive, YYYY-MM-DD) and filters
+// from the query string, the range defaults to the last 30 days
+func analyticsRequest(c *fiber.Ctx) (shared.AnalyticsRequest, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	req := shared.AnalyticsRequest{
+		AccountID:  c.Locals("account_id").(string),
+		From:       today.AddDate(0, 0, -defaultAnalyticsDays+1),
+		To:         today.AddDate(0, 0, 1),
+		TemplateID: c.Query("template_id"),
+		Kind:       entity.EventKind(c.Query("kind")),
+		Limit:      c.QueryInt("limit"),
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(analyticsDateLayout, from)
+		if err != nil {
+			return req, err
+		}
+		req.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(analyticsDateLayout, to)
+		if err != nil {
+			return req, err
+		}
+		req.To = t.AddDate(0, 0, 1)
+	}
+	return req, req.Validate()
+}
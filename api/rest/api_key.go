@@ -15,12 +15,15 @@ func (s *server) AddKey(c *fiber.Ctx) error {
 		return HandleBadRequest(c, err)
 	}
 	request.AccountID = c.Locals("account_id").(string)
-	err = s.authApp.CreateAccessKey(ctx, request)
+	if err := request.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	key, err := s.authApp.CreateAccessKey(ctx, request)
 	if err != nil {
 		return HandleError(c, err)
 	}
 
-	return HandleSuccess(c, "successfully created key", nil)
+	return HandleSuccess(c, "successfully created key, store the secret as it will not be shown again", key)
 }
 
 func (s *server) ListAccessKeys(c *fiber.Ctx) error {
@@ -41,6 +44,7 @@ func (s *server) DeleteKey(c *fiber.Ctx) error {
 	ID := c.Params("id")
 
 	request := shared.DeleteAccessKeyRequest{
+		AccountID:   c.Locals("account_id").(string),
 		AccessKeyID: ID,
 	}
 	err := s.authApp.DeleteAccessKey(ctx, request)
@@ -50,3 +54,38 @@ func (s *server) DeleteKey(c *fiber.Ctx) error {
 
 	return HandleSuccess(c, "successfully deleted key", nil)
 }
+
+func (s *server) RotateKey(c *fiber.Ctx) error {
+	var request shared.RotateAccessKeyRequest
+	if err := c.BodyParser(&request); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	request.AccountID = c.Locals("account_id").(string)
+	request.AccessKeyID = c.Params("id")
+	if err := request.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	key, err := s.authApp.RotateAccessKey(c.Context(), request)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "successfully rotated key, store the secret as it will not be shown again", key)
+}
+
+// StaleKeys lists the keys that expire or weren't used within ?days, 30 by default
+func (s *server) StaleKeys(c *fiber.Ctx) error {
+	request := shared.StaleAccessKeysRequest{
+		AccountID: c.Locals("account_id").(string),
+		Days:      c.QueryInt("days", shared.DefaultStaleKeyDays),
+	}
+	if err := request.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	keys, err := s.authApp.StaleAccessKeys(c.Context(), request)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "successfully retrieved stale keys", keys)
+}
@@ -16,6 +16,9 @@ func (s *server) Signup(c *fiber.Ctx) error {
 	if err != nil {
 		return HandleBadRequest(c, err)
 	}
+	if err := request.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
 
 	err = s.authApp.Signup(ctx, request)
 	if err != nil {
@@ -25,6 +28,36 @@ func (s *server) Signup(c *fiber.Ctx) error {
 	return HandleSuccess(c, "check your email to continue sign up", nil)
 }
 
+func (s *server) VerifyEmail(c *fiber.Ctx) error {
+	var request shared.VerifyEmailRequest
+	if err := c.BodyParser(&request); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	if err := request.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	if err := s.authApp.VerifyEmail(c.Context(), request); err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "email verified successfully", nil)
+}
+
+func (s *server) ResendVerification(c *fiber.Ctx) error {
+	var request shared.ResendVerificationRequest
+	if err := c.BodyParser(&request); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	if err := request.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	if err := s.authApp.ResendVerification(c.Context(), request); err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "if the account exists and isn't verified, check your email for a new link", nil)
+}
+
 func (s *server) Login(c *fiber.Ctx) error {
 	var request shared.LoginRequest
 	err := c.BodyParser(&request)
@@ -63,6 +96,21 @@ func (s *server) InitiateResetPassword(c *fiber.Ctx) error {
 	return HandleSuccess(c, "check your email to continue password reset", nil)
 }
 
+func (s *server) ResetPassword(c *fiber.Ctx) error {
+	var request shared.ResetPasswordRequest
+	if err := c.BodyParser(&request); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	if err := request.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	if err := s.authApp.ResetPassword(c.Context(), request); err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "password reset successfully, login with the new password", nil)
+}
+
 func (s *server) Logout(c *fiber.Ctx) error {
 	var request shared.LogoutRequest
 	err := c.BodyParser(&request)
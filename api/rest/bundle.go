@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"template-manager/internal/app/template"
+	"template-manager/internal/shared"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// ExportBundle downloads the templates of the account as a zip, ?keys=a,b
+// limits it to some templates and ?latest_only=true to their latest version
+func (s *server) ExportBundle(c *fiber.Ctx) error {
+	var req = shared.ExportBundleRequest{
+		AccountID:  c.Locals("account_id").(string),
+		LatestOnly: c.QueryBool("latest_only"),
+	}
+	if keys := c.Query("keys"); keys != "" {
+		req.Keys = strings.Split(keys, ",")
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	var bundle bytes.Buffer
+	if err := s.templateApp.ExportBundle(c.Context(), req, &bundle); err != nil {
+		return HandleError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="templates-%s.zip"`, time.Now().UTC().Format("20060102-150405")))
+	return c.Send(bundle.Bytes())
+}
+
+// ImportBundle imports a bundle sent either as the "bundle" file of a
+// multipart form or as an application/zip body. ?conflict= (or the form value)
+// decides what happens to templates that already exist.
+func (s *server) ImportBundle(c *fiber.Ctx) error {
+	var req = shared.ImportBundleRequest{
+		AccountID: c.Locals("account_id").(string),
+		Conflict:  c.Query("conflict", c.FormValue("conflict", shared.ConflictSkip)),
+	}
+
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		header, err := c.FormFile("bundle")
+		if err != nil {
+			return HandleBadRequest(c, err)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return HandleBadRequest(c, err)
+		}
+		defer file.Close()
+		if req.Bundle, err = io.ReadAll(io.LimitReader(file, template.MaxBundleSize)); err != nil {
+			return HandleBadRequest(c, err)
+		}
+	} else {
+		req.Bundle = c.Body()
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	result, err := s.templateApp.ImportBundle(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "bundle imported successfully", result)
+}
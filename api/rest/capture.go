@@ -0,0 +1,42 @@
+package rest
+
+import (
+	fiber "github.com/gofiber/fiber/v2"
+
+	"template-manager/internal/shared"
+)
+
+// ListCapturedMessages lists the messages sent with test API keys
+func (s *server) ListCapturedMessages(c *fiber.Ctx) error {
+	var req = shared.ListCapturedMessagesRequest{
+		AccountID: c.Locals("account_id").(string),
+		To:        c.Query("to"),
+		Page:      c.QueryInt("page", 1),
+		PageSize:  c.QueryInt("page_size", 10),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	messages, err := s.templateApp.ListCaptured(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "captured messages retrieved successfully", messages)
+}
+
+func (s *server) GetCapturedMessage(c *fiber.Ctx) error {
+	message, err := s.templateApp.GetCaptured(c.Context(), c.Locals("account_id").(string), c.Params("id"))
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "captured message retrieved successfully", message)
+}
+
+func (s *server) ClearCapturedMessages(c *fiber.Ctx) error {
+	if err := s.templateApp.ClearCaptured(c.Context(), c.Locals("account_id").(string)); err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "captured messages deleted successfully", nil)
+}
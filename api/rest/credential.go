@@ -25,7 +25,8 @@ func (s *server) UpdateCredential(c *fiber.Ctx) error {
 		return HandleBadRequest(c, err)
 	}
 
-	if err := s.credentialApp.Update(c.Context(), req); err != nil {
+	accountID := c.Locals("account_id").(string)
+	if err := s.credentialApp.Update(c.Context(), accountID, req); err != nil {
 		return HandleError(c, err)
 	}
 	return HandleSuccess(c, "credential updated successfully", nil)
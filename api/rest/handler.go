@@ -1,10 +1,17 @@
 package rest
 
 import (
+	"io"
+
+	"template-manager/internal/app/analytics"
 	"template-manager/internal/app/auth"
 	"template-manager/internal/app/credential"
 	"template-manager/internal/app/template"
+	"template-manager/internal/app/webhook"
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
 	"template-manager/pkg/config"
+	"template-manager/pkg/uploader"
 
 	fiber "github.com/gofiber/fiber/v2"
 )
@@ -12,89 +19,230 @@ import (
 type Middleware interface {
 	FiberAuthMiddleware(c *fiber.Ctx) error
 	CorsMiddleware(c *fiber.Ctx) error
+	RequireScope(scope entity.KeyScope) fiber.Handler
+	RequireSession(c *fiber.Ctx) error
 }
 
 type server struct {
 	conf          *config.Config
+	grpc          bool // the gRPC API is served next to this one
 	authApp       *auth.App
 	templateApp   *template.App
 	credentialApp *credential.Credential
+	analyticsApp  *analytics.App
+	webhookApp    *webhook.App
+	storage       uploader.Uploader
 	middleware    Middleware
 }
 
 // New creates a new fiber app
 func New(
 	conf *config.Config,
+	grpc bool,
 	authApp *auth.App,
 	templateApp *template.App,
 	credentialApp *credential.Credential,
+	analyticsApp *analytics.App,
+	webhookApp *webhook.App,
+	storage uploader.Uploader,
 	middleware Middleware,
 ) *server {
 	return &server{
 		conf:          conf,
+		grpc:          grpc,
 		authApp:       authApp,
 		templateApp:   templateApp,
 		credentialApp: credentialApp,
+		analyticsApp:  analyticsApp,
+		webhookApp:    webhookApp,
+		storage:       storage,
 		middleware:    middleware,
 	}
 }
 
 func (s server) Listen(port string) error {
-	app := fiber.New()
+	// Start the server on port 8080
+	return s.routes().Listen(port)
+}
+
+// defaultBodyLimit is the largest body of every route but the bundle import,
+// template content may be up to 5MB
+const defaultBodyLimit = 8 << 20
+
+// bodyLimits raises the body limit of single routes
+var bodyLimits = map[string]int{
+	"/api/bundles/import": template.MaxBundleSize + 1<<20,
+}
+
+// routes creates the fiber app serving every route of the API
+func (s server) routes() *fiber.App {
+	app := fiber.New(fiber.Config{
+		// bodies larger than the limit are streamed instead of rejected,
+		// limitBody reads them when the route allows it
+		BodyLimit:                    defaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 
+	app.Use(limitBody)
 	app.Use(s.middleware.CorsMiddleware)
 	app.Use(s.middleware.FiberAuthMiddleware)
 
 	// Setup route for the API health check
 	app.Get("/health", health)
-	app.Get("/stats", stats)
+	app.Get("/stats", s.stats)
+
+	// Setup routes for the API documentation
+	app.Get("/api/openapi.json", s.OpenAPI)
+	app.Get("/api/docs", s.APIDocs)
+
+	// Signed upload and download URLs of the local and postgres storage, the
+	// signed URLs of s3 point at the bucket
+	if _, ok := s.storage.(uploader.Served); ok {
+		app.Put(uploader.ServePath+"*", s.UploadFile)
+		app.Get(uploader.ServePath+"*", s.DownloadFile)
+	}
 
 	api := app.Group("/api")
 
+	// API keys can only call the routes of their scopes, see entity.KeyScope
+	var (
+		sessionOnly    = s.middleware.RequireSession
+		templatesRead  = s.middleware.RequireScope(entity.KeyScopeTemplatesRead)
+		templatesWrite = s.middleware.RequireScope(entity.KeyScopeTemplatesWrite)
+		approve        = s.middleware.RequireScope(entity.KeyScopeTemplatesApprove)
+		render         = s.middleware.RequireScope(entity.KeyScopeRender)
+		send           = s.middleware.RequireScope(entity.KeyScopeSend)
+		credentials    = s.middleware.RequireScope(entity.KeyScopeCredentialsManage)
+	)
+
 	// Define API endpoints for managing users
 	api.Post("/users/signup", s.Signup)
 	api.Post("/users/login", s.Login)
-	api.Post("/users/logout", s.Logout)
+	api.Post("/users/verify", s.VerifyEmail)
+	api.Post("/users/verify/resend", s.ResendVerification)
+	api.Post("/users/logout", sessionOnly, s.Logout)
 	api.Post("/users/reset-password", s.InitiateResetPassword)
+	api.Post("/users/reset-password/complete", s.ResetPassword)
 
 	// Define API endpoints for managing keys
-	api.Post("/keys", s.AddKey)
-	api.Get("/keys", s.ListAccessKeys)
-	api.Delete("/keys/:id", s.DeleteKey)
+	api.Post("/keys", sessionOnly, s.AddKey)
+	api.Get("/keys", sessionOnly, s.ListAccessKeys)
+	api.Get("/keys/stale", sessionOnly, s.StaleKeys)
+	api.Post("/keys/:id/rotate", sessionOnly, s.RotateKey)
+	api.Delete("/keys/:id", sessionOnly, s.DeleteKey)
 
 	// Define API endpoints for managing templates
-	api.Post("/templates/upload-url", s.GetUploadURL)
-	api.Post("/templates", s.AddTemplate)
-	api.Get("/templates", s.ListTemplates)
-	api.Get("/templates/:id", s.GetTemplate)
-	api.Put("/templates/:id", s.UpdateTemplate)
-	api.Put("/templates/edit/:id", s.EditTemplate)
-	api.Delete("/templates/:id", s.DeleteTemplate)
-	api.Post("/templates/import", s.ImportTemplate)
-	api.Post("/templates/export", s.ExportTemplate)
+	api.Post("/templates/upload-url", templatesWrite, s.GetUploadURL)
+	api.Post("/templates", templatesWrite, s.AddTemplate)
+	api.Get("/templates", templatesRead, s.ListTemplates)
+	api.Get("/templates/:id", templatesRead, s.GetTemplate)
+	api.Get("/templates/:id/content", templatesRead, s.GetTemplateContent)
+	api.Get("/templates/:id/versions/:version/content", templatesRead, s.GetTemplateContent)
+	api.Put("/templates/:id", templatesWrite, s.UpdateTemplate)
+	api.Put("/templates/edit/:id", templatesWrite, s.EditTemplate)
+	api.Delete("/templates/:id", templatesWrite, s.DeleteTemplate)
+	api.Post("/templates/:id/render", render, s.RenderTemplate)
+	api.Post("/templates/:id/send", send, s.SendTemplate)
+
+	// Define API endpoints for the messages captured from test API keys
+	api.Get("/test/messages", send, s.ListCapturedMessages)
+	api.Get("/test/messages/:id", send, s.GetCapturedMessage)
+	api.Delete("/test/messages", send, s.ClearCapturedMessages)
+
+	// Define API endpoints for the template review workflow
+	api.Get("/templates/:id/reviews", templatesRead, s.ListTemplateReviews)
+	api.Post("/templates/:id/review", templatesWrite, s.reviewHandler(s.templateApp.RequestReview, "template submitted for review"))
+	api.Post("/templates/:id/approve", approve, s.reviewHandler(s.templateApp.Approve, "template approved successfully"))
+	api.Post("/templates/:id/reject", approve, s.reviewHandler(s.templateApp.Reject, "template rejected successfully"))
+	api.Post("/templates/:id/publish", approve, s.reviewHandler(s.templateApp.Publish, "template published successfully"))
+	api.Post("/templates/:id/archive", templatesWrite, s.reviewHandler(s.templateApp.Archive, "template archived successfully"))
+
+	// Define API endpoints for scheduled publishing
+	api.Post("/templates/:id/schedules", approve, s.ScheduleTemplate)
+	api.Get("/schedules", templatesRead, s.ListSchedules)
+	api.Delete("/schedules/:id", approve, s.CancelSchedule)
+	api.Post("/templates/import", templatesWrite, s.ImportTemplate)
+	api.Post("/templates/export", templatesWrite, s.ExportTemplate)
+
+	// Define API endpoints for bulk export and import
+	api.Get("/bundles/export", templatesRead, s.ExportBundle)
+	api.Post("/bundles/import", templatesWrite, s.ImportBundle)
+
+	// Define API endpoints for the template trash
+	api.Get("/trash/templates", templatesRead, s.ListTrash)
+	api.Post("/trash/templates/:id/restore", templatesWrite, s.RestoreTemplate)
+	api.Delete("/trash/templates/:id", templatesWrite, s.PurgeTemplate)
+
+	// Define API endpoints for render and send analytics
+	api.Get("/analytics/summary", templatesRead, s.AnalyticsSummary)
+	api.Get("/analytics/daily", templatesRead, s.DailyAnalytics)
+	api.Get("/analytics/templates/top", templatesRead, s.TopTemplates)
+	api.Get("/analytics/templates/unused", templatesRead, s.UnusedTemplates)
+
+	// Define API endpoints for managing webhooks
+	api.Post("/webhooks", sessionOnly, s.AddWebhook)
+	api.Get("/webhooks", sessionOnly, s.ListWebhooks)
+	api.Get("/webhooks/:id", sessionOnly, s.GetWebhook)
+	api.Put("/webhooks/:id", sessionOnly, s.UpdateWebhook)
+	api.Delete("/webhooks/:id", sessionOnly, s.DeleteWebhook)
+	api.Get("/webhooks/:id/deliveries", sessionOnly, s.ListWebhookDeliveries)
+	api.Post("/webhooks/deliveries/:id/redeliver", sessionOnly, s.RedeliverWebhook)
 
 	// Define API endpoints for managing credentials\
-	api.Post("/credentials", s.AddCredential)
-	api.Get("/credentials", s.GetCredentials)
-	api.Get("/credentials/:id", s.GetCredential)
-	api.Put("/credentials", s.UpdateCredential)
-	api.Delete("/credentials/:id", s.DeleteCredential)
+	api.Post("/credentials", credentials, s.AddCredential)
+	api.Get("/credentials", credentials, s.GetCredentials)
+	api.Get("/credentials/:id", credentials, s.GetCredential)
+	api.Put("/credentials", credentials, s.UpdateCredential)
+	api.Delete("/credentials/:id", credentials, s.DeleteCredential)
 
-	// Start the server on port 8080
-	return app.Listen(port)
+	return app
+}
+
+// limitBody reads the body of the request up to the limit of its route, the
+// handlers only see bodies that fit
+func limitBody(c *fiber.Ctx) error {
+	if !c.Request().IsBodyStream() {
+		return c.Next()
+	}
+	limit, ok := bodyLimits[c.Path()]
+	if !ok {
+		limit = defaultBodyLimit
+	}
+	if c.Request().Header.ContentLength() > limit {
+		c.Context().SetConnectionClose()
+		return c.SendStatus(fiber.StatusRequestEntityTooLarge)
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Context().RequestBodyStream(), int64(limit)+1))
+	if err != nil {
+		return HandleBadRequest(c, err)
+	}
+	if len(body) > limit {
+		c.Context().SetConnectionClose()
+		return c.SendStatus(fiber.StatusRequestEntityTooLarge)
+	}
+	c.Request().SetBody(body)
+	return c.Next()
+}
+
+type healthResponse struct {
+	Message string `json:"message"`
 }
 
 func health(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"message": "unicorns are running free! 🦄",
+	return c.JSON(healthResponse{
+		Message: "unicorns are running free! 🦄",
 	})
 }
 
-func stats(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"grpc":    false,
-		"version": "v1.0.0",
-		"open":    false, // open source version
+// stats is public, it only reports the state of this server. The usage of an
+// account is served by /api/analytics/summary.
+func (s server) stats(c *fiber.Ctx) error {
+	return c.JSON(shared.ServerStats{
+		GRPC:    s.grpc,
+		Version: shared.APIVersion,
+		Open:    false,
+		Cache:   s.templateApp.CacheStats(),
 	})
 }
 
@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"template-manager/internal/app/template"
+	"template-manager/internal/entity"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func TestBodyLimit(t *testing.T) {
+	app := server{middleware: openMiddleware{}}.routes()
+
+	tests := []struct {
+		name   string
+		path   string
+		size   int
+		status int
+	}{
+		// the routes fail on the empty or invalid body once it is let through
+		{"within the default limit", "/api/templates", 1 << 10, fiber.StatusBadRequest},
+		{"above the default limit", "/api/templates", defaultBodyLimit + 1, fiber.StatusRequestEntityTooLarge},
+		{"bundle above the default limit", "/api/bundles/import", defaultBodyLimit + 1, fiber.StatusUnprocessableEntity},
+		{"bundle above its limit", "/api/bundles/import", template.MaxBundleSize + 2<<20, fiber.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(make([]byte, tt.size)))
+			req.Header.Set(fiber.HeaderContentType, "application/zip")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+// keyMiddleware authenticates every request as an API key of account "acc"
+type keyMiddleware struct {
+	openMiddleware
+	key *entity.Key
+}
+
+func (m keyMiddleware) FiberAuthMiddleware(c *fiber.Ctx) error {
+	c.Locals("account_id", "acc")
+	c.Locals("api_key", m.key)
+	return c.Next()
+}
+
+func TestDraftsNeedTemplateScope(t *testing.T) {
+	tests := []struct {
+		path  string
+		scope entity.KeyScope
+	}{
+		{"/api/templates/t1/render", entity.KeyScopeRender},
+		{"/api/templates/t1/send", entity.KeyScopeSend},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			key := &entity.Key{ID: "k1", AccountID: "acc", Scopes: entity.KeyScopeSet{tt.scope}}
+			app := server{middleware: keyMiddleware{key: key}}.routes()
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{"draft": true}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusForbidden {
+				t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"sync"
+
+	"template-manager/api/middleware"
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/openapi"
+	"template-manager/pkg/repository/util"
+	"template-manager/pkg/uploader"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func init() {
+	openapi.RegisterEnum(entity.TemplateStatusDraft, entity.TemplateStatusInReview, entity.TemplateStatusApproved, entity.TemplateStatusPublished, entity.TemplateStatusArchived)
+	openapi.RegisterEnum(entity.ReviewActionRequested, entity.ReviewActionApproved, entity.ReviewActionRejected, entity.ReviewActionPublished, entity.ReviewActionArchived)
+	openapi.RegisterEnum(entity.ScheduleStatusPending, entity.ScheduleStatusPublishing, entity.ScheduleStatusActive, entity.ScheduleStatusReverting, entity.ScheduleStatusCompleted, entity.ScheduleStatusCancelled, entity.ScheduleStatusFailed)
+	openapi.RegisterEnum(entity.WebhookEvents...)
+	openapi.RegisterEnum(entity.KeyScopes...)
+	openapi.RegisterEnum(shared.KeyStalenessExpired, shared.KeyStalenessExpiring, shared.KeyStalenessUnused, shared.KeyStalenessNeverUsed)
+	openapi.RegisterEnum(entity.DeliveryStatusPending, entity.DeliveryStatusSucceeded, entity.DeliveryStatusFailed)
+	openapi.RegisterEnum(entity.EventKindRender, entity.EventKindSend)
+	openapi.RegisterEnum(entity.EventOutcomeSuccess, entity.EventOutcomeFailure)
+	openapi.RegisterEnum(entity.MAILJET, entity.MAILGUN)
+	openapi.RegisterEnum(entity.EMAIL, entity.SMS, entity.PUSH)
+}
+
+var (
+	pageParams = []openapi.Parameter{
+		{Name: "page", Description: "1 by default", Schema: &openapi.Schema{Type: "integer"}},
+		{Name: "page_size", Description: "10 by default", Schema: &openapi.Schema{Type: "integer"}},
+	}
+	analyticsParams = []openapi.Parameter{
+		{Name: "from", Description: "first day (YYYY-MM-DD), 30 days ago by default", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		{Name: "to", Description: "last day (YYYY-MM-DD), today by default", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		{Name: "template_id", Description: "only count the versions of this template", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "kind", Schema: &openapi.Schema{Type: "string", Enum: []any{entity.EventKindRender, entity.EventKindSend}}},
+		{Name: "limit", Description: "at most 100", Schema: &openapi.Schema{Type: "integer"}},
+	}
+	ifMatchHeader = []openapi.Parameter{
+		{Name: fiber.HeaderIfMatch, Description: "the ETag of the template the changes are based on, stale writes fail with 412", Schema: &openapi.Schema{Type: "string"}},
+	}
+)
+
+// operations documents every route registered in Listen, routes missing from
+// it are logged when the server starts
+var operations = []openapi.Operation{
+	{Method: fiber.MethodGet, Path: "/health", Tag: "system", Summary: "Check that the server is up", Public: true,
+		ResponseContentType: fiber.MIMEApplicationJSON, Response: healthResponse{}},
+	{Method: fiber.MethodGet, Path: "/stats", Tag: "system", Summary: "Version and cache statistics of the server", Public: true,
+		ResponseContentType: fiber.MIMEApplicationJSON, Response: shared.ServerStats{}},
+	{Method: fiber.MethodGet, Path: "/api/openapi.json", Tag: "system", Summary: "This document", Public: true,
+		ResponseContentType: fiber.MIMEApplicationJSON, Response: map[string]any{}},
+	{Method: fiber.MethodGet, Path: "/api/docs", Tag: "system", Summary: "API documentation page", Public: true,
+		ResponseContentType: fiber.MIMETextHTML},
+
+	{Method: fiber.MethodPut, Path: uploader.ServePath + "*", Tag: "storage", Summary: "Upload to a signed url", Public: true,
+		Description: "Only served by the local and postgres storage. The url is returned by /api/templates/upload-url, the Content-Type must be the one it was signed for.",
+		Request:     openapi.Binary{}, RequestContentType: "*/*", ResponseContentType: fiber.MIMETextPlain, Errors: []int{fiber.StatusForbidden, fiber.StatusNotFound}},
+	{Method: fiber.MethodGet, Path: uploader.ServePath + "*", Tag: "storage", Summary: "Download from a signed url", Public: true,
+		Description:         "Only served by the local and postgres storage.",
+		ResponseContentType: fiber.MIMEOctetStream, Errors: []int{fiber.StatusForbidden, fiber.StatusNotFound}},
+
+	{Method: fiber.MethodPost, Path: "/api/users/signup", Tag: "users", Summary: "Create an account", Public: true,
+		Description: "A verification link is emailed, the password is chosen when verifying. The account can't log in until it is verified.",
+		Request:     shared.SignUpRequest{}},
+	{Method: fiber.MethodPost, Path: "/api/users/verify", Tag: "users", Summary: "Verify the email of an account", Public: true,
+		Description: "The token is the one of the emailed link, it can only be used once and expires after 24 hours. It sets the password of the account, a rejected password leaves the token usable.",
+		Request:     shared.VerifyEmailRequest{}},
+	{Method: fiber.MethodPost, Path: "/api/users/verify/resend", Tag: "users", Summary: "Email a new verification link", Public: true,
+		Description: "Answers the same for every email so it doesn't tell who has an account. An account is sent at most one link a minute and 5 a day, requests past that send nothing.",
+		Request:     shared.ResendVerificationRequest{}},
+	{Method: fiber.MethodPost, Path: "/api/users/login", Tag: "users", Summary: "Start a session", Public: true,
+		Description: "The token of the session authenticates the other requests. Accounts that didn't verify their email can't log in.",
+		Request:     shared.LoginRequest{}, Response: shared.LoginResponse{}},
+	{Method: fiber.MethodPost, Path: "/api/users/logout", Security: sessionOnly, Tag: "users", Summary: "End a session",
+		Request: shared.LogoutRequest{}},
+	{Method: fiber.MethodPost, Path: "/api/users/reset-password", Tag: "users", Summary: "Email a link to reset the password of an account", Public: true,
+		Description: "The password is unchanged until the token of the link is used. Answers the same for every email so it doesn't tell who has an account. An account is sent at most one link a minute and 5 a day, requests past that send nothing.",
+		Request:     shared.InitiateResetPasswordRequest{}},
+	{Method: fiber.MethodPost, Path: "/api/users/reset-password/complete", Tag: "users", Summary: "Set a new password with the emailed token", Public: true,
+		Description: "The token can only be used once and expires after an hour, a rejected password leaves it usable. Every session of the account ends.",
+		Request:     shared.ResetPasswordRequest{}},
+
+	{Method: fiber.MethodPost, Path: "/api/keys", Security: sessionOnly, Tag: "keys", Summary: "Create an API key",
+		Description: "The secret is only returned here, only its prefix is shown afterwards.",
+		Request:     shared.CreateAccessKeyRequest{}, Response: entity.Key{}},
+	{Method: fiber.MethodGet, Path: "/api/keys", Security: sessionOnly, Tag: "keys", Summary: "List the API keys of the account",
+		Response: []entity.Key{}},
+	{Method: fiber.MethodGet, Path: "/api/keys/stale", Security: sessionOnly, Tag: "keys", Summary: "List the keys that expire or weren't used recently",
+		Query:    []openapi.Parameter{{Name: "days", Description: "keys expiring or unused within this many days, 30 by default", Schema: &openapi.Schema{Type: "integer"}}},
+		Response: []shared.StaleKey{}},
+	{Method: fiber.MethodPost, Path: "/api/keys/:id/rotate", Security: sessionOnly, Tag: "keys", Summary: "Give an API key a new secret",
+		Description: "The new secret is only returned here. The old secret remains valid for the grace period.",
+		Request:     shared.RotateAccessKeyRequest{}, Response: entity.Key{}},
+	{Method: fiber.MethodDelete, Path: "/api/keys/:id", Security: sessionOnly, Tag: "keys", Summary: "Delete an API key"},
+
+	{Method: fiber.MethodPost, Path: "/api/templates/upload-url", Scope: scopeTemplatesWrite, Tag: "templates", Summary: "Get a signed url to upload the content of a template to",
+		Request: shared.GetUploadURLRequest{}, Response: shared.UploadURLResponse{}},
+	{Method: fiber.MethodPost, Path: "/api/templates", Scope: scopeTemplatesWrite, Tag: "templates", Summary: "Create a template from uploaded content",
+		Request: shared.CreateTemplateRequest{}},
+	{Method: fiber.MethodGet, Path: "/api/templates", Scope: scopeTemplatesRead, Tag: "templates", Summary: "List the templates of the account",
+		Query: pageParams, Response: util.PaginationT[[]entity.Template]{}},
+	{Method: fiber.MethodGet, Path: "/api/templates/:id", Scope: scopeTemplatesRead, Tag: "templates", Summary: "Get a template version",
+		Description: "The ETag header is the If-Match value of updates.",
+		Response:    entity.Template{}},
+	{Method: fiber.MethodGet, Path: "/api/templates/:id/content", Scope: scopeTemplatesRead, Tag: "templates", Summary: "Get the content of a template version",
+		Description:         "Served with the content type of the template, supports If-None-Match.",
+		ResponseContentType: fiber.MIMETextHTML, Response: openapi.Binary{}},
+	{Method: fiber.MethodGet, Path: "/api/templates/:id/versions/:version/content", Scope: scopeTemplatesRead, Tag: "templates", Summary: "Get the content of another version of a template",
+		Description:         "Served with the content type of the template, supports If-None-Match.",
+		ResponseContentType: fiber.MIMETextHTML, Response: openapi.Binary{}},
+	{Method: fiber.MethodPut, Path: "/api/templates/:id", Scope: scopeTemplatesWrite, Tag: "templates", Summary: "Create a new version of a template",
+		Headers: ifMatchHeader, Request: shared.UpdateTemplateRequest{}, Errors: []int{fiber.StatusPreconditionFailed}},
+	{Method: fiber.MethodPut, Path: "/api/templates/edit/:id", Scope: scopeTemplatesWrite, Tag: "templates", Summary: "Change a draft version in place",
+		Headers: ifMatchHeader, Request: shared.UpdateTemplateRequest{}, Errors: []int{fiber.StatusPreconditionFailed}},
+	{Method: fiber.MethodDelete, Path: "/api/templates/:id", Scope: scopeTemplatesWrite, Tag: "templates", Summary: "Move a template version to the trash",
+		Request: shared.DeleteTemplateRequest{}},
+	{Method: fiber.MethodPost, Path: "/api/templates/:id/render", Scope: scopeRender, Tag: "templates", Summary: "Render a template",
+		Description: "The published version is rendered unless draft is set, API keys need the templates:read or templates:write scope to render drafts.",
+		Request:     shared.RenderTemplateRequest{}, Response: shared.RenderTemplateResponse{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodPost, Path: "/api/templates/:id/send", Scope: scopeSend, Tag: "templates", Summary: "Render a template and send it",
+		Description: "Sends made with a test API key are captured instead of delivered, see /api/test/messages. Other sends fail with 403 until the email of the account is verified. API keys need the templates:read or templates:write scope to send drafts.",
+		Request:     shared.SendTemplateRequest{}, Response: shared.SendTemplateResponse{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodPost, Path: "/api/templates/import", Scope: scopeTemplatesWrite, Tag: "templates", Summary: "Import a template from an email provider",
+		Request: shared.ImportTemplateRequest{}},
+	{Method: fiber.MethodPost, Path: "/api/templates/export", Scope: scopeTemplatesWrite, Tag: "templates", Summary: "Export a template to an email provider",
+		Request: shared.ExportTemplateRequest{}},
+
+	{Method: fiber.MethodGet, Path: "/api/test/messages", Scope: scopeSend, Tag: "test", Summary: "List the messages sent with test API keys",
+		Description: "Sends made with a test key (tm_test_) are rendered and checked like real ones, then stored here instead of being delivered.",
+		Query:       append([]openapi.Parameter{{Name: "to", Description: "only messages to this address", Schema: &openapi.Schema{Type: "string"}}}, pageParams...),
+		Response:    util.PaginationT[[]entity.CapturedMessage]{}},
+	{Method: fiber.MethodGet, Path: "/api/test/messages/:id", Scope: scopeSend, Tag: "test", Summary: "Get a message sent with a test API key",
+		Response: entity.CapturedMessage{}},
+	{Method: fiber.MethodDelete, Path: "/api/test/messages", Scope: scopeSend, Tag: "test", Summary: "Delete the messages sent with test API keys"},
+
+	{Method: fiber.MethodGet, Path: "/api/templates/:id/reviews", Scope: scopeTemplatesRead, Tag: "reviews", Summary: "List the review history of a template",
+		Response: []entity.TemplateReview{}},
+	{Method: fiber.MethodPost, Path: "/api/templates/:id/review", Scope: scopeTemplatesWrite, Tag: "reviews", Summary: "Submit a draft for review",
+		Request: shared.ReviewTemplateRequest{}, Response: entity.Template{}, Errors: []int{fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/templates/:id/approve", Scope: scopeTemplatesApprove, Tag: "reviews", Summary: "Approve a version in review",
+		Description: "Approval is an action of the account, the templates:approve scope decides who may approve. Keep it off the keys that submit versions to separate authors from reviewers.",
+		Request:     shared.ReviewTemplateRequest{}, Response: entity.Template{}, Errors: []int{fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/templates/:id/reject", Scope: scopeTemplatesApprove, Tag: "reviews", Summary: "Send a version in review back to draft",
+		Request: shared.ReviewTemplateRequest{}, Response: entity.Template{}, Errors: []int{fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/templates/:id/publish", Scope: scopeTemplatesApprove, Tag: "reviews", Summary: "Publish an approved version",
+		Request: shared.ReviewTemplateRequest{}, Response: entity.Template{}, Errors: []int{fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/templates/:id/archive", Scope: scopeTemplatesWrite, Tag: "reviews", Summary: "Archive a version",
+		Request: shared.ReviewTemplateRequest{}, Response: entity.Template{}, Errors: []int{fiber.StatusConflict}},
+
+	{Method: fiber.MethodPost, Path: "/api/templates/:id/schedules", Scope: scopeTemplatesApprove, Tag: "schedules", Summary: "Schedule the publishing of an approved version",
+		Request: shared.ScheduleTemplateRequest{}, Response: entity.TemplateSchedule{}},
+	{Method: fiber.MethodGet, Path: "/api/schedules", Scope: scopeTemplatesRead, Tag: "schedules", Summary: "List the schedules of the account",
+		Query:    []openapi.Parameter{{Name: "status", Schema: &openapi.Schema{Type: "string"}}},
+		Response: []entity.TemplateSchedule{}},
+	{Method: fiber.MethodDelete, Path: "/api/schedules/:id", Scope: scopeTemplatesApprove, Tag: "schedules", Summary: "Cancel a pending schedule"},
+
+	{Method: fiber.MethodGet, Path: "/api/bundles/export", Scope: scopeTemplatesRead, Tag: "bundles", Summary: "Export templates as a zip bundle",
+		Query: []openapi.Parameter{
+			{Name: "keys", Description: "comma separated slugs, all templates by default", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "latest_only", Description: "only export the latest version of every template", Schema: &openapi.Schema{Type: "boolean"}},
+		},
+		ResponseContentType: "application/zip", Response: openapi.Binary{}},
+	{Method: fiber.MethodPost, Path: "/api/bundles/import", Scope: scopeTemplatesWrite, Tag: "bundles", Summary: "Import a zip bundle",
+		Description: "The bundle is the body, or the bundle file of a multipart form. Keys must be slugs of lowercase letters, digits and hyphens, or slugs of this service. Imported versions are drafts.",
+		Query: []openapi.Parameter{
+			{Name: "conflict", Description: "what to do with keys that already exist, skip by default", Schema: &openapi.Schema{Type: "string", Enum: []any{shared.ConflictSkip, shared.ConflictOverwrite, shared.ConflictNewVersion}}},
+		},
+		Request: openapi.Binary{}, RequestContentType: "application/zip", Response: shared.ImportBundleResult{}},
+
+	{Method: fiber.MethodGet, Path: "/api/trash/templates", Scope: scopeTemplatesRead, Tag: "trash", Summary: "List the deleted templates",
+		Query: pageParams, Response: util.PaginationT[[]entity.Template]{}},
+	{Method: fiber.MethodPost, Path: "/api/trash/templates/:id/restore", Scope: scopeTemplatesWrite, Tag: "trash", Summary: "Restore a deleted template"},
+	{Method: fiber.MethodDelete, Path: "/api/trash/templates/:id", Scope: scopeTemplatesWrite, Tag: "trash", Summary: "Permanently delete a template"},
+
+	{Method: fiber.MethodGet, Path: "/api/analytics/summary", Scope: scopeTemplatesRead, Tag: "analytics", Summary: "Renders, sends, error rate and p95 latency of the account",
+		Query: analyticsParams, Response: shared.UsageSummary{}},
+	{Method: fiber.MethodGet, Path: "/api/analytics/daily", Scope: scopeTemplatesRead, Tag: "analytics", Summary: "Renders and sends per day",
+		Query: analyticsParams, Response: []shared.DailyStat{}},
+	{Method: fiber.MethodGet, Path: "/api/analytics/templates/top", Scope: scopeTemplatesRead, Tag: "analytics", Summary: "Most used templates",
+		Query: analyticsParams, Response: []shared.TemplateStat{}},
+	{Method: fiber.MethodGet, Path: "/api/analytics/templates/unused", Scope: scopeTemplatesRead, Tag: "analytics", Summary: "Templates that were not used",
+		Query: analyticsParams, Response: []entity.Template{}},
+
+	{Method: fiber.MethodPost, Path: "/api/webhooks", Security: sessionOnly, Tag: "webhooks", Summary: "Create a webhook",
+		Description: "The signing secret is only returned here.",
+		Request:     shared.CreateWebhookRequest{}, Response: entity.Webhook{}},
+	{Method: fiber.MethodGet, Path: "/api/webhooks", Security: sessionOnly, Tag: "webhooks", Summary: "List the webhooks of the account",
+		Response: []entity.Webhook{}},
+	{Method: fiber.MethodGet, Path: "/api/webhooks/:id", Security: sessionOnly, Tag: "webhooks", Summary: "Get a webhook",
+		Response: entity.Webhook{}},
+	{Method: fiber.MethodPut, Path: "/api/webhooks/:id", Security: sessionOnly, Tag: "webhooks", Summary: "Update a webhook",
+		Request: shared.UpdateWebhookRequest{}, Response: entity.Webhook{}},
+	{Method: fiber.MethodDelete, Path: "/api/webhooks/:id", Security: sessionOnly, Tag: "webhooks", Summary: "Delete a webhook"},
+	{Method: fiber.MethodGet, Path: "/api/webhooks/:id/deliveries", Security: sessionOnly, Tag: "webhooks", Summary: "List the deliveries of a webhook",
+		Query: append([]openapi.Parameter{
+			{Name: "status", Schema: &openapi.Schema{Type: "string", Enum: []any{entity.DeliveryStatusPending, entity.DeliveryStatusSucceeded, entity.DeliveryStatusFailed}}},
+		}, pageParams...),
+		Response: util.PaginationT[[]entity.WebhookDelivery]{}},
+	{Method: fiber.MethodPost, Path: "/api/webhooks/deliveries/:id/redeliver", Security: sessionOnly, Tag: "webhooks", Summary: "Send a delivery again",
+		Response: entity.WebhookDelivery{}},
+
+	{Method: fiber.MethodPost, Path: "/api/credentials", Scope: scopeCredentials, Tag: "credentials", Summary: "Add the credentials of a provider",
+		Request: shared.CredentialInput{}},
+	{Method: fiber.MethodGet, Path: "/api/credentials", Scope: scopeCredentials, Tag: "credentials", Summary: "List the credentials of the account",
+		Response: []entity.Credential{}},
+	{Method: fiber.MethodGet, Path: "/api/credentials/:id", Scope: scopeCredentials, Tag: "credentials", Summary: "Get credentials",
+		Response: entity.Credential{}},
+	{Method: fiber.MethodPut, Path: "/api/credentials", Scope: scopeCredentials, Tag: "credentials", Summary: "Update credentials",
+		Request: shared.CredentialInput{}},
+	{Method: fiber.MethodDelete, Path: "/api/credentials/:id", Scope: scopeCredentials, Tag: "credentials", Summary: "Delete credentials"},
+}
+
+// the scopes API keys need, see entity.KeyScope
+const (
+	scopeTemplatesRead    = string(entity.KeyScopeTemplatesRead)
+	scopeTemplatesWrite   = string(entity.KeyScopeTemplatesWrite)
+	scopeTemplatesApprove = string(entity.KeyScopeTemplatesApprove)
+	scopeRender           = string(entity.KeyScopeRender)
+	scopeSend             = string(entity.KeyScopeSend)
+	scopeCredentials      = string(entity.KeyScopeCredentialsManage)
+)
+
+// sessionOnly is the security of the routes API keys can't call
+var sessionOnly = []string{"session"}
+
+var securitySchemes = map[string]openapi.SecurityScheme{
+	"session": {Type: "http", Scheme: "bearer", Description: "the token of a session, see /api/users/login"},
+	"apiKey":  {Type: "apiKey", In: "header", Name: middleware.APIKeyHeader, Description: "the secret of an API key, see /api/keys"},
+}
+
+// openAPIDocument is built once, the first time it is needed
+var openAPIDocument = sync.OnceValue(func() *openapi.Document {
+	return openapi.Build(openapi.Info{
+		Title:   "Template Manager API",
+		Version: shared.APIVersion,
+		Description: "Successful JSON responses are wrapped in {status, message, data}. " +
+			"Failures answer 400 with the errors by field, 401 without a body, or 422 with a message.",
+	}, operations, securitySchemes)
+})
+
+var openAPISpec = sync.OnceValues(func() ([]byte, error) {
+	return json.Marshal(openAPIDocument())
+})
+
+func (s *server) OpenAPI(c *fiber.Ctx) error {
+	spec, err := openAPISpec()
+	if err != nil {
+		return HandleError(c, err)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(spec)
+}
+
+func (s *server) APIDocs(c *fiber.Ctx) error {
+	page, err := docsPage()
+	if err != nil {
+		return HandleError(c, err)
+	}
+	// the page is self-contained, nothing else may load on the API origin
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(page)
+}
+
+// docsPage lists the operations by tag. It is rendered here rather than with
+// a third party viewer so no script from outside runs on the API origin, such
+// viewers can load /api/openapi.json instead.
+var docsPage = sync.OnceValues(func() ([]byte, error) {
+	type docsOperation struct {
+		openapi.Operation
+		Access string
+	}
+	type docsGroup struct {
+		Tag        string
+		Operations []docsOperation
+	}
+
+	var groups []*docsGroup
+	byTag := make(map[string]*docsGroup)
+	for _, op := range operations {
+		group, ok := byTag[op.Tag]
+		if !ok {
+			group = &docsGroup{Tag: op.Tag}
+			byTag[op.Tag] = group
+			groups = append(groups, group)
+		}
+		access := "session or API key"
+		switch {
+		case op.Public:
+			access = "public"
+		case len(op.Security) > 0:
+			access = "session only"
+		case op.Scope != "":
+			access = "session or API key with the " + op.Scope + " scope"
+		}
+		group.Operations = append(group.Operations, docsOperation{Operation: op, Access: access})
+	}
+
+	var buf bytes.Buffer
+	err := docsTemplate.Execute(&buf, map[string]any{"Version": shared.APIVersion, "Groups": groups})
+	return buf.Bytes(), err
+})
+
+var docsTemplate = htmltemplate.Must(htmltemplate.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Template Manager API</title>
+  <style>
+    body { font-family: sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+    .op { border-top: 1px solid #ddd; padding: .5rem 0; }
+    .method { display: inline-block; width: 4.5rem; font-weight: bold; }
+    code { font-size: 1rem; }
+    .access { color: #666; font-size: .9rem; }
+  </style>
+</head>
+<body>
+  <h1>Template Manager API {{.Version}}</h1>
+  <p>The schemas of the requests and responses are in the <a href="/api/openapi.json">OpenAPI document</a>, load it into any OpenAPI viewer or client generator.</p>
+  {{range .Groups}}
+  <h2>{{.Tag}}</h2>
+  {{range .Operations}}
+  <div class="op">
+    <span class="method">{{.Method}}</span><code>{{.Path}}</code> {{.Summary}}
+    <div class="access">{{.Access}}</div>
+    {{with .Description}}<p>{{.}}</p>{{end}}
+  </div>
+  {{end}}
+  {{end}}
+</body>
+</html>
+`))
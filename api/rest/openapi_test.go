@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// TestRoutesDocumented fails when a route is added without its operation, or
+// an operation is left behind after its route was removed
+func TestRoutesDocumented(t *testing.T) {
+	// a served storage registers the storage routes too
+	app := newStorageApp(t, newLocalStorage(t))
+	doc := openAPIDocument()
+
+	routes := make(map[string]bool)
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue // added by fiber for every GET route
+		}
+		routes[route.Method+" "+route.Path] = true
+		if !doc.Has(route.Method, route.Path) {
+			t.Errorf("%s %s is missing from the openapi document", route.Method, route.Path)
+		}
+	}
+	for _, op := range operations {
+		if !routes[op.Method+" "+op.Path] {
+			t.Errorf("%s %s is documented but not routed", op.Method, op.Path)
+		}
+	}
+}
+
+func TestDocsPageIsSelfContained(t *testing.T) {
+	app := newStorageApp(t, newLocalStorage(t))
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/docs", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if strings.Contains(string(body), "<script") {
+		t.Error("the docs page loads scripts")
+	}
+	if !strings.Contains(string(body), "/api/templates/:id/render") {
+		t.Error("the docs page is missing the operations")
+	}
+	if resp.Header.Get(fiber.HeaderContentSecurityPolicy) == "" {
+		t.Error("the docs page has no Content-Security-Policy")
+	}
+}
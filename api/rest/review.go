@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"context"
+	"errors"
+
+	"template-manager/internal/app/template"
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+type reviewFunc func(ctx context.Context, req shared.ReviewTemplateRequest) (*entity.Template, error)
+
+// reviewHandler builds the handlers of the template lifecycle endpoints, they
+// only differ in the transition they apply
+func (s *server) reviewHandler(transition reviewFunc, message string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req shared.ReviewTemplateRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return HandleBadRequest(c, err)
+			}
+		}
+		req.AccountID = c.Locals("account_id").(string)
+		req.TemplateID = c.Params("id")
+		req.Actor = actor(c)
+
+		if err := req.Validate(); err != nil {
+			return HandleBadRequest(c, err)
+		}
+
+		updated, err := transition(c.Context(), req)
+		if errors.Is(err, template.ErrStatusChanged) {
+			c.Status(fiber.StatusConflict)
+			return c.JSON(fiber.Map{"status": false, "message": err.Error()})
+		}
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return HandleSuccess(c, message, updated)
+	}
+}
+
+// actor identifies who made the request, see shared.ReviewTemplateRequest.Actor
+func actor(c *fiber.Ctx) string {
+	if key, ok := c.Locals("api_key").(*entity.Key); ok {
+		return "key:" + key.ID
+	}
+	return "session"
+}
+
+func (s *server) ListTemplateReviews(c *fiber.Ctx) error {
+	var req = shared.GetTemplateRequest{
+		AccountID:  c.Locals("account_id").(string),
+		TemplateID: c.Params("id"),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	reviews, err := s.templateApp.ListReviews(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "template reviews retrieved successfully", reviews)
+}
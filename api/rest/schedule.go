@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"template-manager/internal/shared"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func (s *server) ScheduleTemplate(c *fiber.Ctx) error {
+	var req shared.ScheduleTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	req.AccountID = c.Locals("account_id").(string)
+	req.TemplateID = c.Params("id")
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	schedule, err := s.templateApp.Schedule(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "template scheduled successfully", schedule)
+}
+
+func (s *server) ListSchedules(c *fiber.Ctx) error {
+	var req = shared.ListSchedulesRequest{
+		AccountID: c.Locals("account_id").(string),
+		Status:    c.Query("status"),
+	}
+
+	schedules, err := s.templateApp.ListSchedules(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "schedules retrieved successfully", schedules)
+}
+
+func (s *server) CancelSchedule(c *fiber.Ctx) error {
+	var req = shared.CancelScheduleRequest{
+		AccountID:  c.Locals("account_id").(string),
+		ScheduleID: c.Params("id"),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	if err := s.templateApp.CancelSchedule(c.Context(), req); err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "schedule cancelled successfully", nil)
+}
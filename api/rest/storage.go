@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"template-manager/pkg/uploader"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// UploadFile receives uploads to signed URLs of the local and postgres storage
+func (s *server) UploadFile(c *fiber.Ctx) error {
+	served, filename, query, ok := s.servedFile(c, http.MethodPut)
+	if !ok {
+		return nil
+	}
+
+	contentType := c.Get(fiber.HeaderContentType)
+	if signed := query.Get("content_type"); signed != "" && !sameMediaType(signed, contentType) {
+		return HandleBadRequest(c, errors.New("content type does not match the upload url"))
+	}
+
+	info, err := served.PutFile(c.Context(), filename, contentType, bytes.NewReader(c.Body()))
+	if err != nil {
+		return HandleError(c, err)
+	}
+	c.Set(fiber.HeaderETag, info.ETag)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// DownloadFile serves signed download URLs of the local and postgres storage
+func (s *server) DownloadFile(c *fiber.Ctx) error {
+	served, filename, _, ok := s.servedFile(c, http.MethodGet)
+	if !ok {
+		return nil
+	}
+
+	file, info, err := served.GetFile(c.Context(), filename)
+	if errors.Is(err, uploader.ErrNotFound) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if err != nil {
+		return HandleError(c, err)
+	}
+	c.Set(fiber.HeaderContentType, info.ContentType)
+	c.Set(fiber.HeaderETag, info.ETag)
+	return c.SendStream(file, int(info.Size))
+}
+
+// servedFile checks the signature of the request. When it isn't ok the
+// rejection is already written to the response and the handler has to stop.
+func (s *server) servedFile(c *fiber.Ctx, method string) (uploader.Served, string, url.Values, bool) {
+	served, ok := s.storage.(uploader.Served)
+	if !ok {
+		c.Status(fiber.StatusNotFound)
+		return nil, "", nil, false
+	}
+	filename, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		_ = HandleBadRequest(c, err)
+		return nil, "", nil, false
+	}
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		_ = HandleBadRequest(c, err)
+		return nil, "", nil, false
+	}
+	if err := served.VerifySignature(method, filename, query); err != nil {
+		c.Status(fiber.StatusForbidden)
+		_ = c.JSON(fiber.Map{"status": false, "message": err.Error()})
+		return nil, "", nil, false
+	}
+	return served, filename, query, true
+}
+
+func sameMediaType(a, b string) bool {
+	mediaA, _, errA := mime.ParseMediaType(a)
+	mediaB, _, errB := mime.ParseMediaType(b)
+	return errA == nil && errB == nil && mediaA == mediaB
+}
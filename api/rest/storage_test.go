@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/pkg/uploader"
+	"template-manager/pkg/uploader/local"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+const storageBaseURL = "http://templates.test"
+
+// openMiddleware lets every request through as account "acc", the storage
+// routes are authorized by their signature
+type openMiddleware struct{}
+
+func (openMiddleware) FiberAuthMiddleware(c *fiber.Ctx) error {
+	c.Locals("account_id", "acc")
+	return c.Next()
+}
+func (openMiddleware) CorsMiddleware(c *fiber.Ctx) error { return c.Next() }
+func (openMiddleware) RequireSession(c *fiber.Ctx) error { return c.Next() }
+func (openMiddleware) RequireScope(entity.KeyScope) fiber.Handler {
+	return func(c *fiber.Ctx) error { return c.Next() }
+}
+
+// unservedStorage can't serve files, like s3 whose signed URLs point at the bucket
+type unservedStorage struct {
+	uploader.Uploader
+}
+
+func newStorageApp(t *testing.T, storage uploader.Uploader) *fiber.App {
+	t.Helper()
+	return server{storage: storage, middleware: openMiddleware{}}.routes()
+}
+
+func newLocalStorage(t *testing.T) *local.Local {
+	t.Helper()
+	storage, err := local.New(t.TempDir(), storageBaseURL, []byte("signing key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+// do sends method to the path and query of the signed url
+func do(t *testing.T, app *fiber.App, method, signed, contentType, body string) *http.Response {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, u.RequestURI(), strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(fiber.HeaderContentType, contentType)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestUploadAndDownloadFile(t *testing.T) {
+	storage := newLocalStorage(t)
+	app := newStorageApp(t, storage)
+
+	upload := storage.SignedURL(http.MethodPut, "acc/welcome.html", "text/html", time.Minute)
+	if resp := do(t, app, http.MethodPut, upload, "text/html", "<p>hi</p>"); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("upload status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+
+	download := storage.SignedURL(http.MethodGet, "acc/welcome.html", "", time.Minute)
+	resp := do(t, app, http.MethodGet, download, "", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("download status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "<p>hi</p>" {
+		t.Errorf("download body = %q", body)
+	}
+}
+
+func TestStorageRejectsBadSignature(t *testing.T) {
+	storage := newLocalStorage(t)
+	app := newStorageApp(t, storage)
+
+	upload := storage.SignedURL(http.MethodPut, "acc/welcome.html", "text/html", time.Minute)
+	tampered := strings.Replace(upload, "signature=", "signature=00", 1)
+	if resp := do(t, app, http.MethodPut, tampered, "text/html", "<p>hi</p>"); resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("upload status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+	// an upload url can't be used to download
+	if resp := do(t, app, http.MethodGet, upload, "", ""); resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("download status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+	if _, err := storage.GetFileDetails(context.Background(), "acc/welcome.html"); err == nil {
+		t.Error("file was written despite the bad signature")
+	}
+}
+
+func TestStorageRejectsExpiredSignature(t *testing.T) {
+	storage := newLocalStorage(t)
+	app := newStorageApp(t, storage)
+
+	upload := storage.SignedURL(http.MethodPut, "acc/welcome.html", "text/html", -time.Minute)
+	resp := do(t, app, http.MethodPut, upload, "text/html", "<p>hi</p>")
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("upload status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), uploader.ErrURLExpired.Error()) {
+		t.Errorf("upload body = %s, want %q", body, uploader.ErrURLExpired)
+	}
+	if _, err := storage.GetFileDetails(context.Background(), "acc/welcome.html"); err == nil {
+		t.Error("file was written with an expired url")
+	}
+}
+
+func TestStorageRoutesNeedServedDriver(t *testing.T) {
+	signer := uploader.Signer{BaseURL: storageBaseURL, Key: []byte("signing key")}
+	app := newStorageApp(t, unservedStorage{})
+
+	upload := signer.SignedURL(http.MethodPut, "acc/welcome.html", "text/html", time.Minute)
+	if resp := do(t, app, http.MethodPut, upload, "text/html", "<p>hi</p>"); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("upload status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+	download := signer.SignedURL(http.MethodGet, "acc/welcome.html", "", time.Minute)
+	if resp := do(t, app, http.MethodGet, download, "", ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("download status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+}
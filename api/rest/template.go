@@ -1,6 +1,13 @@
 package rest
 
 import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"template-manager/internal/app/template"
+	"template-manager/internal/entity"
 	"template-manager/internal/shared"
 
 	fiber "github.com/gofiber/fiber/v2"
@@ -52,12 +59,67 @@ func (s *server) GetTemplate(c *fiber.Ctx) error {
 		return HandleBadRequest(c, err)
 	}
 
-	template, err := s.templateApp.Get(c.Context(), req)
+	found, err := s.templateApp.Get(c.Context(), req)
 	if err != nil {
 		return HandleError(c, err)
 	}
 
-	return HandleSuccess(c, "template retrieved successfully", template)
+	c.Set(fiber.HeaderETag, found.ETag())
+	return HandleSuccess(c, "template retrieved successfully", found)
+}
+
+// GetTemplateContent serves the body of a template so clients don't need
+// access to the storage. Only drafts can change, so other versions are cached.
+func (s *server) GetTemplateContent(c *fiber.Ctx) error {
+	var req = shared.TemplateContentRequest{
+		AccountID:  c.Locals("account_id").(string),
+		TemplateID: c.Params("id"),
+	}
+	if version := c.Params("version"); version != "" {
+		v, err := strconv.ParseUint(version, 10, 64)
+		if err != nil {
+			return HandleBadRequest(c, err)
+		}
+		req.Version = v
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	found, content, err := s.templateApp.Content(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, `"`+found.ContentHash+`"`)
+	c.Set(fiber.HeaderLastModified, found.UpdatedAt.UTC().Format(http.TimeFormat))
+	if found.Status == entity.TemplateStatusDraft {
+		c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	} else {
+		c.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+	}
+	if fresh(c) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, found.ContentType)
+	return c.Send(content)
+}
+
+// fresh reports whether the client already has the response, based on the ETag set on it
+func fresh(c *fiber.Ctx) bool {
+	ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch)
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag := string(c.Response().Header.Peek(fiber.HeaderETag))
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *server) ListTemplates(c *fiber.Ctx) error {
@@ -86,13 +148,14 @@ func (s *server) UpdateTemplate(c *fiber.Ctx) error {
 	}
 	req.AccountID = c.Locals("account_id").(string)
 	req.TemplateID = c.Params("id")
+	req.IfMatch = c.Get(fiber.HeaderIfMatch)
 
 	if err := req.Validate(); err != nil {
 		return HandleBadRequest(c, err)
 	}
 
 	if err := s.templateApp.Update(c.Context(), req); err != nil {
-		return HandleError(c, err)
+		return handleTemplateWriteError(c, err)
 	}
 	return HandleSuccess(c, "template updated successfully", nil)
 }
@@ -104,13 +167,14 @@ func (s *server) EditTemplate(c *fiber.Ctx) error {
 	}
 	req.AccountID = c.Locals("account_id").(string)
 	req.TemplateID = c.Params("id")
+	req.IfMatch = c.Get(fiber.HeaderIfMatch)
 
 	if err := req.Validate(); err != nil {
 		return HandleBadRequest(c, err)
 	}
 
 	if err := s.templateApp.Edit(c.Context(), req); err != nil {
-		return HandleError(c, err)
+		return handleTemplateWriteError(c, err)
 	}
 	return HandleSuccess(c, "template updated successfully", nil)
 }
@@ -167,3 +231,74 @@ func (s *server) ExportTemplate(c *fiber.Ctx) error {
 	}
 	return HandleSuccess(c, "template exported successfully", nil)
 }
+
+func (s *server) RenderTemplate(c *fiber.Ctx) error {
+	var req shared.RenderTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	req.AccountID = c.Locals("account_id").(string)
+	req.TemplateID = c.Params("id")
+	if key, ok := c.Locals("api_key").(*entity.Key); ok && req.Draft && !key.Scopes.ReadsDrafts() {
+		c.Status(fiber.StatusForbidden)
+		return c.JSON(fiber.Map{"status": false, "message": entity.ErrDraftScope.Error()})
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	rendered, err := s.templateApp.Render(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "template rendered successfully", rendered)
+}
+
+func (s *server) SendTemplate(c *fiber.Ctx) error {
+	var req shared.SendTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	req.AccountID = c.Locals("account_id").(string)
+	req.TemplateID = c.Params("id")
+	if key, ok := c.Locals("api_key").(*entity.Key); ok {
+		if req.Draft && !key.Scopes.ReadsDrafts() {
+			c.Status(fiber.StatusForbidden)
+			return c.JSON(fiber.Map{"status": false, "message": entity.ErrDraftScope.Error()})
+		}
+		if key.Test {
+			req.TestKeyID = key.ID
+		}
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	sent, err := s.templateApp.Send(c.Context(), req)
+	if errors.Is(err, template.ErrUnverifiedAccount) {
+		c.Status(fiber.StatusForbidden)
+		return c.JSON(fiber.Map{"status": false, "message": err.Error()})
+	}
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "template sent successfully", sent)
+}
+
+// handleTemplateWriteError answers stale writes with 412 and the current state
+// of the template so the client can merge and retry
+func handleTemplateWriteError(c *fiber.Ctx, err error) error {
+	var stale *template.PreconditionFailedError
+	if !errors.As(err, &stale) {
+		return HandleError(c, err)
+	}
+	c.Set(fiber.HeaderETag, stale.Current.ETag())
+	c.Status(fiber.StatusPreconditionFailed)
+	return c.JSON(fiber.Map{
+		"status":  false,
+		"message": err.Error(),
+		"data":    stale.Current,
+	})
+}
@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"template-manager/internal/shared"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func (s *server) ListTrash(c *fiber.Ctx) error {
+	var req = shared.ListTemplatesRequest{
+		AccountID: c.Locals("account_id").(string),
+		Page:      c.QueryInt("page", 1),
+		PageSize:  c.QueryInt("page_size", 10),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	templates, err := s.templateApp.ListTrash(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "deleted templates retrieved successfully", templates)
+}
+
+func (s *server) RestoreTemplate(c *fiber.Ctx) error {
+	var req = shared.GetTemplateRequest{
+		AccountID:  c.Locals("account_id").(string),
+		TemplateID: c.Params("id"),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	if err := s.templateApp.Restore(c.Context(), req); err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "template restored successfully", nil)
+}
+
+func (s *server) PurgeTemplate(c *fiber.Ctx) error {
+	var req = shared.GetTemplateRequest{
+		AccountID:  c.Locals("account_id").(string),
+		TemplateID: c.Params("id"),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	if err := s.templateApp.Purge(c.Context(), req); err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "template permanently deleted", nil)
+}
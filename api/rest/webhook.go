@@ -0,0 +1,123 @@
+package rest
+
+import (
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func (s *server) AddWebhook(c *fiber.Ctx) error {
+	var req shared.CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	req.AccountID = c.Locals("account_id").(string)
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	webhook, err := s.webhookApp.Create(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "webhook created successfully, store the secret as it will not be shown again", webhook)
+}
+
+func (s *server) ListWebhooks(c *fiber.Ctx) error {
+	webhooks, err := s.webhookApp.List(c.Context(), c.Locals("account_id").(string))
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "webhooks retrieved successfully", webhooks)
+}
+
+func (s *server) GetWebhook(c *fiber.Ctx) error {
+	var req = shared.WebhookRequest{
+		AccountID: c.Locals("account_id").(string),
+		WebhookID: c.Params("id"),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	webhook, err := s.webhookApp.Get(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "webhook retrieved successfully", webhook)
+}
+
+func (s *server) UpdateWebhook(c *fiber.Ctx) error {
+	var req shared.UpdateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return HandleBadRequest(c, err)
+	}
+	req.AccountID = c.Locals("account_id").(string)
+	req.WebhookID = c.Params("id")
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	webhook, err := s.webhookApp.Update(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "webhook updated successfully", webhook)
+}
+
+func (s *server) DeleteWebhook(c *fiber.Ctx) error {
+	var req = shared.WebhookRequest{
+		AccountID: c.Locals("account_id").(string),
+		WebhookID: c.Params("id"),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	if err := s.webhookApp.Delete(c.Context(), req); err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "webhook deleted successfully", nil)
+}
+
+func (s *server) ListWebhookDeliveries(c *fiber.Ctx) error {
+	var req = shared.ListDeliveriesRequest{
+		AccountID: c.Locals("account_id").(string),
+		WebhookID: c.Params("id"),
+		Status:    entity.DeliveryStatus(c.Query("status")),
+		Page:      c.QueryInt("page", 1),
+		PageSize:  c.QueryInt("page_size", 10),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	deliveries, err := s.webhookApp.ListDeliveries(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "deliveries retrieved successfully", deliveries)
+}
+
+func (s *server) RedeliverWebhook(c *fiber.Ctx) error {
+	var req = shared.RedeliverRequest{
+		AccountID:  c.Locals("account_id").(string),
+		DeliveryID: c.Params("id"),
+	}
+
+	if err := req.Validate(); err != nil {
+		return HandleBadRequest(c, err)
+	}
+
+	delivery, err := s.webhookApp.Redeliver(c.Context(), req)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return HandleSuccess(c, "webhook redelivered", delivery)
+}
@@ -1,23 +1,37 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"template-manager/internal/app"
+	"time"
 
-	// "template-manager/internal/entity"
-
+	"template-manager/api/grpc"
 	"template-manager/api/middleware"
 	"template-manager/api/rest"
+	"template-manager/internal/app/analytics"
+	"template-manager/internal/app/auth"
 	"template-manager/internal/app/credential"
 	"template-manager/internal/app/session"
+	"template-manager/internal/app/webhook"
+	"template-manager/internal/entity"
+	"template-manager/internal/migration"
+	"template-manager/internal/pkg/email"
 	"template-manager/internal/pkg/email/mailjet"
+	"template-manager/pkg/cache"
 	"template-manager/pkg/config"
 	"template-manager/pkg/database"
 	"template-manager/pkg/repository"
+	"template-manager/pkg/uploader"
+	"template-manager/pkg/uploader/local"
+	"template-manager/pkg/uploader/postgres"
+	"template-manager/pkg/uploader/s3"
 )
 
 func cleanPort(port string) string {
@@ -28,11 +42,30 @@ func cleanPort(port string) string {
 }
 
 func main() {
-	var server, port string
+	if len(os.Args) > 1 && os.Args[1] == "sync" {
+		if err := runSync(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var server, port, restPort string
 	flag.StringVar(&server, "server", "rest", "grpc or rest")
 	flag.StringVar(&port, "port", "8080", "port to listen on")
+	flag.StringVar(&restPort, "rest-port", "8080", "port of the REST API served next to grpc, for health checks and the signed storage urls")
 	flag.Parse()
 	port = cleanPort(port)
+	switch server {
+	case "rest":
+		restPort = port
+	case "grpc":
+		restPort = cleanPort(restPort)
+		if restPort == port {
+			log.Fatalf("-port and -rest-port must differ with -server grpc")
+		}
+	default:
+		log.Fatalf("unknown server %q, use grpc or rest", server)
+	}
 
 	conf := loadConfig()
 
@@ -43,48 +76,158 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// TODO: migrated this to repository.NewRepository()
-	// err = db.Client.AutoMigrate(
-	// 	&entity.Account{},
-	// 	&entity.Key{},
-	// 	&entity.Session{},
-	// 	&entity.Template{},
-	// 	&entity.TemplateSync{},
-	// )
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
+	models := []any{
+		&entity.Account{},
+		&entity.Key{},
+		&entity.Session{},
+		&entity.Credential{},
+		&entity.Template{},
+		&entity.TemplateSync{},
+		&entity.TemplateReview{},
+		&entity.TemplateSchedule{},
+		&entity.TemplateEvent{},
+		&entity.Webhook{},
+		&entity.WebhookDelivery{},
+		&entity.CapturedMessage{},
+		&entity.AccountToken{},
+	}
+	if conf.GetString("STORAGE_DRIVER") == "postgres" {
+		models = append(models, &postgres.File{})
+	}
+	if err := migration.Run(db.Client, models...); err != nil {
+		log.Fatal(err)
+	}
+	mailjetOpts := []mailjet.Option{mailjet.WithName("template manager")}
+	if id := conf.GetString("MAILJET_PASSWORD_RESET_TEMPLATE_ID"); id != "" {
+		templateID, err := strconv.Atoi(id)
+		if err != nil {
+			log.Fatalf("invalid MAILJET_PASSWORD_RESET_TEMPLATE_ID: %v", err)
+		}
+		mailjetOpts = append(mailjetOpts, mailjet.WithTemplate(email.TemplateIDPasswordReset, templateID))
+	}
 	mj := mailjet.New(
 		conf.GetString("MAILJET_PUBLIC_KEY"),
 		conf.GetString("MAILJET_PRIVATE_KEY"),
 		conf.GetString("MAILJET_DEFAULT_SENDER"),
-		mailjet.WithName("template manager"),
+		mailjetOpts...,
 	)
 	logger := slog.Default()
 	sessionManager := session.New(db.Client, conf, logger)
-	midware := middleware.NewAuth(sessionManager)
 	repo := repository.NewRepositoryContainer(db)
-	credentialManager := credential.New(repo)
+	// signed storage urls are served by the REST API in both modes
+	storage, err := newStorage(conf, db, restPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhookApp := webhook.New(logger, repo)
+	credentialManager := credential.New(repo, webhookApp)
 
-	apps := app.NewApp(conf, mj, logger, repo, sessionManager)
+	analyticsApp := analytics.New(db.Client, logger)
+	broadcaster := cache.NewBroadcaster(db.Client, conf.GetString("POSTGRES_DSN"), "template_cache", logger)
+
+	passwords, err := auth.NewPasswordPolicy(conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	apps := app.NewApp(conf, mj, logger, repo, storage, sessionManager, analyticsApp, webhookApp, broadcaster, passwords)
+	midware := middleware.NewAuth(sessionManager, apps.AuthApp)
+	go func() {
+		if err := broadcaster.Listen(context.Background(), apps.TemplateApp.EvictCache); err != nil {
+			logger.Error("template cache invalidation listener stopped", "err", err)
+		}
+	}()
+	go apps.TemplateApp.RunScheduler(context.Background(), 30*time.Second)
+	go apps.TemplateApp.RunTrashPurger(context.Background(), time.Hour)
+	go webhookApp.RunDispatcher(context.Background(), 15*time.Second)
+	go analyticsApp.RunRecorder(context.Background(), 4)
 
 	restApp := rest.New(
 		conf,
+		server == "grpc",
 		apps.AuthApp,
 		apps.TemplateApp,
 		credentialManager,
+		analyticsApp,
+		webhookApp,
+		storage,
 		midware,
 	)
+
+	if server == "grpc" {
+		grpcApp := grpc.New(
+			logger,
+			sessionManager,
+			apps.AuthApp,
+			apps.TemplateApp,
+			credentialManager,
+		)
+		go func() {
+			log.Fatal(restApp.Listen(restPort))
+		}()
+		log.Fatal(grpcApp.Listen(port))
+	}
 	log.Fatal(restApp.Listen(port))
 }
 
+// newStorage returns the content storage selected by STORAGE_DRIVER, s3 by default
+func newStorage(conf *config.Config, db *database.PostgresClient, port string) (uploader.Uploader, error) {
+	baseURL := conf.GetString("STORAGE_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost" + port
+	}
+	signingKey := conf.GetString("STORAGE_SIGNING_KEY")
+	if signingKey == "" {
+		signingKey = conf.GetString("JWT_SIGNING_KEY")
+	}
+
+	switch conf.GetString("STORAGE_DRIVER") {
+	case "local":
+		root := conf.GetString("STORAGE_LOCAL_PATH")
+		if root == "" {
+			root = "./data/storage"
+		}
+		return local.New(root, baseURL, []byte(signingKey))
+	case "postgres":
+		return postgres.New(db.Client, baseURL, []byte(signingKey))
+	case "", "s3":
+		bucket := conf.GetString("S3_BUCKET")
+		if bucket == "" {
+			bucket = "template-manager-service"
+		}
+		region := conf.GetString("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return s3.NewS3(bucket, region, "", "")
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", conf.GetString("STORAGE_DRIVER"))
+	}
+}
+
 func loadConfig() *config.Config {
 	conf := config.New().
 		SetEnv("MAILJET_DOMAIN", os.Getenv("MAILJET_DOMAIN")).
 		SetEnv("MAILJET_PRIVATE_KEY", os.Getenv("MAILJET_PRIVATE_KEY")).
 		SetEnv("MAILJET_PUBLIC_KEY", os.Getenv("MAILJET_PUBLIC_KEY")).
 		SetEnv("MAILJET_DEFAULT_SENDER", os.Getenv("MAILJET_DEFAULT_SENDER")).
+		SetEnv("MAILJET_PASSWORD_RESET_TEMPLATE_ID", os.Getenv("MAILJET_PASSWORD_RESET_TEMPLATE_ID")).
 		SetEnv("POSTGRES_DSN", os.Getenv("POSTGRES_DSN")).
-		SetEnv("JWT_SIGNING_KEY", os.Getenv("JWT_SIGNING_KEY"))
+		SetEnv("ENVIRONMENT", os.Getenv("ENVIRONMENT")).
+		SetEnv("STORAGE_DRIVER", os.Getenv("STORAGE_DRIVER")).
+		SetEnv("STORAGE_BASE_URL", os.Getenv("STORAGE_BASE_URL")).
+		SetEnv("STORAGE_SIGNING_KEY", os.Getenv("STORAGE_SIGNING_KEY")).
+		SetEnv("STORAGE_LOCAL_PATH", os.Getenv("STORAGE_LOCAL_PATH")).
+		SetEnv("S3_BUCKET", os.Getenv("S3_BUCKET")).
+		SetEnv("S3_REGION", os.Getenv("S3_REGION")).
+		SetEnv("TEMPLATE_CACHE_ENTRIES", os.Getenv("TEMPLATE_CACHE_ENTRIES")).
+		SetEnv("TEMPLATE_CACHE_MAX_BYTES", os.Getenv("TEMPLATE_CACHE_MAX_BYTES")).
+		SetEnv("TEMPLATE_CACHE_TTL", os.Getenv("TEMPLATE_CACHE_TTL")).
+		SetEnv("JWT_SIGNING_KEY", os.Getenv("JWT_SIGNING_KEY")).
+		SetEnv("APP_URL", os.Getenv("APP_URL")).
+		SetEnv("PASSWORD_MIN_LENGTH", os.Getenv("PASSWORD_MIN_LENGTH")).
+		SetEnv("PASSWORD_BREACHED_LIST", os.Getenv("PASSWORD_BREACHED_LIST")).
+		SetEnv("TEMPLATE_TRASH_RETENTION_DAYS", os.Getenv("TEMPLATE_TRASH_RETENTION_DAYS"))
 	return conf
 }
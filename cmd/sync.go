@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"template-manager/internal/shared"
+	"template-manager/pkg/client"
+	"template-manager/pkg/templatedir"
+)
+
+const syncUsage = `usage: %s sync pull|push [flags]
+
+pull writes the latest version of the templates of the account into -dir,
+push creates the templates of -dir that changed as new (draft) versions.
+See pkg/templatedir for the layout of the directory.
+
+`
+
+// runSync keeps a directory of templates and an account in sync, so templates
+// can be reviewed in pull requests and deployed with push
+func runSync(args []string) error {
+	var url, token, dir, keys string
+	var dryRun bool
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	flags.StringVar(&url, "url", envOr("TM_URL", "http://localhost:8080"), "url of the template manager, $TM_URL")
+	flags.StringVar(&token, "token", os.Getenv("TM_TOKEN"), "session token, $TM_TOKEN")
+	flags.StringVar(&dir, "dir", "templates", "directory of the templates")
+	flags.StringVar(&keys, "keys", "", "comma separated keys of the templates to sync, all by default")
+	flags.BoolVar(&dryRun, "dry-run", false, "print what would change without changing it")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), syncUsage, os.Args[0])
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.New("sync needs pull or push")
+	}
+	action := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var only []string
+	if keys != "" {
+		only = strings.Split(keys, ",")
+	}
+	api := client.New(url, client.WithToken(token))
+	ctx := context.Background()
+	switch action {
+	case "pull":
+		return pull(ctx, api, dir, only, dryRun)
+	case "push":
+		return push(ctx, api, dir, only, dryRun)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown sync action %q", action)
+	}
+}
+
+func pull(ctx context.Context, api *client.Client, dir string, keys []string, dryRun bool) error {
+	bundle, err := api.ExportBundle(ctx, keys, true)
+	if err != nil {
+		return err
+	}
+	remote, err := templatedir.FromBundle(bundle)
+	if err != nil {
+		return err
+	}
+	local, err := readTemplates(dir)
+	if err != nil {
+		return err
+	}
+
+	byKey := make(map[string]templatedir.Template, len(local))
+	dirs := make(map[string]string, len(local))
+	for _, template := range local {
+		byKey[template.Key] = template
+		dirs[template.Dir] = template.Key
+	}
+	for _, template := range remote {
+		existing, ok := byKey[template.Key]
+		if ok && !templatedir.Changed(existing, template) {
+			fmt.Printf("unchanged %s\n", existing.Dir)
+			continue
+		}
+		if ok {
+			// keep the files where they are so the diff only shows what changed
+			template.Dir, template.Content = existing.Dir, existing.Content
+			for i, block := range template.Blocks {
+				for _, old := range existing.Blocks {
+					if old.Name == block.Name {
+						template.Blocks[i].File = old.File
+					}
+				}
+			}
+		} else {
+			template.Dir = shared.Slugify(template.Key)
+			if other, taken := dirs[template.Dir]; taken {
+				return fmt.Errorf("cannot pull %s into %s, it already holds %s", template.Key, template.Dir, other)
+			}
+		}
+
+		fmt.Printf("pull %s v%d -> %s\n", template.Key, template.Version, template.Dir)
+		if dryRun {
+			continue
+		}
+		if err := templatedir.Write(dir, template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func push(ctx context.Context, api *client.Client, dir string, keys []string, dryRun bool) error {
+	local, err := readTemplates(dir)
+	if err != nil {
+		return err
+	}
+	bundle, err := api.ExportBundle(ctx, keys, true)
+	if err != nil {
+		return err
+	}
+	remote, err := templatedir.FromBundle(bundle)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]templatedir.Template, len(remote))
+	for _, template := range remote {
+		existing[template.Key] = template
+	}
+
+	var changed []templatedir.Template
+	for _, template := range local {
+		if len(keys) > 0 && !slices.Contains(keys, template.Key) {
+			continue
+		}
+		current, ok := existing[template.Key]
+		switch {
+		case !ok:
+			fmt.Printf("create %s (%s)\n", template.Key, template.Dir)
+		case templatedir.Changed(template, current):
+			fmt.Printf("update %s (%s)\n", template.Key, template.Dir)
+		default:
+			continue
+		}
+		changed = append(changed, template)
+	}
+	if len(changed) == 0 {
+		fmt.Println("everything is up to date")
+		return nil
+	}
+	if dryRun {
+		return nil
+	}
+
+	bundle, err = templatedir.ToBundle(changed)
+	if err != nil {
+		return err
+	}
+	result, err := api.ImportBundle(ctx, bundle, client.ConflictNewVersion)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created %d and updated %d templates, the new versions are drafts until they are reviewed and published\n", len(result.Created), len(result.Versioned))
+	return nil
+}
+
+// readTemplates returns the templates under dir, a missing dir has none
+func readTemplates(dir string) ([]templatedir.Template, error) {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return templatedir.Read(dir)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
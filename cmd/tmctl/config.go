@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// config is kept between runs, login saves the session token in it
+type config struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// configPath is $TMCTL_CONFIG or tmctl/config.json in the user config directory
+func configPath() (string, error) {
+	if path := os.Getenv("TMCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tmctl", "config.json"), nil
+}
+
+func loadConfig() (*config, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var conf config
+	if err := json.Unmarshal(raw, &conf); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// save writes the config readable only by the user, it holds a session token
+func (c *config) save() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o600)
+}
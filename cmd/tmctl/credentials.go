@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strconv"
+
+	"template-manager/internal/entity"
+	"template-manager/pkg/client"
+)
+
+func (c *cli) credentials(ctx context.Context, args []string) error {
+	name, args, err := subcommand("credentials", args, "list", "get", "create", "update", "delete")
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "list":
+		credentials, err := c.api.ListCredentials(ctx)
+		if err != nil {
+			return err
+		}
+		return c.out.print(credentials, []string{"ID", "PLATFORM", "TYPE", "ACTIVE", "CREATED"}, func() [][]string {
+			rows := make([][]string, 0, len(credentials))
+			for _, credential := range credentials {
+				rows = append(rows, credentialRow(credential))
+			}
+			return rows
+		})
+	case "create":
+		var platform, kind, meta string
+		flags := flag.NewFlagSet("credentials create", flag.ExitOnError)
+		flags.StringVar(&platform, "platform", "", "mailjet or mailgun")
+		flags.StringVar(&kind, "type", string(entity.EMAIL), "email, sms or push")
+		flags.StringVar(&meta, "meta", "", "settings of the platform as a JSON object e.g. API keys")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+		if platform == "" || meta == "" {
+			return errors.New("credentials create needs -platform and -meta")
+		}
+		settings, err := jsonObject("meta", meta)
+		if err != nil {
+			return err
+		}
+		if err := c.api.CreateCredential(ctx, client.CredentialInput{
+			Platform: platform,
+			Type:     kind,
+			Meta:     settings,
+		}); err != nil {
+			return err
+		}
+		return c.out.done("credential created")
+	}
+
+	var platform, kind, meta string
+	flags := flag.NewFlagSet("credentials "+name, flag.ExitOnError)
+	if name == "update" {
+		flags.StringVar(&platform, "platform", "", "mailjet or mailgun, unchanged by default")
+		flags.StringVar(&kind, "type", "", "email, sms or push, unchanged by default")
+		flags.StringVar(&meta, "meta", "", "new settings of the platform as a JSON object")
+	}
+	positional, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	id, err := exactlyOne(positional, "credential id")
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "get":
+		credential, err := c.api.GetCredential(ctx, id)
+		if err != nil {
+			return err
+		}
+		return c.out.print(credential, []string{"ID", "PLATFORM", "TYPE", "ACTIVE", "CREATED"}, func() [][]string {
+			return [][]string{credentialRow(*credential)}
+		})
+	case "update":
+		settings, err := jsonObject("meta", meta)
+		if err != nil {
+			return err
+		}
+		if err := c.api.UpdateCredential(ctx, client.CredentialInput{
+			ID:       id,
+			Platform: platform,
+			Type:     kind,
+			Meta:     settings,
+		}); err != nil {
+			return err
+		}
+		return c.out.done("credential updated")
+	default:
+		if err := c.api.DeleteCredential(ctx, id); err != nil {
+			return err
+		}
+		return c.out.done("credential deleted")
+	}
+}
+
+// credentialRow leaves out the meta of the credential, it holds the secrets of the platform
+func credentialRow(credential client.Credential) []string {
+	return []string{credential.ID, credential.Platform, credential.Type, strconv.FormatBool(credential.IsActive == 1), formatTime(credential.CreatedAt)}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the memory of the line diff, larger inputs are only
+// reported as different
+const maxDiffCells = 16 << 20
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// diff returns the lines of a and b in order, marking the ones only in a with
+// '-' and the ones only in b with '+', based on their longest common subsequence
+func diff(a, b string) []diffLine {
+	x, y := splitLines(a), splitLines(b)
+	if len(x)*len(y) > maxDiffCells {
+		if a == b {
+			return nil
+		}
+		return []diffLine{{op: '-', text: fmt.Sprintf("(%d lines)", len(x))}, {op: '+', text: fmt.Sprintf("(%d lines, too large to compare line by line)", len(y))}}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, max(len(x), len(y)))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, diffLine{' ', x[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', x[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, diffLine{'-', x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, diffLine{'+', y[j]})
+	}
+	return lines
+}
+
+func changed(lines []diffLine) bool {
+	for _, line := range lines {
+		if line.op != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// unified formats lines like diff -u, with 3 lines of context around changes
+func unified(from, to string, lines []diffLine) string {
+	const context = 3
+	if !changed(lines) {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+
+	// line numbers in a and b where every line starts
+	numbers := make([][2]int, len(lines))
+	n := [2]int{1, 1}
+	for k, line := range lines {
+		numbers[k] = n
+		if line.op != '+' {
+			n[0]++
+		}
+		if line.op != '-' {
+			n[1]++
+		}
+	}
+
+	for k := 0; k < len(lines); {
+		if lines[k].op == ' ' {
+			k++
+			continue
+		}
+		// a hunk runs until there are more than 2*context unchanged lines
+		start, end := max(0, k-context), k
+		for unchanged := 0; end < len(lines) && unchanged <= 2*context; end++ {
+			if lines[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		end = min(len(lines), end-max(0, countTrailing(lines[:end])-context))
+
+		var removed, added int
+		for _, line := range lines[start:end] {
+			if line.op != '+' {
+				removed++
+			}
+			if line.op != '-' {
+				added++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", numbers[start][0], removed, numbers[start][1], added)
+		for _, line := range lines[start:end] {
+			b.WriteByte(line.op)
+			b.WriteString(line.text)
+			b.WriteByte('\n')
+		}
+		k = end
+	}
+	return b.String()
+}
+
+// countTrailing counts the unchanged lines at the end of lines
+func countTrailing(lines []diffLine) int {
+	count := 0
+	for k := len(lines) - 1; k >= 0 && lines[k].op == ' '; k-- {
+		count++
+	}
+	return count
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
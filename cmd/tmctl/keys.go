@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strings"
+	"time"
+
+	"template-manager/internal/shared"
+	"template-manager/pkg/client"
+)
+
+func (c *cli) keys(ctx context.Context, args []string) error {
+	name, args, err := subcommand("keys", args, "list", "create", "rotate", "stale", "delete")
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "list":
+		keys, err := c.api.ListKeys(ctx)
+		if err != nil {
+			return err
+		}
+		return c.out.print(keys, []string{"ID", "NAME", "PREFIX", "SCOPES", "CREATED", "LAST USED", "EXPIRES"}, func() [][]string {
+			rows := make([][]string, 0, len(keys))
+			for _, key := range keys {
+				rows = append(rows, []string{key.ID, key.Name, key.Prefix, joinScopes(key.Scopes), formatTime(key.CreatedAt),
+					formatOptionalTime(key.LastUsedAt), formatOptionalTime(key.ExpiresAt)})
+			}
+			return rows
+		})
+	case "create":
+		var keyName, scopes, expires string
+		var test bool
+		flags := flag.NewFlagSet("keys create", flag.ExitOnError)
+		flags.StringVar(&keyName, "name", "", "name of the key")
+		flags.StringVar(&scopes, "scopes", "", "comma separated scopes of the key e.g. render,send")
+		flags.StringVar(&expires, "expires", "", "RFC3339 time the key expires at, never by default")
+		flags.BoolVar(&test, "test", false, "create a test key, its sends are captured instead of delivered")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+		if keyName == "" || scopes == "" {
+			return errors.New("keys create needs -name and -scopes")
+		}
+		req := client.CreateKeyRequest{Name: keyName, Test: test}
+		for _, scope := range strings.Split(scopes, ",") {
+			req.Scopes = append(req.Scopes, strings.TrimSpace(scope))
+		}
+		if expires != "" {
+			t, err := time.Parse(time.RFC3339, expires)
+			if err != nil {
+				return errors.New("-expires must be an RFC3339 time")
+			}
+			req.ExpiresAt = &t
+		}
+		key, err := c.api.CreateKey(ctx, req)
+		if err != nil {
+			return err
+		}
+		return c.printSecret(key)
+	case "rotate":
+		var grace string
+		flags := flag.NewFlagSet("keys rotate", flag.ExitOnError)
+		flags.StringVar(&grace, "grace", "24h", "how long the old secret stays valid, 0 revokes it immediately")
+		positional, err := parse(flags, args)
+		if err != nil {
+			return err
+		}
+		id, err := exactlyOne(positional, "key id")
+		if err != nil {
+			return err
+		}
+		key, err := c.api.RotateKey(ctx, id, grace)
+		if err != nil {
+			return err
+		}
+		return c.printSecret(key)
+	case "stale":
+		var days int
+		flags := flag.NewFlagSet("keys stale", flag.ExitOnError)
+		flags.IntVar(&days, "days", shared.DefaultStaleKeyDays, "keys expiring or unused within this many days")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+		keys, err := c.api.StaleKeys(ctx, days)
+		if err != nil {
+			return err
+		}
+		return c.out.print(keys, []string{"ID", "NAME", "PREFIX", "REASON", "LAST USED", "EXPIRES"}, func() [][]string {
+			rows := make([][]string, 0, len(keys))
+			for _, key := range keys {
+				rows = append(rows, []string{key.ID, key.Name, key.Prefix, key.Reason,
+					formatOptionalTime(key.LastUsedAt), formatOptionalTime(key.ExpiresAt)})
+			}
+			return rows
+		})
+	default:
+		flags := flag.NewFlagSet("keys delete", flag.ExitOnError)
+		positional, err := parse(flags, args)
+		if err != nil {
+			return err
+		}
+		id, err := exactlyOne(positional, "key id")
+		if err != nil {
+			return err
+		}
+		if err := c.api.DeleteKey(ctx, id); err != nil {
+			return err
+		}
+		return c.out.done("key deleted")
+	}
+}
+
+// printSecret prints a key with its secret, the secret can't be retrieved later
+// so it is printed even in table output
+func (c *cli) printSecret(key *client.Key) error {
+	return c.out.print(key, []string{"ID", "NAME", "SECRET"}, func() [][]string {
+		return [][]string{{key.ID, key.Name, key.Secret}}
+	})
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return formatTime(*t)
+}
+
+func joinScopes(scopes []string) string {
+	return strings.Join(scopes, ",")
+}
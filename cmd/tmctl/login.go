@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func (c *cli) login(ctx context.Context, args []string) error {
+	var email, password string
+	flags := flag.NewFlagSet("login", flag.ExitOnError)
+	flags.StringVar(&email, "email", "", "email of the account")
+	flags.StringVar(&password, "password", os.Getenv("TM_PASSWORD"), "password of the account, $TM_PASSWORD, read from stdin when empty")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+	if email == "" {
+		return errors.New("login needs -email")
+	}
+	if password == "" {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	response, err := c.api.Login(ctx, email, password)
+	if err != nil {
+		return err
+	}
+	c.config.Token = response.Session.Token
+	if err := c.config.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "logged in as %s until %s\n", email, response.Session.ExpiresAt.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+func (c *cli) logout(ctx context.Context) error {
+	if c.config.Token == "" {
+		return errors.New("not logged in")
+	}
+	if err := c.api.Logout(ctx, c.config.Token); err != nil {
+		return err
+	}
+	c.config.Token = ""
+	return c.config.save()
+}
@@ -0,0 +1,161 @@
+// tmctl manages the templates, keys and credentials of an account from the
+// command line
+//
+//	tmctl login -email me@example.com
+//	tmctl templates list
+//	tmctl -o json templates get <id>
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"template-manager/pkg/client"
+)
+
+const usage = `usage: tmctl [flags] <command> [args]
+
+commands:
+  login -email <email> [-password <password>]
+  logout
+  templates list [-page 1] [-page-size 20]
+  templates get <id>
+  templates create -name <name> -file <path> [-vars <json>]
+  templates update -file <path> [-vars <json>] <id>
+  templates render [-vars <json>] [-attributes <json>] [-draft] <id>
+  templates diff [-version <n>] (-file <path> | -against <n>) <id>
+  keys list
+  keys create -name <name> -scopes <scope,...> [-expires <time>] [-test]
+  keys rotate [-grace 24h] <id>
+  keys stale [-days 30]
+  keys delete <id>
+  credentials list
+  credentials get <id>
+  credentials create -platform <mailjet|mailgun> -type <email|sms|push> -meta <json>
+  credentials update -meta <json> <id>
+  credentials delete <id>
+
+flags:
+`
+
+// cli is what every command gets, the client is authenticated with the api
+// key if there is one and the token saved by login otherwise
+type cli struct {
+	api    *client.Client
+	config *config
+	out    output
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "tmctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	conf, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var baseURL, apiKey, format string
+	flags := flag.NewFlagSet("tmctl", flag.ExitOnError)
+	flags.StringVar(&baseURL, "url", envOr("TM_URL", conf.URL), "url of the template manager, $TM_URL")
+	flags.StringVar(&apiKey, "api-key", os.Getenv("TM_API_KEY"), "API key to use instead of the login session, $TM_API_KEY")
+	flags.StringVar(&format, "o", "table", "output format, table or json")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("missing command")
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	opts := []client.Option{client.WithToken(envOr("TM_TOKEN", conf.Token))}
+	if apiKey != "" {
+		opts = append(opts, client.WithAPIKey(apiKey))
+	}
+	c := &cli{
+		api:    client.New(baseURL, opts...),
+		config: conf,
+		out:    output{json: format == "json", w: os.Stdout},
+	}
+	conf.URL = baseURL
+
+	ctx := context.Background()
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "login":
+		return c.login(ctx, rest)
+	case "logout":
+		return c.logout(ctx)
+	case "templates", "template":
+		return c.templates(ctx, rest)
+	case "keys", "key":
+		return c.keys(ctx, rest)
+	case "credentials", "credential":
+		return c.credentials(ctx, rest)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// parse parses flags that come before, between or after the positional
+// arguments and returns the positional arguments
+func parse(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// subcommand splits args into the name of a subcommand and its arguments
+func subcommand(command string, args []string, names ...string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("%s needs one of %s", command, strings.Join(names, ", "))
+	}
+	for _, name := range names {
+		if args[0] == name {
+			return name, args[1:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown %s command %q, expected one of %s", command, args[0], strings.Join(names, ", "))
+}
+
+// exactlyOne returns the only positional argument, usually an id
+func exactlyOne(positional []string, name string) (string, error) {
+	if len(positional) != 1 {
+		return "", fmt.Errorf("expected a single %s", name)
+	}
+	return positional[0], nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
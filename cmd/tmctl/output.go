@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// output prints results either as indented JSON or as a table
+type output struct {
+	json bool
+	w    io.Writer
+}
+
+// print writes v as JSON, or the rows returned by table under header
+func (o output) print(v any, header []string, table func() [][]string) error {
+	if o.json {
+		encoder := json.NewEncoder(o.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	if len(header) > 0 {
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+	}
+	for _, row := range table() {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// done reports a change that has no result
+func (o output) done(message string) error {
+	if o.json {
+		return o.print(map[string]any{"status": true, "message": message}, nil, nil)
+	}
+	_, err := fmt.Fprintln(o.w, message)
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
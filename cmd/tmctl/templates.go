@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"template-manager/pkg/client"
+)
+
+func (c *cli) templates(ctx context.Context, args []string) error {
+	name, args, err := subcommand("templates", args, "list", "get", "create", "update", "render", "diff")
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "list":
+		return c.listTemplates(ctx, args)
+	case "get":
+		return c.getTemplate(ctx, args)
+	case "create":
+		return c.createTemplate(ctx, args)
+	case "update":
+		return c.updateTemplate(ctx, args)
+	case "render":
+		return c.renderTemplate(ctx, args)
+	default:
+		return c.diffTemplate(ctx, args)
+	}
+}
+
+func (c *cli) listTemplates(ctx context.Context, args []string) error {
+	var page, pageSize int
+	flags := flag.NewFlagSet("templates list", flag.ExitOnError)
+	flags.IntVar(&page, "page", 1, "page to list")
+	flags.IntVar(&pageSize, "page-size", 20, "templates per page")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+
+	templates, err := c.api.ListTemplates(ctx, page, pageSize)
+	if err != nil {
+		return err
+	}
+	return c.out.print(templates, []string{"ID", "NAME", "VERSION", "STATUS", "CONTENT TYPE", "UPDATED"}, func() [][]string {
+		rows := make([][]string, 0, len(templates.Data)+1)
+		for _, t := range templates.Data {
+			rows = append(rows, []string{t.ID, t.Name, strconv.FormatUint(t.Version, 10), string(t.Status), t.ContentType, formatTime(t.UpdatedAt)})
+		}
+		return append(rows, []string{fmt.Sprintf("page %d of %d, %d templates", templates.Page, templates.PageCount, templates.RecordCount)})
+	})
+}
+
+func (c *cli) getTemplate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("templates get", flag.ExitOnError)
+	positional, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	id, err := exactlyOne(positional, "template id")
+	if err != nil {
+		return err
+	}
+
+	template, err := c.api.GetTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.out.print(template, nil, func() [][]string {
+		vars, _ := json.Marshal(template.Vars)
+		rows := [][]string{
+			{"ID", template.ID},
+			{"NAME", template.Name},
+			{"KEY", template.Slug},
+			{"VERSION", strconv.FormatUint(template.Version, 10)},
+			{"STATUS", string(template.Status)},
+			{"CONTENT TYPE", template.ContentType},
+			{"CONTENT HASH", template.ContentHash},
+			{"LOCATION", template.Location},
+			{"VARS", string(vars)},
+			{"CREATED", formatTime(template.CreatedAt)},
+			{"UPDATED", formatTime(template.UpdatedAt)},
+		}
+		for _, block := range template.Blocks {
+			rows = append(rows, []string{"BLOCK", block.Name + " if " + block.Condition})
+		}
+		return rows
+	})
+}
+
+func (c *cli) createTemplate(ctx context.Context, args []string) error {
+	var name, file, contentType, vars string
+	flags := flag.NewFlagSet("templates create", flag.ExitOnError)
+	flags.StringVar(&name, "name", "", "name of the template")
+	flags.StringVar(&file, "file", "", "file with the body of the template")
+	flags.StringVar(&contentType, "content-type", "", "text/html or text/plain, guessed from the file extension by default")
+	flags.StringVar(&vars, "vars", "", "default vars as a JSON object")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+	if name == "" || file == "" {
+		return errors.New("templates create needs -name and -file")
+	}
+	if contentType == "" {
+		contentType = contentTypeOf(file)
+	}
+	defaults, err := jsonObject("vars", vars)
+	if err != nil {
+		return err
+	}
+
+	location, err := c.upload(ctx, name, contentType, file)
+	if err != nil {
+		return err
+	}
+	if err := c.api.CreateTemplate(ctx, client.CreateTemplateRequest{
+		Name:        name,
+		ContentType: contentType,
+		Location:    location,
+		Vars:        defaults,
+	}); err != nil {
+		return err
+	}
+	return c.out.done("template created")
+}
+
+func (c *cli) updateTemplate(ctx context.Context, args []string) error {
+	var file, vars string
+	flags := flag.NewFlagSet("templates update", flag.ExitOnError)
+	flags.StringVar(&file, "file", "", "file with the new body of the template")
+	flags.StringVar(&vars, "vars", "", "default vars of the new version as a JSON object")
+	positional, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	id, err := exactlyOne(positional, "template id")
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		return errors.New("templates update needs -file")
+	}
+	defaults, err := jsonObject("vars", vars)
+	if err != nil {
+		return err
+	}
+
+	template, err := c.api.GetTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+	location, err := c.upload(ctx, template.Name, template.ContentType, file)
+	if err != nil {
+		return err
+	}
+	if err := c.api.UpdateTemplate(ctx, client.UpdateTemplateRequest{
+		TemplateID: id,
+		Location:   location,
+		Vars:       defaults,
+		IfMatch:    template.ETag(),
+	}); err != nil {
+		return err
+	}
+	return c.out.done(fmt.Sprintf("created version %d of the template", template.Version+1))
+}
+
+func (c *cli) renderTemplate(ctx context.Context, args []string) error {
+	var vars, attributes string
+	var draft bool
+	flags := flag.NewFlagSet("templates render", flag.ExitOnError)
+	flags.StringVar(&vars, "vars", "", "vars as a JSON object")
+	flags.StringVar(&attributes, "attributes", "", "recipient attributes for the blocks as a JSON object")
+	flags.BoolVar(&draft, "draft", false, "render this version instead of the published one")
+	positional, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	id, err := exactlyOne(positional, "template id")
+	if err != nil {
+		return err
+	}
+	values, err := jsonObject("vars", vars)
+	if err != nil {
+		return err
+	}
+	attrs, err := jsonObject("attributes", attributes)
+	if err != nil {
+		return err
+	}
+
+	rendered, err := c.api.RenderTemplate(ctx, client.RenderTemplateRequest{
+		TemplateID: id,
+		Vars:       values,
+		Attributes: attrs,
+		Draft:      draft,
+	})
+	if err != nil {
+		return err
+	}
+	if c.out.json {
+		return c.out.print(rendered, nil, nil)
+	}
+	_, err = fmt.Fprintln(c.out.w, rendered.Content)
+	return err
+}
+
+// diffTemplate compares the body of a template with a local file or with
+// another version of the template
+func (c *cli) diffTemplate(ctx context.Context, args []string) error {
+	var file string
+	var version, against uint64
+	flags := flag.NewFlagSet("templates diff", flag.ExitOnError)
+	flags.StringVar(&file, "file", "", "local file to compare with")
+	flags.Uint64Var(&version, "version", 0, "version of the template to compare, the given one by default")
+	flags.Uint64Var(&against, "against", 0, "other version of the template to compare with")
+	positional, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	id, err := exactlyOne(positional, "template id")
+	if err != nil {
+		return err
+	}
+	if (file == "") == (against == 0) {
+		return errors.New("templates diff needs either -file or -against")
+	}
+
+	current, err := c.api.TemplateContent(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	from := fmt.Sprintf("%s (version %d)", id, version)
+	if version == 0 {
+		from = id
+	}
+	var other []byte
+	var to string
+	if file != "" {
+		other, err = os.ReadFile(file)
+		to = file
+	} else {
+		other, err = c.api.TemplateContent(ctx, id, against)
+		to = fmt.Sprintf("%s (version %d)", id, against)
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := diff(string(current), string(other))
+	if c.out.json {
+		return c.out.print(map[string]any{"from": from, "to": to, "changed": changed(lines), "diff": unified(from, to, lines)}, nil, nil)
+	}
+	if !changed(lines) {
+		_, err := fmt.Fprintln(c.out.w, "no differences")
+		return err
+	}
+	_, err = fmt.Fprint(c.out.w, unified(from, to, lines))
+	return err
+}
+
+func (c *cli) upload(ctx context.Context, name, contentType, file string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return c.api.UploadContent(ctx, name, contentType, content)
+}
+
+func contentTypeOf(file string) string {
+	switch filepath.Ext(file) {
+	case ".html", ".htm":
+		return "text/html"
+	default:
+		return "text/plain"
+	}
+}
+
+// jsonObject parses the value of a flag holding a JSON object, empty is nil
+func jsonObject(name, value string) (map[string]any, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var object map[string]any
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return nil, fmt.Errorf("-%s must be a JSON object: %w", name, err)
+	}
+	return object, nil
+}
@@ -0,0 +1,210 @@
+package analytics
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+)
+
+const (
+	defaultTopLimit = 10
+
+	// recordQueue is how many events wait for the recorder before new ones are
+	// dropped, recordBatch how many are inserted at once
+	recordQueue = 4096
+	recordBatch = 100
+)
+
+type App struct {
+	db     *gorm.DB
+	logger *slog.Logger
+
+	events  chan *entity.TemplateEvent
+	dropped atomic.Int64
+}
+
+func New(db *gorm.DB, logger *slog.Logger) *App {
+	return &App{
+		db:     db,
+		logger: logger,
+		events: make(chan *entity.TemplateEvent, recordQueue),
+	}
+}
+
+// Record queues an event for RunRecorder so renders and sends are not held up
+// by it. The event is dropped when the queue is full, e.g. while the database
+// is slow, rather than piling up.
+func (a *App) Record(ctx context.Context, event *entity.TemplateEvent) {
+	select {
+	case a.events <- event:
+	default:
+		if dropped := a.dropped.Add(1); dropped == 1 || dropped%1000 == 0 {
+			a.logger.WarnContext(ctx, "template event queue is full, events are dropped", "dropped", dropped)
+		}
+	}
+}
+
+// RunRecorder stores the queued events with the given number of workers until
+// ctx is done, each worker inserts the events waiting in the queue in batches
+func (a *App) RunRecorder(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.record(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (a *App) record(ctx context.Context) {
+	for {
+		var batch []*entity.TemplateEvent
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-a.events:
+			batch = append(batch, event)
+		}
+	drain:
+		for len(batch) < recordBatch {
+			select {
+			case event := <-a.events:
+				batch = append(batch, event)
+			default:
+				break drain
+			}
+		}
+
+		if err := a.db.WithContext(ctx).Create(batch).Error; err != nil {
+			a.logger.ErrorContext(ctx, "failed to record template events", "count", len(batch), "err", err)
+		}
+	}
+}
+
+// Daily returns counts, error rate and p95 latency per day and event kind
+func (a *App) Daily(ctx context.Context, req shared.AnalyticsRequest) ([]shared.DailyStat, error) {
+	where, args := filters(req)
+	var stats []shared.DailyStat
+	err := a.db.WithContext(ctx).Raw(`
+		SELECT date_trunc('day', created_at) AS day,
+			kind,
+			count(*) AS count,
+			count(*) FILTER (WHERE outcome = ?) AS failures,
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms) AS p95_latency_ms
+		FROM template_events
+		WHERE `+where+`
+		GROUP BY 1, 2
+		ORDER BY 1, 2`,
+		append([]any{entity.EventOutcomeFailure}, args...)...,
+	).Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		stats[i].ErrorRate = errorRate(stats[i].Failures, stats[i].Count)
+	}
+	return stats, nil
+}
+
+// Top returns the most used templates, all versions of a template are counted together
+func (a *App) Top(ctx context.Context, req shared.AnalyticsRequest) ([]shared.TemplateStat, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultTopLimit
+	}
+	where, args := filters(req)
+	var stats []shared.TemplateStat
+	err := a.db.WithContext(ctx).Raw(`
+		SELECT slug,
+			(array_agg(template_id ORDER BY created_at DESC))[1] AS template_id,
+			count(*) AS count,
+			count(*) FILTER (WHERE outcome = ?) AS failures,
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms) AS p95_latency_ms,
+			max(created_at) AS last_used_at
+		FROM template_events
+		WHERE `+where+`
+		GROUP BY slug
+		ORDER BY count DESC
+		LIMIT ?`,
+		append(append([]any{entity.EventOutcomeFailure}, args...), limit)...,
+	).Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		stats[i].ErrorRate = errorRate(stats[i].Failures, stats[i].Count)
+	}
+	return stats, nil
+}
+
+// Unused returns the published templates that were neither rendered nor sent since req.From
+func (a *App) Unused(ctx context.Context, req shared.AnalyticsRequest) ([]entity.Template, error) {
+	var templates []entity.Template
+	err := a.db.WithContext(ctx).Raw(`
+		SELECT t.*
+		FROM templates t
+		WHERE t.account_id = ?
+			AND t.status = ?
+			AND t.deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM template_events e
+				WHERE e.account_id = t.account_id AND e.slug = t.slug AND e.created_at >= ?
+			)
+		ORDER BY t.published_at`,
+		req.AccountID, entity.TemplateStatusPublished, req.From,
+	).Scan(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// Summary aggregates the events of an account over the range of req
+func (a *App) Summary(ctx context.Context, req shared.AnalyticsRequest) (*shared.UsageSummary, error) {
+	where, args := filters(req)
+	summary := shared.UsageSummary{Since: req.From, Until: req.To}
+	err := a.db.WithContext(ctx).Raw(`
+		SELECT count(*) FILTER (WHERE kind = ?) AS renders,
+			count(*) FILTER (WHERE kind = ?) AS sends,
+			count(*) FILTER (WHERE outcome = ?) AS failures,
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms), 0) AS p95_latency_ms
+		FROM template_events
+		WHERE `+where,
+		append([]any{entity.EventKindRender, entity.EventKindSend, entity.EventOutcomeFailure}, args...)...,
+	).Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	summary.ErrorRate = errorRate(summary.Failures, summary.Renders+summary.Sends)
+	return &summary, nil
+}
+
+func filters(req shared.AnalyticsRequest) (string, []any) {
+	conditions := []string{"account_id = ?", "created_at >= ?", "created_at < ?"}
+	args := []any{req.AccountID, req.From, req.To}
+	if req.Kind != "" {
+		conditions = append(conditions, "kind = ?")
+		args = append(args, req.Kind)
+	}
+	if req.TemplateID != "" {
+		conditions = append(conditions, "slug = (SELECT slug FROM templates WHERE id = ? AND account_id = ?)")
+		args = append(args, req.TemplateID, req.AccountID)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func errorRate(failures, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(failures) / float64(total)
+}
@@ -0,0 +1,25 @@
+package analytics
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"template-manager/internal/entity"
+)
+
+func TestRecordDropsEventsWhenQueueIsFull(t *testing.T) {
+	// no recorder runs, so the queue fills up
+	app := New(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for i := 0; i < recordQueue+3; i++ {
+		app.Record(context.Background(), &entity.TemplateEvent{})
+	}
+
+	if got := len(app.events); got != recordQueue {
+		t.Errorf("queued %d events, want %d", got, recordQueue)
+	}
+	if got := app.dropped.Load(); got != 3 {
+		t.Errorf("dropped %d events, want 3", got)
+	}
+}
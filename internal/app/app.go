@@ -2,12 +2,15 @@ package app
 
 import (
 	"log/slog"
+	"template-manager/internal/app/analytics"
 	"template-manager/internal/app/auth"
 	"template-manager/internal/app/session"
 	"template-manager/internal/app/template"
+	"template-manager/internal/app/webhook"
 	"template-manager/internal/pkg/email"
 	"template-manager/pkg/config"
 	"template-manager/pkg/repository"
+	"template-manager/pkg/uploader"
 )
 
 type App struct {
@@ -15,9 +18,9 @@ type App struct {
 	AuthApp     *auth.App
 }
 
-func NewApp(conf *config.Config, mails email.Provider, logger *slog.Logger, repo repository.Container, sessionManager *session.Session) *App {
+func NewApp(conf *config.Config, mails email.Provider, logger *slog.Logger, repo repository.Container, storage uploader.Uploader, sessionManager *session.Session, analyticsApp *analytics.App, webhookApp *webhook.App, broadcaster template.Broadcaster, passwords *auth.PasswordPolicy) *App {
 	return &App{
-		TemplateApp: template.New(conf, logger, repo),
-		AuthApp:     auth.New(conf, mails, logger, repo, sessionManager),
+		TemplateApp: template.New(conf, logger, repo, storage, analyticsApp, webhookApp, broadcaster),
+		AuthApp:     auth.New(conf, mails, logger, repo, sessionManager, passwords),
 	}
 }
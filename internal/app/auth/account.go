@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
 	"template-manager/internal/entity"
 	"template-manager/internal/pkg/email"
 	"template-manager/internal/shared"
 	"template-manager/pkg/config"
 	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
 )
 
 type SessionManager interface {
@@ -24,85 +26,80 @@ type SessionManager interface {
 }
 
 type App struct {
-	config *config.Config
-	email  email.Provider
-	logger *slog.Logger
-	db     repository.Container // TODO: replace with repository
-	sess   SessionManager
+	config    *config.Config
+	email     email.Provider
+	logger    *slog.Logger
+	db        repository.Container // TODO: replace with repository
+	sess      SessionManager
+	passwords *PasswordPolicy
 }
 
-func New(config *config.Config, email email.Provider, logger *slog.Logger, db repository.Container, sessionManager SessionManager) *App {
+func New(config *config.Config, email email.Provider, logger *slog.Logger, db repository.Container, sessionManager SessionManager, passwords *PasswordPolicy) *App {
 	return &App{
-		config: config,
-		email:  email,
-		db:     db,
-		logger: logger,
-		sess:   sessionManager,
+		config:    config,
+		email:     email,
+		db:        db,
+		logger:    logger,
+		sess:      sessionManager,
+		passwords: passwords,
 	}
 }
 
+// Signup creates an account without a password, it is chosen with the emailed
+// verification link so only the owner of the email can use the account
 func (a *App) Signup(ctx context.Context, req shared.SignUpRequest) error {
 	var account = entity.Account{
 		Email: req.Email,
 	}
 
-	// generate password
-	randomPassword := entity.GenerateRandomPassword()
-	if err := account.SetPassword(randomPassword); err != nil {
-		a.logger.ErrorContext(ctx, "failed to set password %+v", err)
-		return err
-	}
-
 	// find existing account
 	if _, err := a.db.AuthRepository.Get(ctx, "email = ?", req.Email); err == nil {
 		return errors.New("account already exists")
 	}
 
 	if err := a.db.AuthRepository.Create(ctx, &account); err != nil {
-		a.logger.ErrorContext(ctx, "failed to create account %+v", err)
+		a.logger.ErrorContext(ctx, "failed to create account", "err", err)
 		return err
 	}
 
-	//send email
-	vars := map[string]any{
-		"to":           req.Email,
-		"subject":      "Password Setup",
-		"password":     randomPassword,
-		"company_name": "Template Manager",
-	}
-	if err := a.email.Send(ctx, email.TemplateIDSignupVerification, vars); err != nil {
-		a.logger.ErrorContext(ctx, "failed to send email %+v", err)
-		return err
-	}
-	return nil
+	// the account can't send until its email is verified
+	return a.sendVerification(ctx, &account)
 }
 
+var ErrLoginUnverified = errors.New("verify your email before logging in, ask for a new link if it expired")
+
 const (
 	LoginFailed = "login failed. please check your email and password and try again"
+
+	resetPasswordTTL = time.Hour
 )
 
 func (a *App) Login(ctx context.Context, req shared.LoginRequest) (*shared.LoginResponse, error) {
 
 	fetchedAccount, err := a.db.AuthRepository.Get(ctx, "email = ?", req.Email)
 	if err != nil {
-		a.logger.InfoContext(ctx, "failed to find account %+v", err)
+		a.logger.InfoContext(ctx, "failed to find account", "err", err)
 		return nil, errors.New(LoginFailed)
 	}
 	// check password
 	if !fetchedAccount.ComparePassword(req.Password) {
 		return nil, errors.New(LoginFailed)
 	}
+	// accounts of emails nobody proved to own can't be used
+	if fetchedAccount.VerifiedAt == nil {
+		return nil, ErrLoginUnverified
+	}
 
 	// delete existing sessions
 	if err := a.sess.Delete(ctx, fetchedAccount.ID); err != nil {
-		a.logger.ErrorContext(ctx, "failed to delete session %+v", err)
+		a.logger.ErrorContext(ctx, "failed to delete session", "err", err)
 		return nil, err
 	}
 
 	// create session
 	sess, err := a.sess.Create(ctx, fetchedAccount.ID, req.Device)
 	if err != nil {
-		a.logger.ErrorContext(ctx, "failed to create session %+v", err)
+		a.logger.ErrorContext(ctx, "failed to create session", "err", err)
 		return nil, err
 	}
 
@@ -113,41 +110,92 @@ func (a *App) Login(ctx context.Context, req shared.LoginRequest) (*shared.Login
 	}, nil
 }
 
+// InitiateResetPassword emails a link to reset the password, the password
+// only changes once the token of the link is used with ResetPassword. It
+// succeeds for unknown emails too, and when the account was sent too many
+// links or the email couldn't be sent, so it can't be used to find out who has
+// an account.
 func (a *App) InitiateResetPassword(ctx context.Context, req shared.InitiateResetPasswordRequest) error {
-	var (
-		acc *entity.Account
-		err error
-	)
-
-	if acc, err = a.db.AuthRepository.Get(ctx, "email = ?", req.Email); err != nil {
-		return errors.New("account does not exist")
+	acc, err := a.db.AuthRepository.Get(ctx, "email = ?", req.Email)
+	if err != nil {
+		return nil
 	}
-
-	// generate password
-	randomPassword := entity.GenerateRandomPassword()
-	if err := acc.SetPassword(randomPassword); err != nil {
-		a.logger.ErrorContext(ctx, "failed to set password %+v", err)
-		return err
+	if err := a.checkEmailRate(ctx, acc.ID, entity.TokenPurposeResetPassword); err != nil {
+		a.logger.InfoContext(ctx, "reset email not sent", "account_id", acc.ID, "err", err)
+		return nil
 	}
 
-	// update account
-	if err := a.db.AuthRepository.Update(ctx, acc); err != nil {
-		a.logger.ErrorContext(ctx, "failed to update account %+v", err)
-		return err
+	token, secret, err := entity.NewAccountToken(acc.ID, entity.TokenPurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to create reset token", "err", err)
+		return nil
+	}
+	if err := a.db.TokenRepository.Create(ctx, token); err != nil {
+		a.logger.ErrorContext(ctx, "failed to create reset token", "err", err)
+		return nil
 	}
 
 	//send email
 	vars := map[string]any{
-		"to":           req.Email,
+		"to":           acc.Email,
 		"subject":      "Password Reset",
-		"password":     randomPassword,
 		"company_name": "Template Manager",
+		"token":        secret,
+		"reset_url":    a.link("/reset-password", secret),
+	}
+	if err := a.email.Send(ctx, email.TemplateIDPasswordReset, vars); err != nil {
+		a.logger.ErrorContext(ctx, "failed to send email", "account_id", acc.ID, "err", err)
 	}
-	if err := a.email.Send(ctx, email.TemplateIDSignupVerification, vars); err != nil {
-		a.logger.ErrorContext(ctx, "failed to send email %+v", err)
+	return nil
+}
+
+// ResetPassword sets the password of the account with the token emailed by
+// InitiateResetPassword and ends all its sessions
+func (a *App) ResetPassword(ctx context.Context, req shared.ResetPasswordRequest) error {
+	acc, err := a.db.AuthRepository.Get(ctx, "email = ?", req.Email)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	token, err := a.findToken(ctx, entity.TokenPurposeResetPassword, req.Token)
+	if err != nil {
+		return err
+	}
+	if token.AccountID != acc.ID {
+		return ErrInvalidToken
+	}
+	// a rejected password leaves the token usable
+	if err := a.passwords.Check(req.NewPassword); err != nil {
+		return err
+	}
+	if err := a.useToken(ctx, token); err != nil {
 		return err
 	}
 
+	if err := acc.SetPassword(req.NewPassword); err != nil {
+		a.logger.ErrorContext(ctx, "failed to set password", "err", err)
+		return err
+	}
+	// the token was emailed so the address is verified too
+	now := time.Now().UTC()
+	if acc.VerifiedAt == nil {
+		acc.VerifiedAt = &now
+	}
+	acc.UpdatedAt = &now
+	if err := a.db.AuthRepository.Update(ctx, acc); err != nil {
+		a.logger.ErrorContext(ctx, "failed to update account", "err", err)
+		return err
+	}
+
+	// the other reset links and the sessions of the old password stop working
+	if _, err := a.db.TokenRepository.UpdateWhere(ctx,
+		util.Query{Query: "account_id = ? AND purpose = ? AND used_at IS NULL", Args: []any{acc.ID, entity.TokenPurposeResetPassword}},
+		map[string]any{"used_at": now}); err != nil {
+		a.logger.ErrorContext(ctx, "failed to expire reset tokens", "err", err)
+	}
+	if err := a.sess.Delete(ctx, acc.ID); err != nil {
+		a.logger.ErrorContext(ctx, "failed to delete sessions", "err", err)
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+)
+
+// requestReset returns the secret of a reset link emailed to someone@example.com
+func requestReset(t *testing.T, app *App, fakes accountFakes) string {
+	t.Helper()
+	if err := app.InitiateResetPassword(context.Background(), shared.InitiateResetPasswordRequest{Email: "someone@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	return fakes.email.token(t)
+}
+
+func resetAccount() entity.Account {
+	acc := entity.Account{ID: "acc1", Email: "someone@example.com"}
+	verifiedAt := time.Now()
+	acc.VerifiedAt = &verifiedAt
+	return acc
+}
+
+func TestResetPasswordEndsSessions(t *testing.T) {
+	app, fakes := newAccountApp(t, resetAccount())
+	first := requestReset(t, app, fakes)
+	// the rate limit allows a second link a minute later
+	fakes.tokens.rows[0].CreatedAt = time.Now().Add(-2 * emailInterval)
+	second := requestReset(t, app, fakes)
+	ctx := context.Background()
+
+	err := app.ResetPassword(ctx, shared.ResetPasswordRequest{Email: "someone@example.com", Token: second, NewPassword: newPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fakes.accounts.rows[0].ComparePassword(newPassword) {
+		t.Error("the password didn't change")
+	}
+	if !slices.Equal(fakes.sessions.deleted, []string{"acc1"}) {
+		t.Errorf("deleted the sessions of %v, want [acc1]", fakes.sessions.deleted)
+	}
+
+	for name, secret := range map[string]string{"used": second, "other": first} {
+		err := app.ResetPassword(ctx, shared.ResetPasswordRequest{Email: "someone@example.com", Token: secret, NewPassword: "another long password"})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("ResetPassword() with the %s link err = %v, want %v", name, err, ErrInvalidToken)
+		}
+	}
+	if !fakes.accounts.rows[0].ComparePassword(newPassword) {
+		t.Error("an old link changed the password again")
+	}
+}
+
+func TestResetPasswordChecksTheAccount(t *testing.T) {
+	other := entity.Account{ID: "acc2", Email: "other@example.com"}
+	app, fakes := newAccountApp(t, resetAccount(), other)
+	secret := requestReset(t, app, fakes)
+
+	err := app.ResetPassword(context.Background(), shared.ResetPasswordRequest{Email: "other@example.com", Token: secret, NewPassword: newPassword})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("ResetPassword() err = %v, want %v", err, ErrInvalidToken)
+	}
+	if len(fakes.sessions.deleted) != 0 {
+		t.Errorf("deleted the sessions of %v, want none", fakes.sessions.deleted)
+	}
+}
+
+func TestInitiateResetPasswordAnswersTheSame(t *testing.T) {
+	recently := entity.AccountToken{ID: "t1", AccountID: "acc1", Purpose: entity.TokenPurposeResetPassword, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name     string
+		accounts []entity.Account
+		tokens   []entity.AccountToken
+		sendErr  error
+		wantSent int
+	}{
+		{"sends a link", []entity.Account{resetAccount()}, nil, nil, 1},
+		{"unknown email", nil, nil, nil, 0},
+		{"rate limited", []entity.Account{resetAccount()}, []entity.AccountToken{recently}, nil, 0},
+		{"email fails", []entity.Account{resetAccount()}, nil, errors.New("provider is down"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, fakes := newAccountApp(t, tt.accounts...)
+			fakes.tokens.rows = tt.tokens
+			fakes.email.err = tt.sendErr
+
+			err := app.InitiateResetPassword(context.Background(), shared.InitiateResetPasswordRequest{Email: "someone@example.com"})
+			if err != nil {
+				t.Fatalf("InitiateResetPassword() err = %v, want nil", err)
+			}
+			if len(fakes.email.sent) != tt.wantSent {
+				t.Errorf("sent %d emails, want %d", len(fakes.email.sent), tt.wantSent)
+			}
+		})
+	}
+}
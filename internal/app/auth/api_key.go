@@ -3,26 +3,41 @@ package auth
 import (
 	"context"
 	"errors"
+	"time"
 
 	"template-manager/internal/entity"
 	"template-manager/internal/shared"
+	"template-manager/pkg/repository/util"
 )
 
-func (a App) CreateAccessKey(ctx context.Context, req shared.CreateAccessKeyRequest) error {
+var (
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrExpiredKey = errors.New("api key expired")
+)
+
+// keyUsageInterval limits how often the last use of a key is written, uses
+// from the same IP within it aren't recorded
+const keyUsageInterval = time.Minute
+
+// CreateAccessKey returns the key with its secret, the only time it is available
+func (a App) CreateAccessKey(ctx context.Context, req shared.CreateAccessKeyRequest) (*entity.Key, error) {
 	var key = entity.Key{
 		AccountID: req.AccountID,
 		Name:      req.AccessKeyName,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		Test:      req.Test,
 	}
 
 	if err := key.GenerateKey(); err != nil {
-		return err
+		return nil, err
 	}
 	if err := a.db.KeyRepository.Create(ctx, &key); err != nil {
-		a.logger.ErrorContext(ctx, "failed to create account %+v", err)
-		return err
+		a.logger.ErrorContext(ctx, "failed to create key", "err", err)
+		return nil, err
 	}
 
-	return nil
+	return &key, nil
 }
 
 func (a App) ListAccessKeys(ctx context.Context, req shared.ListAccessKeysRequest) ([]entity.Key, error) {
@@ -39,13 +54,111 @@ func (a App) ListAccessKeys(ctx context.Context, req shared.ListAccessKeysReques
 }
 
 func (a App) DeleteAccessKey(ctx context.Context, req shared.DeleteAccessKeyRequest) error {
-	var key = entity.Key{
-		AccountID: req.AccountID,
-		ID:        req.AccessKeyID,
+	key, err := a.db.KeyRepository.Get(ctx, "id = ? AND account_id = ?", req.AccessKeyID, req.AccountID)
+	if err != nil {
+		return errors.New("key not found")
 	}
-	if err := a.db.KeyRepository.Delete(ctx, &key); err != nil {
+	if err := a.db.KeyRepository.Delete(ctx, key); err != nil {
 		return errors.New("problem deleting key: " + err.Error())
 	}
 
 	return nil
 }
+
+// RotateAccessKey gives the key a new secret and returns it, the only time
+// it is available. The old secret remains valid for the grace period.
+func (a App) RotateAccessKey(ctx context.Context, req shared.RotateAccessKeyRequest) (*entity.Key, error) {
+	grace, err := time.ParseDuration(req.GracePeriod)
+	if err != nil {
+		return nil, err
+	}
+	key, err := a.db.KeyRepository.Get(ctx, "id = ? AND account_id = ?", req.AccessKeyID, req.AccountID)
+	if err != nil {
+		return nil, errors.New("key not found")
+	}
+	if key.Expired(time.Now()) {
+		return nil, errors.New("key expired, create a new one instead")
+	}
+
+	oldHash := key.SecretHash
+	if err := key.Rotate(grace); err != nil {
+		return nil, err
+	}
+	// compare-and-swap on the secret so concurrent rotations don't both succeed
+	updated, err := a.db.KeyRepository.UpdateWhere(ctx,
+		util.Query{Query: "id = ? AND secret_hash = ?", Args: []any{key.ID, oldHash}},
+		map[string]any{
+			"prefix":               key.Prefix,
+			"secret_hash":          key.SecretHash,
+			"previous_secret_hash": key.PreviousSecretHash,
+			"previous_expires_at":  key.PreviousExpiresAt,
+			"rotated_at":           key.RotatedAt,
+		})
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to rotate key", "key_id", key.ID, "err", err)
+		return nil, err
+	}
+	if updated == 0 {
+		return nil, errors.New("key was rotated concurrently, try again")
+	}
+	return key, nil
+}
+
+// StaleAccessKeys lists the keys that expired or expire within req.Days, and
+// the keys that weren't used within req.Days
+func (a App) StaleAccessKeys(ctx context.Context, req shared.StaleAccessKeysRequest) ([]shared.StaleKey, error) {
+	keys, err := a.ListAccessKeys(ctx, shared.ListAccessKeysRequest{AccountID: req.AccountID})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	cutoff := now.AddDate(0, 0, -req.Days)
+	stale := []shared.StaleKey{}
+	for _, key := range keys {
+		var reason shared.KeyStaleness
+		switch {
+		case key.Expired(now):
+			reason = shared.KeyStalenessExpired
+		case key.ExpiresAt != nil && key.ExpiresAt.Before(now.AddDate(0, 0, req.Days)):
+			reason = shared.KeyStalenessExpiring
+		case key.LastUsedAt == nil && key.CreatedAt.Before(cutoff):
+			reason = shared.KeyStalenessNeverUsed
+		case key.LastUsedAt != nil && key.LastUsedAt.Before(cutoff):
+			reason = shared.KeyStalenessUnused
+		default:
+			continue
+		}
+		stale = append(stale, shared.StaleKey{Key: key, Reason: reason})
+	}
+	return stale, nil
+}
+
+// VerifyAccessKey returns the key with the given secret, or the previous
+// secret of a rotated key within its grace period, and records its use from ip
+func (a App) VerifyAccessKey(ctx context.Context, secret, ip string) (*entity.Key, error) {
+	if secret == "" {
+		return nil, ErrInvalidKey
+	}
+	now := time.Now().UTC()
+	hash := entity.HashKeySecret(secret)
+	key, err := a.db.KeyRepository.Get(ctx,
+		"secret_hash = ? OR (previous_secret_hash = ? AND previous_expires_at > ?)", hash, hash, now)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	if key.Expired(now) {
+		return nil, ErrExpiredKey
+	}
+
+	if key.LastUsedAt == nil || key.LastUsedAt.Before(now.Add(-keyUsageInterval)) || key.LastUsedIP != ip {
+		// the request goes through even when its use can't be recorded
+		if _, err := a.db.KeyRepository.UpdateWhere(ctx, util.Eq("id", key.ID),
+			map[string]any{"last_used_at": now, "last_used_ip": ip}); err != nil {
+			a.logger.ErrorContext(ctx, "failed to record key usage", "key_id", key.ID, "err", err)
+		} else {
+			key.LastUsedAt, key.LastUsedIP = &now, ip
+		}
+	}
+	return key, nil
+}
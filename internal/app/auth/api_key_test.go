@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+)
+
+func TestCreateAccessKeyStoresOnlyTheHash(t *testing.T) {
+	app, keys := newKeyApp(t)
+	key, err := app.CreateAccessKey(context.Background(), shared.CreateAccessKeyRequest{AccountID: "acc", AccessKeyName: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(key.Secret, entity.KeyPrefix) || !strings.HasPrefix(key.Secret, key.Prefix) {
+		t.Errorf("secret %q, prefix %q", key.Secret, key.Prefix)
+	}
+	stored := keys.rows[0]
+	if stored.SecretHash != entity.HashKeySecret(key.Secret) || stored.SecretHash == key.Secret {
+		t.Errorf("stored hash %q is not the hash of the secret", stored.SecretHash)
+	}
+}
+
+func TestVerifyAccessKey(t *testing.T) {
+	app, _ := newKeyApp(t)
+	ctx := context.Background()
+	live, err := app.CreateAccessKey(ctx, shared.CreateAccessKeyRequest{AccountID: "acc", AccessKeyName: "live"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(-time.Minute)
+	expired, err := app.CreateAccessKey(ctx, shared.CreateAccessKeyRequest{AccountID: "acc", AccessKeyName: "old", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		wantID  string
+		wantErr error
+	}{
+		{"valid secret", live.Secret, live.ID, nil},
+		{"unknown secret", live.Secret + "0", "", ErrInvalidKey},
+		{"stored hash as secret", entity.HashKeySecret(live.Secret), "", ErrInvalidKey},
+		{"empty secret", "", "", ErrInvalidKey},
+		{"expired key", expired.Secret, "", ErrExpiredKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := app.VerifyAccessKey(ctx, tt.secret, "10.0.0.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyAccessKey() err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && key.ID != tt.wantID {
+				t.Errorf("VerifyAccessKey() = %s, want %s", key.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestVerifyAccessKeyRecordsUse(t *testing.T) {
+	app, keys := newKeyApp(t)
+	ctx := context.Background()
+	key, err := app.CreateAccessKey(ctx, shared.CreateAccessKeyRequest{AccountID: "acc", AccessKeyName: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first use is recorded, uses from the same IP right after aren't
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		if _, err := app.VerifyAccessKey(ctx, key.Secret, ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys.updates != 2 {
+		t.Errorf("recorded %d uses, want 2", keys.updates)
+	}
+	if got := keys.rows[0]; got.LastUsedAt == nil || got.LastUsedIP != "10.0.0.2" {
+		t.Errorf("last use = %v from %q, want the last IP", got.LastUsedAt, got.LastUsedIP)
+	}
+}
+
+func TestRotateAccessKeyGracePeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		grace    string
+		elapsed  time.Duration // how long after the rotation the old secret is used
+		oldWorks bool
+	}{
+		{"within the grace period", "1h", 0, true},
+		{"after the grace period", "1h", 2 * time.Hour, false},
+		{"without a grace period", "0s", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, keys := newKeyApp(t)
+			ctx := context.Background()
+			old, err := app.CreateAccessKey(ctx, shared.CreateAccessKeyRequest{AccountID: "acc", AccessKeyName: "ci"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rotated, err := app.RotateAccessKey(ctx, shared.RotateAccessKeyRequest{AccountID: "acc", AccessKeyID: old.ID, GracePeriod: tt.grace})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rotated.Secret == old.Secret {
+				t.Fatal("the rotated key kept its secret")
+			}
+			if end := keys.rows[0].PreviousExpiresAt; end != nil {
+				passed := end.Add(-tt.elapsed)
+				keys.rows[0].PreviousExpiresAt = &passed
+			}
+
+			if _, err := app.VerifyAccessKey(ctx, rotated.Secret, "10.0.0.1"); err != nil {
+				t.Errorf("new secret: %v", err)
+			}
+			_, err = app.VerifyAccessKey(ctx, old.Secret, "10.0.0.1")
+			if tt.oldWorks && err != nil {
+				t.Errorf("old secret: %v, want it to work", err)
+			}
+			if !tt.oldWorks && !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("old secret: %v, want %v", err, ErrInvalidKey)
+			}
+		})
+	}
+}
+
+func TestRotateAccessKeyConcurrently(t *testing.T) {
+	app, keys := newKeyApp(t)
+	ctx := context.Background()
+	key, err := app.CreateAccessKey(ctx, shared.CreateAccessKeyRequest{AccountID: "acc", AccessKeyName: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := shared.RotateAccessKeyRequest{AccountID: "acc", AccessKeyID: key.ID, GracePeriod: "1h"}
+
+	// another rotation writes its secret between the read and the write of this one
+	var first *entity.Key
+	keys.beforeUpdate = func() {
+		if first, err = app.RotateAccessKey(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := app.RotateAccessKey(ctx, req); err == nil {
+		t.Fatal("both rotations succeeded")
+	}
+	if _, err := app.VerifyAccessKey(ctx, first.Secret, "10.0.0.1"); err != nil {
+		t.Errorf("secret of the rotation that won: %v", err)
+	}
+}
+
+func TestRotateExpiredAccessKey(t *testing.T) {
+	app, _ := newKeyApp(t)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(-time.Minute)
+	key, err := app.CreateAccessKey(ctx, shared.CreateAccessKeyRequest{AccountID: "acc", AccessKeyName: "old", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.RotateAccessKey(ctx, shared.RotateAccessKeyRequest{AccountID: "acc", AccessKeyID: key.ID, GracePeriod: "1h"}); err == nil {
+		t.Error("an expired key was rotated")
+	}
+}
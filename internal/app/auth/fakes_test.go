@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/pkg/email"
+	"template-manager/pkg/config"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+// fakeKeys keeps keys in memory, it only understands the queries of the key
+// lifecycle
+type fakeKeys struct {
+	repository.KeyRepositoryInterface[entity.Key]
+	t       *testing.T
+	rows    []entity.Key
+	updates int
+	// beforeUpdate runs once before the next update, e.g. to let a concurrent
+	// request change the key first
+	beforeUpdate func()
+}
+
+func (f *fakeKeys) match(query util.Query, row entity.Key) bool {
+	switch query.Query {
+	case "secret_hash = ? OR (previous_secret_hash = ? AND previous_expires_at > ?)":
+		return row.SecretHash == query.Args[0] ||
+			(row.PreviousSecretHash == query.Args[1] && row.PreviousExpiresAt != nil && row.PreviousExpiresAt.After(query.Args[2].(time.Time)))
+	case "id = ? AND account_id = ?":
+		return row.ID == query.Args[0] && row.AccountID == query.Args[1]
+	case "id = ?":
+		return row.ID == query.Args[0]
+	case "id = ? AND secret_hash = ?":
+		return row.ID == query.Args[0] && row.SecretHash == query.Args[1]
+	}
+	f.t.Fatalf("unexpected key query %q", query.Query)
+	return false
+}
+
+func (f *fakeKeys) Create(_ context.Context, key *entity.Key) error {
+	if key.ID == "" {
+		key.ID = fmt.Sprintf("key%d", len(f.rows)+1)
+	}
+	f.rows = append(f.rows, *key)
+	return nil
+}
+
+func (f *fakeKeys) Get(_ context.Context, conds ...any) (*entity.Key, error) {
+	query := util.Query{Query: conds[0].(string), Args: conds[1:]}
+	for _, row := range f.rows {
+		if f.match(query, row) {
+			return &row, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeKeys) UpdateWhere(_ context.Context, query any, data any) (int64, error) {
+	if before := f.beforeUpdate; before != nil {
+		f.beforeUpdate = nil
+		before()
+	}
+	f.updates++
+	var updated int64
+	for i, row := range f.rows {
+		if !f.match(query.(util.Query), row) {
+			continue
+		}
+		for column, value := range data.(map[string]any) {
+			switch column {
+			case "last_used_at":
+				usedAt := value.(time.Time)
+				f.rows[i].LastUsedAt = &usedAt
+			case "last_used_ip":
+				f.rows[i].LastUsedIP = value.(string)
+			case "prefix":
+				f.rows[i].Prefix = value.(string)
+			case "secret_hash":
+				f.rows[i].SecretHash = value.(string)
+			case "previous_secret_hash":
+				f.rows[i].PreviousSecretHash = value.(string)
+			case "previous_expires_at":
+				f.rows[i].PreviousExpiresAt = value.(*time.Time)
+			case "rotated_at":
+				f.rows[i].RotatedAt = value.(*time.Time)
+			default:
+				f.t.Fatalf("unexpected key column %q", column)
+			}
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func newKeyApp(t *testing.T) (*App, *fakeKeys) {
+	keys := &fakeKeys{t: t}
+	app := &App{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		db:     repository.Container{KeyRepository: keys},
+	}
+	return app, keys
+}
+
+// fakeAccounts keeps accounts in memory, it only understands the queries of
+// sign up, verification and password resets
+type fakeAccounts struct {
+	repository.AccountRepositoryInterface[entity.Account]
+	t    *testing.T
+	rows []entity.Account
+}
+
+func (f *fakeAccounts) Create(_ context.Context, acc *entity.Account) error {
+	if acc.ID == "" {
+		acc.ID = fmt.Sprintf("acc%d", len(f.rows)+1)
+	}
+	f.rows = append(f.rows, *acc)
+	return nil
+}
+
+func (f *fakeAccounts) Get(_ context.Context, conds ...any) (*entity.Account, error) {
+	for _, row := range f.rows {
+		var found bool
+		switch conds[0] {
+		case "email = ?":
+			found = row.Email == conds[1]
+		case "id = ?":
+			found = row.ID == conds[1]
+		default:
+			f.t.Fatalf("unexpected account query %q", conds[0])
+		}
+		if found {
+			return &row, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeAccounts) Update(_ context.Context, acc *entity.Account) error {
+	for i, row := range f.rows {
+		if row.ID == acc.ID {
+			f.rows[i] = *acc
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// fakeTokens keeps account tokens in memory, it only understands the queries
+// of verification and password resets
+type fakeTokens struct {
+	repository.AccountTokenRepositoryInterface[entity.AccountToken]
+	t    *testing.T
+	rows []entity.AccountToken
+	// beforeUpdate runs once before the next update, e.g. to let a concurrent
+	// request use the token first
+	beforeUpdate func()
+}
+
+func (f *fakeTokens) match(query util.Query, row entity.AccountToken) bool {
+	switch query.Query {
+	case "token_hash = ? AND purpose = ?":
+		return row.TokenHash == query.Args[0] && row.Purpose == query.Args[1]
+	case "account_id = ? AND purpose = ? AND created_at > ?":
+		return row.AccountID == query.Args[0] && row.Purpose == query.Args[1] && row.CreatedAt.After(query.Args[2].(time.Time))
+	case "id = ? AND used_at IS NULL":
+		return row.ID == query.Args[0] && row.UsedAt == nil
+	case "account_id = ? AND purpose = ? AND used_at IS NULL":
+		return row.AccountID == query.Args[0] && row.Purpose == query.Args[1] && row.UsedAt == nil
+	}
+	f.t.Fatalf("unexpected token query %q", query.Query)
+	return false
+}
+
+func (f *fakeTokens) Create(_ context.Context, token *entity.AccountToken) error {
+	if token.ID == "" {
+		token.ID = fmt.Sprintf("token%d", len(f.rows)+1)
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+	f.rows = append(f.rows, *token)
+	return nil
+}
+
+func (f *fakeTokens) Get(_ context.Context, conds ...any) (*entity.AccountToken, error) {
+	query := util.Query{Query: conds[0].(string), Args: conds[1:]}
+	for _, row := range f.rows {
+		if f.match(query, row) {
+			return &row, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeTokens) FindManyWithOptions(_ context.Context, query any, _ ...repository.Opt) ([]entity.AccountToken, error) {
+	var found []entity.AccountToken
+	for _, row := range f.rows {
+		if f.match(query.(util.Query), row) {
+			found = append(found, row)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeTokens) UpdateWhere(_ context.Context, query any, data any) (int64, error) {
+	if before := f.beforeUpdate; before != nil {
+		f.beforeUpdate = nil
+		before()
+	}
+	var updated int64
+	for i, row := range f.rows {
+		if !f.match(query.(util.Query), row) {
+			continue
+		}
+		for column, value := range data.(map[string]any) {
+			switch column {
+			case "used_at":
+				usedAt := value.(time.Time)
+				f.rows[i].UsedAt = &usedAt
+			default:
+				f.t.Fatalf("unexpected token column %q", column)
+			}
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// fakeEmail records the emails it was asked to send, failing with err
+type fakeEmail struct {
+	sent []map[string]any
+	err  error
+}
+
+func (f *fakeEmail) Send(_ context.Context, _ email.TemplateID, vars map[string]any) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, vars)
+	return nil
+}
+
+// token returns the secret of the last email sent
+func (f *fakeEmail) token(t *testing.T) string {
+	t.Helper()
+	if len(f.sent) == 0 {
+		t.Fatal("no email was sent")
+	}
+	return f.sent[len(f.sent)-1]["token"].(string)
+}
+
+// fakeSessions records the accounts whose sessions were deleted
+type fakeSessions struct {
+	SessionManager
+	deleted []string
+}
+
+func (f *fakeSessions) Delete(_ context.Context, accountID string) error {
+	f.deleted = append(f.deleted, accountID)
+	return nil
+}
+
+// accountFakes are the dependencies of an app made by newAccountApp
+type accountFakes struct {
+	accounts *fakeAccounts
+	tokens   *fakeTokens
+	email    *fakeEmail
+	sessions *fakeSessions
+}
+
+func newAccountApp(t *testing.T, accounts ...entity.Account) (*App, accountFakes) {
+	fakes := accountFakes{
+		accounts: &fakeAccounts{t: t, rows: accounts},
+		tokens:   &fakeTokens{t: t},
+		email:    &fakeEmail{},
+		sessions: &fakeSessions{},
+	}
+	app := &App{
+		config:    config.New().SetEnv("APP_URL", "https://app.example.com"),
+		email:     fakes.email,
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		db:        repository.Container{AuthRepository: fakes.accounts, TokenRepository: fakes.tokens},
+		sess:      fakes.sessions,
+		passwords: &PasswordPolicy{MinLength: 10},
+	}
+	return app, fakes
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"template-manager/internal/entity"
+	"template-manager/pkg/config"
+)
+
+const (
+	defaultPasswordMinLength = 10
+	// bcrypt only hashes 72 bytes, the salt comes first
+	maxPasswordBytes = 72 - entity.PasswordSaltLength
+)
+
+var ErrBreachedPassword = errors.New("this password appeared in a data breach, choose another one")
+
+// PasswordPolicy is what the passwords chosen at signup, verification and
+// reset must satisfy
+type PasswordPolicy struct {
+	MinLength int
+	// sorted upper case hex SHA-1 of passwords known from breaches, searched
+	// on disk since the lists are far larger than memory
+	breached     *os.File
+	breachedSize int64
+}
+
+// NewPasswordPolicy reads the policy from PASSWORD_MIN_LENGTH (10 by default)
+// and PASSWORD_BREACHED_LIST, a file of SHA-1 hashes of breached passwords one
+// per line sorted by hash. Lines may have a :count suffix like the Have I Been
+// Pwned "ordered by hash" download, which can be used as is.
+func NewPasswordPolicy(conf *config.Config) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{MinLength: defaultPasswordMinLength}
+	if minLength, err := strconv.Atoi(conf.GetString("PASSWORD_MIN_LENGTH")); err == nil && minLength > 0 {
+		policy.MinLength = minLength
+	}
+
+	path := conf.GetString("PASSWORD_BREACHED_LIST")
+	if path == "" {
+		return policy, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	policy.breached, policy.breachedSize = f, info.Size()
+	return policy, nil
+}
+
+// Check returns why the password isn't allowed, nil when it is
+func (p *PasswordPolicy) Check(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+	if p.breached == nil {
+		return nil
+	}
+	sum := sha1.Sum([]byte(password))
+	found, err := p.isBreached(strings.ToUpper(hex.EncodeToString(sum[:])))
+	if err != nil {
+		return fmt.Errorf("breached password list: %w", err)
+	}
+	if found {
+		return ErrBreachedPassword
+	}
+	return nil
+}
+
+// isBreached binary searches the list for hash. Lines have different lengths
+// so the search is over byte offsets: every probe reads the first line that
+// starts at or after the offset.
+func (p *PasswordPolicy) isBreached(hash string) (bool, error) {
+	lo, hi := int64(0), p.breachedSize // the line of hash starts in [lo, hi)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := p.lineAfter(mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		lineHash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		switch strings.Compare(strings.ToUpper(lineHash), hash) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = start + int64(len(line))
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// lineAfter returns the offset and text, newline included, of the first line
+// starting at or after offset. The offset is the size of the list when there
+// is no such line.
+func (p *PasswordPolicy) lineAfter(offset int64) (int64, string, error) {
+	start := offset
+	if offset > 0 {
+		// the line is whole when the byte before offset ends the previous one
+		start = offset - 1
+	}
+	r := bufio.NewReader(io.NewSectionReader(p.breached, start, p.breachedSize-start))
+	if offset > 0 {
+		skipped, err := r.ReadString('\n')
+		if err == io.EOF {
+			return p.breachedSize, "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		start += int64(len(skipped))
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	return start, line, nil
+}
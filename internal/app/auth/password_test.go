@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"template-manager/pkg/config"
+)
+
+// newPolicy writes the sha1 of every breached password to a sorted list in the
+// Have I Been Pwned format
+func newPolicy(t *testing.T, breached []string) *PasswordPolicy {
+	t.Helper()
+	var lines []string
+	for i, password := range breached {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d\r\n", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPasswordPolicy(config.New().SetEnv("PASSWORD_BREACHED_LIST", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { policy.breached.Close() })
+	return policy
+}
+
+func TestPasswordPolicyBreached(t *testing.T) {
+	var breached []string
+	for i := 0; i < 1000; i++ {
+		breached = append(breached, fmt.Sprintf("breached password %d", i))
+	}
+	policy := newPolicy(t, breached)
+
+	for _, password := range breached {
+		if err := policy.Check(password); !errors.Is(err, ErrBreachedPassword) {
+			t.Fatalf("Check(%q) = %v, want %v", password, err, ErrBreachedPassword)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		password := fmt.Sprintf("unknown password %d", i)
+		if err := policy.Check(password); err != nil {
+			t.Fatalf("Check(%q) = %v, want nil", password, err)
+		}
+	}
+}
+
+func TestPasswordPolicySingleEntry(t *testing.T) {
+	policy := newPolicy(t, []string{"correct horse"})
+	if err := policy.Check("correct horse"); !errors.Is(err, ErrBreachedPassword) {
+		t.Errorf("Check() = %v, want %v", err, ErrBreachedPassword)
+	}
+	if err := policy.Check("battery staple"); err != nil {
+		t.Errorf("Check() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/pkg/email"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+const (
+	verificationTTL = 24 * time.Hour
+	// the emails of each purpose are limited to one per emailInterval and
+	// maxEmailsPerDay a day per account
+	emailInterval   = time.Minute
+	maxEmailsPerDay = 5
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// RateLimitedError is returned when an email was requested too often
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("too many requests, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+var ErrPasswordRequired = errors.New("choose a password to verify the account")
+
+// VerifyEmail marks the account of the token as verified and sets its
+// password. A password chosen before, by whoever signed up with the email,
+// is replaced. The token can't be used again.
+func (a *App) VerifyEmail(ctx context.Context, req shared.VerifyEmailRequest) error {
+	token, err := a.findToken(ctx, entity.TokenPurposeVerifyEmail, req.Token)
+	if err != nil {
+		return err
+	}
+	acc, err := a.db.AuthRepository.Get(ctx, "id = ?", token.AccountID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	// a missing or rejected password leaves the token usable
+	if req.Password == "" {
+		return ErrPasswordRequired
+	}
+	if err := a.passwords.Check(req.Password); err != nil {
+		return err
+	}
+	if err := acc.SetPassword(req.Password); err != nil {
+		a.logger.ErrorContext(ctx, "failed to set password", "err", err)
+		return err
+	}
+	if err := a.useToken(ctx, token); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if acc.VerifiedAt == nil {
+		acc.VerifiedAt = &now
+	}
+	acc.UpdatedAt = &now
+	if err := a.db.AuthRepository.Update(ctx, acc); err != nil {
+		a.logger.ErrorContext(ctx, "failed to verify account", "account_id", acc.ID, "err", err)
+		return err
+	}
+	return nil
+}
+
+// ResendVerification emails a new verification link. It succeeds for unknown
+// and verified emails too, and when the account was sent too many links or the
+// email couldn't be sent, so it can't be used to find out who has an account.
+func (a *App) ResendVerification(ctx context.Context, req shared.ResendVerificationRequest) error {
+	acc, err := a.db.AuthRepository.Get(ctx, "email = ?", req.Email)
+	if err != nil || acc.VerifiedAt != nil {
+		return nil
+	}
+
+	if err := a.checkEmailRate(ctx, acc.ID, entity.TokenPurposeVerifyEmail); err != nil {
+		a.logger.InfoContext(ctx, "verification email not sent", "account_id", acc.ID, "err", err)
+		return nil
+	}
+	// sendVerification logs its failures
+	_ = a.sendVerification(ctx, acc)
+	return nil
+}
+
+// checkEmailRate fails with a RateLimitedError when too many tokens of the
+// purpose were emailed to the account recently
+func (a *App) checkEmailRate(ctx context.Context, accountID string, purpose entity.TokenPurpose) error {
+	now := time.Now().UTC()
+	sent, err := a.db.TokenRepository.FindManyWithOptions(ctx,
+		util.Query{Query: "account_id = ? AND purpose = ? AND created_at > ?", Args: []any{accountID, purpose, now.Add(-24 * time.Hour)}},
+		repository.WithOrderBy("created_at", "asc"),
+	)
+	if err != nil {
+		return err
+	}
+	if len(sent) >= maxEmailsPerDay {
+		return &RateLimitedError{RetryAfter: sent[0].CreatedAt.Add(24 * time.Hour).Sub(now)}
+	}
+	if len(sent) > 0 {
+		if last := sent[len(sent)-1].CreatedAt; now.Sub(last) < emailInterval {
+			return &RateLimitedError{RetryAfter: last.Add(emailInterval).Sub(now)}
+		}
+	}
+	return nil
+}
+
+// sendVerification emails a new verification link for the account
+func (a *App) sendVerification(ctx context.Context, acc *entity.Account) error {
+	token, secret, err := entity.NewAccountToken(acc.ID, entity.TokenPurposeVerifyEmail, verificationTTL)
+	if err != nil {
+		return err
+	}
+	if err := a.db.TokenRepository.Create(ctx, token); err != nil {
+		a.logger.ErrorContext(ctx, "failed to create verification token", "err", err)
+		return err
+	}
+
+	vars := map[string]any{
+		"to":               acc.Email,
+		"subject":          "Verify your email",
+		"company_name":     "Template Manager",
+		"token":            secret,
+		"verification_url": a.link("/verify", secret),
+	}
+	if err := a.email.Send(ctx, email.TemplateIDSignupVerification, vars); err != nil {
+		a.logger.ErrorContext(ctx, "failed to send email", "err", err)
+		return err
+	}
+	return nil
+}
+
+// findToken returns the token of the secret, it fails for unknown, expired
+// and already used tokens
+func (a *App) findToken(ctx context.Context, purpose entity.TokenPurpose, secret string) (*entity.AccountToken, error) {
+	token, err := a.db.TokenRepository.Get(ctx, "token_hash = ? AND purpose = ?", entity.HashKeySecret(secret), purpose)
+	if err != nil || token.UsedAt != nil || !time.Now().Before(token.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return token, nil
+}
+
+// useToken marks a token returned by findToken as used
+func (a *App) useToken(ctx context.Context, token *entity.AccountToken) error {
+	now := time.Now().UTC()
+	// compare-and-swap so a token can't be used twice concurrently
+	used, err := a.db.TokenRepository.UpdateWhere(ctx,
+		util.Query{Query: "id = ? AND used_at IS NULL", Args: []any{token.ID}},
+		map[string]any{"used_at": now})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return ErrInvalidToken
+	}
+	token.UsedAt = &now
+	return nil
+}
+
+// link is the url of a page of the web app with the token, see APP_URL
+func (a *App) link(path, token string) string {
+	return a.config.GetString("APP_URL") + path + "?token=" + url.QueryEscape(token)
+}
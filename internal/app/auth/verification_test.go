@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+)
+
+const newPassword = "a long new password"
+
+// signUp signs up someone@example.com and returns the secret of the emailed
+// verification link
+func signUp(t *testing.T, app *App, fakes accountFakes) string {
+	t.Helper()
+	if err := app.Signup(context.Background(), shared.SignUpRequest{Email: "someone@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	return fakes.email.token(t)
+}
+
+func TestVerifyEmailUsesTokenOnce(t *testing.T) {
+	app, fakes := newAccountApp(t)
+	secret := signUp(t, app, fakes)
+	ctx := context.Background()
+
+	if err := app.VerifyEmail(ctx, shared.VerifyEmailRequest{Token: secret, Password: newPassword}); err != nil {
+		t.Fatal(err)
+	}
+	acc := fakes.accounts.rows[0]
+	if acc.VerifiedAt == nil || !acc.ComparePassword(newPassword) {
+		t.Fatal("the account isn't verified with the chosen password")
+	}
+
+	err := app.VerifyEmail(ctx, shared.VerifyEmailRequest{Token: secret, Password: "another long password"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("VerifyEmail() again err = %v, want %v", err, ErrInvalidToken)
+	}
+	if !fakes.accounts.rows[0].ComparePassword(newPassword) {
+		t.Error("the used token changed the password")
+	}
+}
+
+func TestVerifyEmailConcurrently(t *testing.T) {
+	app, fakes := newAccountApp(t)
+	secret := signUp(t, app, fakes)
+	ctx := context.Background()
+
+	// the other request uses the token between the lookup and the update of this one
+	var otherErr error
+	fakes.tokens.beforeUpdate = func() {
+		otherErr = app.VerifyEmail(ctx, shared.VerifyEmailRequest{Token: secret, Password: "the other long password"})
+	}
+	err := app.VerifyEmail(ctx, shared.VerifyEmailRequest{Token: secret, Password: newPassword})
+	if otherErr != nil {
+		t.Fatalf("the first VerifyEmail() err = %v", otherErr)
+	}
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("the second VerifyEmail() err = %v, want %v", err, ErrInvalidToken)
+	}
+	if !fakes.accounts.rows[0].ComparePassword("the other long password") {
+		t.Error("the request that lost the token changed the password")
+	}
+}
+
+func TestVerifyEmailKeepsTokenOnRejectedPassword(t *testing.T) {
+	app, fakes := newAccountApp(t)
+	secret := signUp(t, app, fakes)
+	ctx := context.Background()
+
+	if err := app.VerifyEmail(ctx, shared.VerifyEmailRequest{Token: secret}); !errors.Is(err, ErrPasswordRequired) {
+		t.Fatalf("VerifyEmail() without password err = %v, want %v", err, ErrPasswordRequired)
+	}
+	if err := app.VerifyEmail(ctx, shared.VerifyEmailRequest{Token: secret, Password: "short"}); err == nil {
+		t.Fatal("VerifyEmail() accepted a short password")
+	}
+	if err := app.VerifyEmail(ctx, shared.VerifyEmailRequest{Token: secret, Password: newPassword}); err != nil {
+		t.Fatalf("VerifyEmail() after rejected passwords err = %v", err)
+	}
+}
+
+func TestVerifyEmailRejectsExpiredToken(t *testing.T) {
+	app, fakes := newAccountApp(t)
+	secret := signUp(t, app, fakes)
+	fakes.tokens.rows[0].ExpiresAt = time.Now().Add(-time.Minute)
+
+	err := app.VerifyEmail(context.Background(), shared.VerifyEmailRequest{Token: secret, Password: newPassword})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("VerifyEmail() err = %v, want %v", err, ErrInvalidToken)
+	}
+	if fakes.accounts.rows[0].VerifiedAt != nil {
+		t.Error("an expired token verified the account")
+	}
+}
+
+func TestResendVerificationAnswersTheSame(t *testing.T) {
+	verifiedAt := time.Now()
+	unverified := entity.Account{ID: "acc1", Email: "someone@example.com"}
+	verified := entity.Account{ID: "acc1", Email: "someone@example.com", VerifiedAt: &verifiedAt}
+	recently := entity.AccountToken{ID: "t1", AccountID: "acc1", Purpose: entity.TokenPurposeVerifyEmail, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name     string
+		accounts []entity.Account
+		tokens   []entity.AccountToken
+		sendErr  error
+		wantSent int
+	}{
+		{"sends a new link", []entity.Account{unverified}, nil, nil, 1},
+		{"unknown email", nil, nil, nil, 0},
+		{"verified account", []entity.Account{verified}, nil, nil, 0},
+		{"rate limited", []entity.Account{unverified}, []entity.AccountToken{recently}, nil, 0},
+		{"email fails", []entity.Account{unverified}, nil, errors.New("provider is down"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, fakes := newAccountApp(t, tt.accounts...)
+			fakes.tokens.rows = tt.tokens
+			fakes.email.err = tt.sendErr
+
+			err := app.ResendVerification(context.Background(), shared.ResendVerificationRequest{Email: "someone@example.com"})
+			if err != nil {
+				t.Fatalf("ResendVerification() err = %v, want nil", err)
+			}
+			if len(fakes.email.sent) != tt.wantSent {
+				t.Errorf("sent %d emails, want %d", len(fakes.email.sent), tt.wantSent)
+			}
+		})
+	}
+}
@@ -9,12 +9,19 @@ import (
 )
 
 type Credential struct {
-	db repository.Container
+	db       repository.Container
+	notifier Notifier
 }
 
-func New(db repository.Container) *Credential {
+// Notifier delivers credential events to the webhooks of an account
+type Notifier interface {
+	Publish(ctx context.Context, accountID string, event entity.WebhookEvent, data any)
+}
+
+func New(db repository.Container, notifier Notifier) *Credential {
 	return &Credential{
-		db: db,
+		db:       db,
+		notifier: notifier,
 	}
 }
 
@@ -34,15 +41,36 @@ func (c *Credential) GetByAccountID(ctx context.Context, accountID string) ([]en
 	return c.db.CredentialRepository.Find(ctx, "account_id = ?", accountID)
 }
 
-func (c *Credential) Update(ctx context.Context, input *shared.CredentialInput) error {
+func (c *Credential) Update(ctx context.Context, accountID string, input *shared.CredentialInput) error {
 	if err := input.ValidateUpdate(); err != nil {
 		return err
 	}
+	existing, err := c.db.CredentialRepository.Get(ctx, "id = ? AND account_id = ?", input.ID, accountID)
+	if err != nil {
+		return err
+	}
 	cred := new(entity.Credential)
 	if err := encoding.UnPackJSON(input, cred); err != nil {
 		return err
 	}
-	return c.db.CredentialRepository.Update(ctx, cred)
+	if err := c.db.CredentialRepository.Update(ctx, cred); err != nil {
+		return err
+	}
+
+	// meta holds the provider secrets, it is left out of the event
+	platform, kind := existing.Platform, existing.Type
+	if cred.Platform != "" {
+		platform = cred.Platform
+	}
+	if cred.Type != "" {
+		kind = cred.Type
+	}
+	c.notifier.Publish(ctx, accountID, entity.WebhookEventCredentialUpdated, map[string]any{
+		"id":       existing.ID,
+		"platform": platform,
+		"type":     kind,
+	})
+	return nil
 }
 
 func (c *Credential) Delete(ctx context.Context, id string) error {
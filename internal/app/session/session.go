@@ -38,11 +38,11 @@ func (s *Session) Create(ctx context.Context, accountID string, device entity.De
 	}
 	err := sess.GenerateToken(s.config.GetString("JWT_SIGNING_KEY"))
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to generate token %+v", err)
+		s.logger.ErrorContext(ctx, "failed to generate token", "err", err)
 		return nil, err
 	}
 	if err := s.db.Model(&sess).Create(&sess).Error; err != nil {
-		s.logger.ErrorContext(ctx, "failed to create session %+v", err)
+		s.logger.ErrorContext(ctx, "failed to create session", "err", err)
 		return nil, err
 	}
 
@@ -56,7 +56,7 @@ func (s *Session) Verify(ctx context.Context, token string) (*entity.Session, er
 	// extract account id from token
 	jwtClaims, err := extractClaims(token, s.config.GetString("JWT_SIGNING_KEY"))
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to extract claims %v", err.Error(), "err")
+		s.logger.ErrorContext(ctx, "failed to extract claims", "err", err)
 		return nil, err
 	}
 
@@ -64,14 +64,14 @@ func (s *Session) Verify(ctx context.Context, token string) (*entity.Session, er
 	if err := s.db.Model(&sess).
 		Where("token = ? AND account_id = ?", token, jwtClaims["account_id"]).
 		First(&sess).Error; err != nil {
-		s.logger.ErrorContext(ctx, "failed to find session %s", err.Error(), "err")
+		s.logger.ErrorContext(ctx, "failed to find session", "err", err)
 		return nil, err
 	}
 	// check if session is expired
 	if sess.ExpiresAt.Before(time.Now()) {
 		// delete session
 		if err := s.db.Model(&sess).Delete(&sess).Error; err != nil {
-			s.logger.ErrorContext(ctx, "failed to delete session %s", err.Error(), "err")
+			s.logger.ErrorContext(ctx, "failed to delete session", "err", err)
 			return nil, err
 		}
 		return nil, errors.New("session expired")
@@ -111,7 +111,7 @@ func (s *Session) Expire(ctx context.Context, token string) error {
 	}
 	// delete session
 	if err := s.db.Model(&sess).Where(sess).Delete(&sess).Error; err != nil {
-		s.logger.ErrorContext(ctx, "failed to delete session %+v", err)
+		s.logger.ErrorContext(ctx, "failed to delete session", "err", err)
 		return err
 	}
 	return nil
@@ -124,7 +124,7 @@ func (s *Session) Delete(ctx context.Context, accountID string) error {
 	}
 	// delete session
 	if err := s.db.Model(&sess).Where(sess).Delete(&sess).Error; err != nil {
-		s.logger.ErrorContext(ctx, "failed to delete session %+v", err)
+		s.logger.ErrorContext(ctx, "failed to delete session", "err", err)
 		return err
 	}
 	return nil
@@ -0,0 +1,377 @@
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+// limits of a bundle on import, the content of every version is limited by
+// maxContentSize and the content of all versions together by maxBundleContent
+const (
+	MaxBundleSize    = 64 << 20 // 64MB
+	maxBundleContent = 128 << 20
+	maxBundleFiles   = 5000
+	maxManifestSize  = 4 << 20
+)
+
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+// ExportBundle writes the templates of an account as a zip of a manifest and
+// the content of every version. Content that no longer matches its hash fails
+// the export instead of being copied around.
+func (a *App) ExportBundle(ctx context.Context, req shared.ExportBundleRequest, w io.Writer) error {
+	query := util.Eq("account_id", req.AccountID)
+	if len(req.Keys) > 0 {
+		query = util.AndQuery(query, util.Eq("slug", req.Keys))
+	}
+	templates, err := a.db.TemplateRepository.FindManyWithOptions(ctx, query, repository.WithOrderBy("version", "asc"))
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	versions := make(map[string][]entity.Template)
+	for _, template := range templates {
+		if _, ok := versions[template.Slug]; !ok {
+			keys = append(keys, template.Slug)
+		}
+		versions[template.Slug] = append(versions[template.Slug], template)
+	}
+	sort.Strings(keys)
+
+	archive := zip.NewWriter(w)
+	manifest := shared.BundleManifest{
+		FormatVersion: shared.BundleFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		AccountID:     req.AccountID,
+		Templates:     make([]shared.BundleTemplate, 0, len(keys)),
+	}
+	for _, key := range keys {
+		exported := versions[key]
+		if req.LatestOnly {
+			exported = exported[len(exported)-1:]
+		}
+		bundled := shared.BundleTemplate{Key: key}
+		for i := range exported {
+			version, err := a.exportVersion(ctx, archive, &exported[i])
+			if err != nil {
+				return err
+			}
+			bundled.Versions = append(bundled.Versions, *version)
+		}
+		manifest.Templates = append(manifest.Templates, bundled)
+	}
+
+	file, err := archive.Create(shared.BundleManifestFile)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func (a *App) exportVersion(ctx context.Context, archive *zip.Writer, template *entity.Template) (*shared.BundleVersion, error) {
+	content, err := a.fetchContent(ctx, template.Location)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to fetch template content", "template_id", template.ID, "err", err)
+		return nil, fmt.Errorf("failed to read the content of %s version %d: %w", template.Slug, template.Version, err)
+	}
+	if err := verifyContent(template, content); err != nil {
+		a.logger.ErrorContext(ctx, "template content failed integrity check", "template_id", template.ID, "location", template.Location)
+		return nil, fmt.Errorf("%s version %d: %w", template.Slug, template.Version, err)
+	}
+
+	contentFile := path.Join("content", shared.Slugify(template.Slug), fmt.Sprintf("v%d%s", template.Version, extension(template.ContentType)))
+	file, err := archive.Create(contentFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(content); err != nil {
+		return nil, err
+	}
+
+	return &shared.BundleVersion{
+		Version:     template.Version,
+		Name:        template.Name,
+		Status:      template.Status,
+		ContentType: template.ContentType,
+		Engine:      engine(template.ContentType),
+		Vars:        template.Vars,
+		Blocks:      template.Blocks,
+		ContentFile: contentFile,
+		ContentHash: contentHash(content),
+		ContentSize: int64(len(content)),
+		PublishedAt: template.PublishedAt,
+	}, nil
+}
+
+// bundledTemplate is a template of a bundle with the content of its versions, oldest version first
+type bundledTemplate struct {
+	shared.BundleTemplate
+	contents [][]byte
+}
+
+// ImportBundle adds the templates of a bundle to an account. The whole bundle
+// is checked before anything is written. Imported versions are drafts and go
+// through review like any other version, templates that already exist are
+// handled according to req.Conflict.
+func (a *App) ImportBundle(ctx context.Context, req shared.ImportBundleRequest) (*shared.ImportBundleResult, error) {
+	templates, err := readBundle(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &shared.ImportBundleResult{
+		Created:     []string{},
+		Overwritten: []string{},
+		Versioned:   []string{},
+		Skipped:     []string{},
+	}
+	for _, bundled := range templates {
+		existing, err := a.db.TemplateRepository.FindManyWithOptions(ctx,
+			util.AndQuery(util.Eq("account_id", req.AccountID), util.Eq("slug", bundled.Key)),
+			repository.WithOrderBy("version", "desc"),
+		)
+		if err != nil {
+			return result, err
+		}
+
+		switch {
+		case len(existing) == 0:
+			created, err := a.importVersions(ctx, a.db.TemplateRepository, req.AccountID, bundled, 0)
+			if err != nil {
+				return result, err
+			}
+			a.notifier.Publish(ctx, req.AccountID, entity.WebhookEventTemplateCreated, created[0])
+			result.Created = append(result.Created, bundled.Key)
+		case req.Conflict == shared.ConflictOverwrite:
+			// the replaced versions go to the trash so the overwrite can be
+			// undone, they are only replaced when every version was imported
+			var created []entity.Template
+			err := a.db.Transaction(ctx, func(tx repository.Container) error {
+				if err := tx.TemplateRepository.DeleteByFieldName(ctx, util.AndQuery(
+					util.Eq("account_id", req.AccountID), util.Eq("slug", bundled.Key),
+				)); err != nil {
+					return err
+				}
+				var err error
+				created, err = a.importVersions(ctx, tx.TemplateRepository, req.AccountID, bundled, 0)
+				return err
+			})
+			if err != nil {
+				return result, err
+			}
+			for _, template := range existing {
+				a.invalidate(ctx, template.ID)
+			}
+			a.notifier.Publish(ctx, req.AccountID, entity.WebhookEventTemplateCreated, created[0])
+			result.Overwritten = append(result.Overwritten, bundled.Key)
+		case req.Conflict == shared.ConflictNewVersion:
+			// versions in the trash keep their number, the new version must
+			// not take the number of one that is restored later
+			highest, err := a.db.TemplateRepository.FindManyWithOptions(ctx,
+				util.AndQuery(util.Eq("account_id", req.AccountID), util.Eq("slug", bundled.Key)),
+				repository.WithOrderBy("version", "desc"),
+				repository.WithPagination(1, 1),
+				repository.WithDeleted(),
+			)
+			if err != nil {
+				return result, err
+			}
+			latest := len(bundled.Versions) - 1
+			bundled.Versions, bundled.contents = bundled.Versions[latest:], bundled.contents[latest:]
+			if _, err := a.importVersions(ctx, a.db.TemplateRepository, req.AccountID, bundled, highest[0].Version); err != nil {
+				return result, err
+			}
+			result.Versioned = append(result.Versioned, bundled.Key)
+		default:
+			result.Skipped = append(result.Skipped, bundled.Key)
+		}
+	}
+	return result, nil
+}
+
+// importVersions uploads the content of the versions of a bundled template and
+// creates them with templates. When after is not zero the versions are
+// numbered from after+1 instead of keeping the numbers of the bundle.
+func (a *App) importVersions(
+	ctx context.Context,
+	templates repository.TemplateRepositoryInterface[entity.Template],
+	accountID string,
+	bundled bundledTemplate,
+	after uint64,
+) ([]entity.Template, error) {
+	created := make([]entity.Template, 0, len(bundled.Versions))
+	for i, version := range bundled.Versions {
+		filename := a.uploadFilename(accountID, version.Name)
+		if _, err := a.storage.UploadFile(ctx, filename, bytes.NewBuffer(bundled.contents[i])); err != nil {
+			a.logger.ErrorContext(ctx, "failed to upload bundled content", "key", bundled.Key, "version", version.Version, "err", err)
+			return nil, err
+		}
+		number := version.Version
+		if after != 0 {
+			number = after + uint64(i) + 1
+		}
+		vars := version.Vars
+		if vars == nil {
+			vars = entity.Map{}
+		}
+		blocks := version.Blocks
+		if blocks == nil {
+			blocks = entity.ContentBlocks{}
+		}
+		template := entity.Template{
+			AccountID:   accountID,
+			Name:        version.Name,
+			Slug:        bundled.Key,
+			Version:     number,
+			ContentType: version.ContentType,
+			ContentHash: contentHash(bundled.contents[i]),
+			ContentSize: int64(len(bundled.contents[i])),
+			Location:    a.storage.GetPublicURl(filename),
+			Vars:        vars,
+			Blocks:      blocks,
+			Active:      true,
+			Status:      entity.TemplateStatusDraft,
+		}
+		if err := templates.Create(ctx, &template); err != nil {
+			return nil, err
+		}
+		created = append(created, template)
+	}
+	return created, nil
+}
+
+// readBundle reads and checks the manifest and content of a bundle
+func readBundle(bundle []byte) ([]bundledTemplate, error) {
+	archive, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if len(archive.File) > maxBundleFiles {
+		return nil, fmt.Errorf("%w: more than %d files", ErrInvalidBundle, maxBundleFiles)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	manifestFile, ok := files[shared.BundleManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, shared.BundleManifestFile)
+	}
+	raw, err := readBundleFile(manifestFile, maxManifestSize)
+	if err != nil {
+		return nil, err
+	}
+	var manifest shared.BundleManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, shared.BundleManifestFile, err)
+	}
+	if manifest.FormatVersion != shared.BundleFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidBundle, manifest.FormatVersion)
+	}
+
+	// the whole bundle is held in memory until it is imported, a zip of highly
+	// compressed files must not make that unbounded
+	remaining := int64(maxBundleContent)
+	seen := make(map[string]bool, len(manifest.Templates))
+	templates := make([]bundledTemplate, 0, len(manifest.Templates))
+	for _, template := range manifest.Templates {
+		if template.Key == "" || len(template.Versions) == 0 {
+			return nil, fmt.Errorf("%w: templates need a key and at least one version", ErrInvalidBundle)
+		}
+		if !shared.ValidSlug(template.Key) {
+			return nil, fmt.Errorf("%w: %q is not a valid key, use lowercase letters, digits and hyphens", ErrInvalidBundle, template.Key)
+		}
+		if seen[template.Key] {
+			return nil, fmt.Errorf("%w: %s is in the bundle more than once", ErrInvalidBundle, template.Key)
+		}
+		seen[template.Key] = true
+
+		sort.Slice(template.Versions, func(i, j int) bool {
+			return template.Versions[i].Version < template.Versions[j].Version
+		})
+		bundled := bundledTemplate{BundleTemplate: template}
+		for i, version := range template.Versions {
+			if version.Version == 0 || (i > 0 && version.Version == template.Versions[i-1].Version) {
+				return nil, fmt.Errorf("%w: %s has an invalid or duplicate version %d", ErrInvalidBundle, template.Key, version.Version)
+			}
+			if version.Name == "" || !allowedContentType(version.ContentType) {
+				return nil, fmt.Errorf("%w: %s version %d needs a name and a text/html or text/plain content type", ErrInvalidBundle, template.Key, version.Version)
+			}
+			file, ok := files[version.ContentFile]
+			if !ok {
+				return nil, fmt.Errorf("%w: content of %s version %d is missing", ErrInvalidBundle, template.Key, version.Version)
+			}
+			if remaining <= 0 {
+				return nil, fmt.Errorf("%w: content is larger than %d bytes in total", ErrInvalidBundle, maxBundleContent)
+			}
+			content, err := readBundleFile(file, min(maxContentSize, remaining))
+			if err != nil {
+				return nil, err
+			}
+			remaining -= int64(len(content))
+			if version.ContentHash != "" && contentHash(content) != version.ContentHash {
+				return nil, fmt.Errorf("%w: content of %s version %d does not match its hash", ErrInvalidBundle, template.Key, version.Version)
+			}
+			if !allowedContentType(http.DetectContentType(content)) {
+				return nil, fmt.Errorf("%w: content of %s version %d is not text", ErrInvalidBundle, template.Key, version.Version)
+			}
+			bundled.contents = append(bundled.contents, content)
+		}
+		templates = append(templates, bundled)
+	}
+	return templates, nil
+}
+
+// readBundleFile reads a file of a bundle, the size in the zip header is not
+// trusted so the read itself is limited
+func readBundleFile(file *zip.File, limit int64) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, file.Name, err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, file.Name, err)
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidBundle, file.Name, limit)
+	}
+	return content, nil
+}
+
+// engine names the package the content of a template is rendered with
+func engine(contentType string) string {
+	if isHTML(contentType) {
+		return "html/template"
+	}
+	return "text/template"
+}
+
+func extension(contentType string) string {
+	if isHTML(contentType) {
+		return ".html"
+	}
+	return ".txt"
+}
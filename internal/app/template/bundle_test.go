@@ -0,0 +1,117 @@
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"template-manager/internal/shared"
+)
+
+// newBundle zips a bundle of one template with a version per content
+func newBundle(t *testing.T, contents ...[]byte) []byte {
+	t.Helper()
+	return newKeyedBundle(t, "welcome", contents...)
+}
+
+// newKeyedBundle is newBundle with the key of the template
+func newKeyedBundle(t *testing.T, key string, contents ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	bundled := shared.BundleTemplate{Key: key}
+	for i, content := range contents {
+		name := fmt.Sprintf("content/welcome/v%d.txt", i+1)
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write(content); err != nil {
+			t.Fatal(err)
+		}
+		bundled.Versions = append(bundled.Versions, shared.BundleVersion{
+			Version:     uint64(i + 1),
+			Name:        "welcome",
+			ContentType: "text/plain",
+			ContentFile: name,
+		})
+	}
+	file, err := archive.Create(shared.BundleManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(file).Encode(shared.BundleManifest{
+		FormatVersion: shared.BundleFormatVersion,
+		Templates:     []shared.BundleTemplate{bundled},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadBundle(t *testing.T) {
+	templates, err := readBundle(newBundle(t, []byte("hello"), []byte("hello again")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || len(templates[0].contents) != 2 {
+		t.Fatalf("templates = %+v", templates)
+	}
+	if got := string(templates[0].contents[1]); got != "hello again" {
+		t.Errorf("content of version 2 = %q", got)
+	}
+}
+
+func TestReadBundleLimitsTotalContent(t *testing.T) {
+	// every version is within maxContentSize and compresses to almost
+	// nothing, together they are larger than maxBundleContent
+	content := bytes.Repeat([]byte("a"), maxContentSize)
+	contents := make([][]byte, maxBundleContent/maxContentSize+1)
+	for i := range contents {
+		contents[i] = content
+	}
+	bundle := newBundle(t, contents...)
+	if len(bundle) > MaxBundleSize {
+		t.Fatalf("bundle is %d bytes, the test needs it to fit MaxBundleSize", len(bundle))
+	}
+
+	_, err := readBundle(bundle)
+	if !errors.Is(err, ErrInvalidBundle) {
+		t.Fatalf("readBundle() err = %v, want %v", err, ErrInvalidBundle)
+	}
+	if !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("readBundle() err = %v, want the content limit", err)
+	}
+}
+
+func TestReadBundleChecksKeys(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"welcome", true},
+		{"order-confirmation-2", true},
+		{shared.GenerateSlug("Welcome Email"), true},
+		{"Welcome Email", false},
+		{"../welcome", false},
+		{"welcome/v2", false},
+		{"-welcome", false},
+		{strings.Repeat("a", 256), false},
+	}
+	for _, tt := range tests {
+		_, err := readBundle(newKeyedBundle(t, tt.key, []byte("hello")))
+		if tt.valid && err != nil {
+			t.Errorf("readBundle() with key %q err = %v, want nil", tt.key, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("readBundle() with key %q err = %v, want %v", tt.key, err, ErrInvalidBundle)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package template
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/pkg/cache"
+	"template-manager/pkg/config"
+)
+
+const (
+	defaultCacheEntries  = 1000
+	defaultCacheMaxBytes = 64 << 20 // 64MB
+	defaultCacheTTL      = 10 * time.Minute
+)
+
+// Broadcaster spreads cache invalidations to the other instances of the service
+type Broadcaster interface {
+	Publish(ctx context.Context, key string) error
+}
+
+// cacheKey includes the content hash so a version whose content changed
+// never hits an entry compiled from the old content
+type cacheKey struct {
+	TemplateID  string
+	Version     uint64
+	ContentHash string
+}
+
+func newCompiledCache(config *config.Config) *cache.LRU[cacheKey, *compiled] {
+	entries, err := strconv.Atoi(config.GetString("TEMPLATE_CACHE_ENTRIES"))
+	if err != nil || entries <= 0 {
+		entries = defaultCacheEntries
+	}
+	maxBytes, err := strconv.ParseInt(config.GetString("TEMPLATE_CACHE_MAX_BYTES"), 10, 64)
+	if err != nil || maxBytes <= 0 {
+		maxBytes = defaultCacheMaxBytes
+	}
+	ttl, err := time.ParseDuration(config.GetString("TEMPLATE_CACHE_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return cache.NewLRU[cacheKey, *compiled](entries, maxBytes, ttl)
+}
+
+// compiled returns the parsed template, from the cache when possible. Versions
+// without a content hash are never cached since their content can't be checked.
+func (a *App) compiled(ctx context.Context, template *entity.Template) (*compiled, error) {
+	key := cacheKey{TemplateID: template.ID, Version: template.Version, ContentHash: template.ContentHash}
+	if template.ContentHash != "" {
+		if c, ok := a.cache.Get(key); ok {
+			return c, nil
+		}
+	}
+
+	content, err := a.fetchContent(ctx, template.Location)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to fetch template content", "template_id", template.ID, "err", err)
+		return nil, err
+	}
+	if err := verifyContent(template, content); err != nil {
+		a.logger.ErrorContext(ctx, "template content failed integrity check", "template_id", template.ID, "location", template.Location)
+		return nil, err
+	}
+
+	c, err := compile(template, string(content))
+	if err != nil {
+		return nil, err
+	}
+	if template.ContentHash != "" {
+		a.cache.Add(key, c, c.size)
+	}
+	return c, nil
+}
+
+// invalidate drops the compiled versions of a template here and on every other instance
+func (a *App) invalidate(ctx context.Context, templateID string) {
+	a.EvictCache(templateID)
+	if a.broadcaster == nil {
+		return
+	}
+	if err := a.broadcaster.Publish(ctx, templateID); err != nil {
+		a.logger.ErrorContext(ctx, "failed to broadcast cache invalidation", "template_id", templateID, "err", err)
+	}
+}
+
+// EvictCache drops the compiled versions of a template from this instance,
+// an empty id drops everything
+func (a *App) EvictCache(templateID string) {
+	if templateID == "" {
+		a.cache.Purge()
+		return
+	}
+	a.cache.RemoveFunc(func(key cacheKey) bool {
+		return key.TemplateID == templateID
+	})
+}
+
+func (a *App) CacheStats() cache.Stats {
+	return a.cache.Stats()
+}
@@ -0,0 +1,56 @@
+package template
+
+import (
+	"context"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/email"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+// capture stores a message sent with a test key in place of the provider, it
+// went through the same rendering and credential checks as a real send
+func (a *App) capture(ctx context.Context, req shared.SendTemplateRequest, rendered *shared.RenderTemplateResponse, cred *entity.Credential, input *email.MessageInput) (*entity.CapturedMessage, error) {
+	captured := &entity.CapturedMessage{
+		AccountID:    req.AccountID,
+		KeyID:        req.TestKeyID,
+		TemplateID:   rendered.TemplateID,
+		Version:      rendered.Version,
+		CredentialID: cred.ID,
+		Platform:     cred.Platform,
+		From:         input.From,
+		To:           input.To,
+		Subject:      input.Subject,
+		HTMLContent:  input.HTMLContent,
+		TextContent:  input.TextContent,
+	}
+	if err := a.db.CaptureRepository.Create(ctx, captured); err != nil {
+		a.logger.ErrorContext(ctx, "failed to capture test message", "template_id", rendered.TemplateID, "err", err)
+		return nil, err
+	}
+	return captured, nil
+}
+
+// ListCaptured returns the messages sent with the account's test keys, newest first
+func (a *App) ListCaptured(ctx context.Context, req shared.ListCapturedMessagesRequest) (*util.PaginationT[[]entity.CapturedMessage], error) {
+	conditions := []util.Query{util.Eq("account_id", req.AccountID)}
+	if req.To != "" {
+		conditions = append(conditions, util.Eq("to_address", req.To))
+	}
+	return a.db.CaptureRepository.FindWithPagination(ctx,
+		util.AndQuery(conditions...),
+		repository.WithOrderBy("created_at", "desc"),
+		repository.WithPagination(req.Page, req.PageSize),
+	)
+}
+
+func (a *App) GetCaptured(ctx context.Context, accountID, id string) (*entity.CapturedMessage, error) {
+	return a.db.CaptureRepository.Get(ctx, "id = ? AND account_id = ?", id, accountID)
+}
+
+// ClearCaptured deletes every captured message of the account
+func (a *App) ClearCaptured(ctx context.Context, accountID string) error {
+	return a.db.CaptureRepository.DeleteByFieldName(ctx, util.Eq("account_id", accountID))
+}
@@ -0,0 +1,39 @@
+package template
+
+import (
+	"context"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+)
+
+// Content returns the body of a template version after checking it against
+// the hash stored with the version. When req.Version is set the content of
+// that version of the template is returned instead.
+func (a *App) Content(ctx context.Context, req shared.TemplateContentRequest) (*entity.Template, []byte, error) {
+	template, err := a.db.TemplateRepository.Get(ctx, "id = ? AND account_id = ?", req.TemplateID, req.AccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.Version != 0 && req.Version != template.Version {
+		template, err = a.db.TemplateRepository.Get(ctx, "account_id = ? AND slug = ? AND version = ?", req.AccountID, template.Slug, req.Version)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	content, err := a.fetchContent(ctx, template.Location)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to fetch template content", "template_id", template.ID, "err", err)
+		return nil, nil, err
+	}
+	if err := verifyContent(template, content); err != nil {
+		a.logger.ErrorContext(ctx, "template content failed integrity check", "template_id", template.ID, "location", template.Location)
+		return nil, nil, err
+	}
+	if template.ContentHash == "" {
+		// versions saved before content was hashed still get an etag
+		template.ContentHash = contentHash(content)
+	}
+	return template, content, nil
+}
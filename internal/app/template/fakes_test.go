@@ -0,0 +1,264 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"template-manager/internal/entity"
+	"template-manager/pkg/email"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+// fakeTemplates keeps templates in memory, it only understands the queries
+// of the trash and the lifecycle of versions
+type fakeTemplates struct {
+	repository.TemplateRepositoryInterface[entity.Template]
+	t    *testing.T
+	rows []entity.Template
+	// onLock runs when a transaction locks the versions of a template, e.g.
+	// to let a concurrent request finish first
+	onLock func()
+}
+
+func (f *fakeTemplates) match(query any, row entity.Template) bool {
+	q := query.(util.Query)
+	deleted := row.DeletedAt.Valid
+	switch q.Query {
+	case "id = ? AND account_id = ? AND deleted_at IS NOT NULL":
+		return deleted && row.ID == q.Args[0] && row.AccountID == q.Args[1]
+	case "deleted_at IS NOT NULL AND deleted_at < ?":
+		return deleted && row.DeletedAt.Time.Before(q.Args[0].(time.Time))
+	case "id IN (?) AND deleted_at IS NOT NULL":
+		return deleted && slices.Contains(q.Args[0].([]string), row.ID)
+	case "location = ?":
+		return row.Location == q.Args[0]
+	case "id = ? AND account_id = ?":
+		return !deleted && row.ID == q.Args[0] && row.AccountID == q.Args[1]
+	case "id = ? AND status = ?":
+		return !deleted && row.ID == q.Args[0] && row.Status == q.Args[1]
+	case " ( account_id = ? AND slug = ? ) ":
+		return !deleted && row.AccountID == q.Args[0] && row.Slug == q.Args[1]
+	case " ( account_id = ? AND slug = ? AND status = ? ) ":
+		// util.Eq passes the status as a plain string
+		return !deleted && row.AccountID == q.Args[0] && row.Slug == q.Args[1] && string(row.Status) == q.Args[2]
+	case "account_id = ? AND slug = ? AND status = ? AND id <> ?":
+		return !deleted && row.AccountID == q.Args[0] && row.Slug == q.Args[1] && row.Status == q.Args[2] && row.ID != q.Args[3]
+	}
+	f.t.Fatalf("unexpected template query %q", q.Query)
+	return false
+}
+
+func (f *fakeTemplates) Get(_ context.Context, conds ...any) (*entity.Template, error) {
+	query := util.Query{Query: conds[0].(string), Args: conds[1:]}
+	for _, row := range f.rows {
+		if f.match(query, row) {
+			return &row, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeTemplates) FindManyWithOptions(_ context.Context, query any, opts ...repository.Opt) ([]entity.Template, error) {
+	// only the lock of transition passes a single option
+	if len(opts) == 1 && f.onLock != nil {
+		lock := f.onLock
+		f.onLock = nil
+		lock()
+	}
+	var found []entity.Template
+	for _, row := range f.rows {
+		if f.match(query, row) {
+			found = append(found, row)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeTemplates) UpdateWhere(_ context.Context, query any, data any) (int64, error) {
+	var updated int64
+	for i, row := range f.rows {
+		if !f.match(query, row) {
+			continue
+		}
+		for column, value := range data.(map[string]any) {
+			switch column {
+			case "status":
+				f.rows[i].Status = value.(entity.TemplateStatus)
+			case "published_at":
+				f.rows[i].PublishedAt = value.(*time.Time)
+			case "updated_at":
+				f.rows[i].UpdatedAt = value.(time.Time)
+			default:
+				f.t.Fatalf("unexpected template column %q", column)
+			}
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func (f *fakeTemplates) UpdateMany(ctx context.Context, query any, data any) error {
+	_, err := f.UpdateWhere(ctx, query, data)
+	return err
+}
+
+func (f *fakeTemplates) Purge(_ context.Context, query any) (int64, error) {
+	var kept []entity.Template
+	for _, row := range f.rows {
+		if !f.match(query, row) {
+			kept = append(kept, row)
+		}
+	}
+	purged := int64(len(f.rows) - len(kept))
+	f.rows = kept
+	return purged, nil
+}
+
+// status returns the status of the version id
+func (f *fakeTemplates) status(id string) entity.TemplateStatus {
+	for _, row := range f.rows {
+		if row.ID == id {
+			return row.Status
+		}
+	}
+	f.t.Fatalf("no template %s", id)
+	return ""
+}
+
+type fakeReviews struct {
+	repository.TemplateReviewRepositoryInterface[entity.TemplateReview]
+	rows []entity.TemplateReview
+}
+
+func (f *fakeReviews) Create(_ context.Context, review *entity.TemplateReview) error {
+	f.rows = append(f.rows, *review)
+	return nil
+}
+
+// fakeNotifier records the webhook events of an app
+type fakeNotifier struct {
+	events []entity.WebhookEvent
+}
+
+func (f *fakeNotifier) Publish(_ context.Context, _ string, event entity.WebhookEvent, _ any) {
+	f.events = append(f.events, event)
+}
+
+// fakeSchedules keeps schedules in memory, it only understands the queries of
+// the scheduler
+type fakeSchedules struct {
+	repository.ScheduleRepositoryInterface[entity.TemplateSchedule]
+	t    *testing.T
+	rows []entity.TemplateSchedule
+}
+
+func (f *fakeSchedules) match(query any, row entity.TemplateSchedule) bool {
+	q := query.(util.Query)
+	claimedBefore := func(at any) bool {
+		return row.ClaimedAt != nil && row.ClaimedAt.Before(at.(time.Time))
+	}
+	switch q.Query {
+	case "(status = ? AND publish_at <= ?) OR (status = ? AND claimed_at < ?)":
+		return (row.Status == q.Args[0] && !row.PublishAt.After(q.Args[1].(time.Time))) ||
+			(row.Status == q.Args[2] && claimedBefore(q.Args[3]))
+	case "(status = ? AND revert_at <= ?) OR (status = ? AND claimed_at < ?)":
+		return (row.Status == q.Args[0] && row.RevertAt != nil && !row.RevertAt.After(q.Args[1].(time.Time))) ||
+			(row.Status == q.Args[2] && claimedBefore(q.Args[3]))
+	case "id = ? AND (status = ? OR (status = ? AND claimed_at < ?))":
+		return row.ID == q.Args[0] && (row.Status == q.Args[1] || (row.Status == q.Args[2] && claimedBefore(q.Args[3])))
+	case "id = ?":
+		return row.ID == q.Args[0]
+	}
+	f.t.Fatalf("unexpected schedule query %q", q.Query)
+	return false
+}
+
+func (f *fakeSchedules) FindManyWithOptions(_ context.Context, query any, _ ...repository.Opt) ([]entity.TemplateSchedule, error) {
+	var found []entity.TemplateSchedule
+	for _, row := range f.rows {
+		if f.match(query, row) {
+			found = append(found, row)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSchedules) UpdateWhere(_ context.Context, query any, data any) (int64, error) {
+	var updated int64
+	for i, row := range f.rows {
+		if !f.match(query, row) {
+			continue
+		}
+		for column, value := range data.(map[string]any) {
+			switch column {
+			case "status":
+				f.rows[i].Status = value.(entity.ScheduleStatus)
+			case "claimed_at":
+				claimedAt := value.(time.Time)
+				f.rows[i].ClaimedAt = &claimedAt
+			case "previous_template_id":
+				f.rows[i].PreviousTemplateID = value.(string)
+			case "error":
+				f.rows[i].Error = value.(string)
+			case "updated_at":
+				f.rows[i].UpdatedAt = value.(time.Time)
+			default:
+				f.t.Fatalf("unexpected schedule column %q", column)
+			}
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+type fakeAccounts struct {
+	repository.AccountRepositoryInterface[entity.Account]
+	account entity.Account
+}
+
+func (f *fakeAccounts) Get(_ context.Context, conds ...any) (*entity.Account, error) {
+	if conds[0] != "id = ?" || conds[1] != f.account.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &f.account, nil
+}
+
+type fakeCredentials struct {
+	repository.CredentialRepositoryInterface[entity.Credential]
+	t    *testing.T
+	cred entity.Credential
+}
+
+func (f *fakeCredentials) Get(_ context.Context, conds ...any) (*entity.Credential, error) {
+	if conds[0] != "account_id = ? AND type = ? AND is_active = ?" {
+		f.t.Fatalf("unexpected credential query %q", conds[0])
+	}
+	return &f.cred, nil
+}
+
+type fakeCaptures struct {
+	repository.CapturedMessageRepositoryInterface[entity.CapturedMessage]
+	rows []entity.CapturedMessage
+}
+
+func (f *fakeCaptures) Create(_ context.Context, message *entity.CapturedMessage) error {
+	message.ID = fmt.Sprintf("msg%d", len(f.rows)+1)
+	f.rows = append(f.rows, *message)
+	return nil
+}
+
+// fakeSender records the messages it was asked to deliver
+type fakeSender struct {
+	sent []*email.MessageInput
+}
+
+func (f *fakeSender) SendMessage(_ context.Context, input *email.MessageInput) error {
+	f.sent = append(f.sent, input)
+	return nil
+}
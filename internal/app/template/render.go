@@ -0,0 +1,278 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/expression"
+	"template-manager/pkg/netguard"
+)
+
+const maxContentSize = 5 << 20 // 5MB
+
+var (
+	ErrContentTooLarge = fmt.Errorf("template content is larger than %d bytes", maxContentSize)
+	ErrContentChanged  = errors.New("template content was modified after the version was saved, refusing to render it")
+)
+
+// Render fetches the content of a template and executes it with the given vars.
+// Only the published version is rendered unless req.Draft is set.
+// Content blocks are selected by evaluating their conditions against the
+// recipient attributes and are exposed to the template as {{ .blocks.<name> }}.
+func (a *App) Render(ctx context.Context, req shared.RenderTemplateRequest) (*shared.RenderTemplateResponse, error) {
+	start := time.Now()
+	template, rendered, err := a.renderTemplate(ctx, req)
+	a.record(ctx, template, entity.EventKindRender, "", start, err)
+	return rendered, err
+}
+
+// renderTemplate returns the resolved template along with the result, the
+// template is nil when it could not be resolved
+func (a *App) renderTemplate(ctx context.Context, req shared.RenderTemplateRequest) (*entity.Template, *shared.RenderTemplateResponse, error) {
+	template, err := a.resolve(ctx, req.AccountID, req.TemplateID, req.Draft)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compiled, err := a.compiled(ctx, template)
+	if err != nil {
+		return template, nil, err
+	}
+
+	rendered, err := compiled.execute(template.Vars, req.Vars, req.Attributes)
+	if err != nil {
+		return template, nil, err
+	}
+
+	return template, &shared.RenderTemplateResponse{
+		TemplateID:  template.ID,
+		Version:     template.Version,
+		ContentType: template.ContentType,
+		Content:     rendered,
+	}, nil
+}
+
+// record reports a render or send to the recorder. Nothing is recorded when
+// the template could not be resolved since there is no template to attribute it to.
+func (a *App) record(ctx context.Context, template *entity.Template, kind entity.EventKind, channel entity.PlatformType, start time.Time, err error) {
+	if a.recorder == nil || template == nil {
+		return
+	}
+	event := &entity.TemplateEvent{
+		AccountID:  template.AccountID,
+		TemplateID: template.ID,
+		Slug:       template.Slug,
+		Version:    template.Version,
+		Kind:       kind,
+		Channel:    channel,
+		LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+		Outcome:    entity.EventOutcomeSuccess,
+	}
+	if err != nil {
+		event.Outcome = entity.EventOutcomeFailure
+		event.Error = err.Error()
+	}
+	a.recorder.Record(ctx, event)
+}
+
+// fetchContent reads the content at location from the storage, locations
+// outside of it (saved before uploads were verified) are fetched over http
+// from public addresses only
+func (a *App) fetchContent(ctx context.Context, location string) ([]byte, error) {
+	filename, err := a.storage.FilenameFromLocation(location)
+	if err != nil {
+		return a.downloadContent(ctx, location)
+	}
+	file, _, err := a.storage.GetFile(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readContent(file)
+}
+
+func (a *App) downloadContent(ctx context.Context, location string) ([]byte, error) {
+	if err := netguard.CheckURL(ctx, location); err != nil {
+		return nil, fmt.Errorf("failed to fetch template content: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch template content: %s", resp.Status)
+	}
+
+	return readContent(resp.Body)
+}
+
+func readContent(r io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(r, maxContentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxContentSize {
+		return nil, ErrContentTooLarge
+	}
+	return content, nil
+}
+
+// verifyContent checks the content is what was stored when the version was
+// saved. Versions saved before hashes were recorded are not checked.
+func verifyContent(template *entity.Template, content []byte) error {
+	if template.ContentHash == "" {
+		return nil
+	}
+	if int64(len(content)) != template.ContentSize || contentHash(content) != template.ContentHash {
+		return ErrContentChanged
+	}
+	return nil
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// compiled is a parsed template version, it is safe for concurrent use
+type compiled struct {
+	html   bool
+	main   executor
+	blocks []compiledBlock
+	size   int64 // approximate memory held, counted against the cache limit
+}
+
+type compiledBlock struct {
+	name      string
+	condition *expression.Expression
+	content   executor
+}
+
+// executor is implemented by both text/template and html/template
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+func compile(template *entity.Template, content string) (*compiled, error) {
+	html := isHTML(template.ContentType)
+	main, err := parse(html, template.Slug, content)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiled{
+		html: html,
+		main: main,
+		size: int64(len(content)),
+	}
+	for _, block := range template.Blocks {
+		condition, err := expression.Compile(block.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("block %q: invalid condition: %w", block.Name, err)
+		}
+		blockContent, err := parse(html, block.Name, block.Content)
+		if err != nil {
+			return nil, fmt.Errorf("block %q: %w", block.Name, err)
+		}
+		c.blocks = append(c.blocks, compiledBlock{name: block.Name, condition: condition, content: blockContent})
+		c.size += int64(len(block.Content) + len(block.Condition))
+	}
+	return c, nil
+}
+
+// execute renders the template, defaults are the vars stored with the
+// template and are overridden by vars
+func (c *compiled) execute(defaults, vars entity.Map, attrs map[string]any) (string, error) {
+	data := make(map[string]any, len(defaults)+len(vars)+2)
+	for k, v := range defaults {
+		data[k] = v
+	}
+	for k, v := range vars {
+		data[k] = v
+	}
+
+	selected, err := c.selectBlocks(attrs)
+	if err != nil {
+		return "", err
+	}
+
+	blocks := make(map[string]any, len(selected))
+	for name, block := range selected {
+		if block == nil {
+			blocks[name] = ""
+			continue
+		}
+		var buf bytes.Buffer
+		if err := block.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("block %q: %w", name, err)
+		}
+		if c.html {
+			// the block went through html/template already, so it is safe to embed as is
+			blocks[name] = htmltemplate.HTML(buf.String())
+			continue
+		}
+		blocks[name] = buf.String()
+	}
+	data["blocks"] = blocks
+	data["recipient"] = attrs
+
+	var buf bytes.Buffer
+	if err := c.main.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// selectBlocks returns the first block of each name whose condition holds
+// for attrs. Names without a matching block map to nil so templates can
+// reference every block unconditionally.
+func (c *compiled) selectBlocks(attrs map[string]any) (map[string]executor, error) {
+	selected := make(map[string]executor, len(c.blocks))
+	matched := make(map[string]bool, len(c.blocks))
+	for _, block := range c.blocks {
+		if _, ok := selected[block.name]; !ok {
+			selected[block.name] = nil
+		}
+		if matched[block.name] {
+			continue
+		}
+		ok, err := block.condition.Eval(attrs)
+		if err != nil {
+			return nil, fmt.Errorf("block %q: %w", block.name, err)
+		}
+		if ok {
+			selected[block.name] = block.content
+			matched[block.name] = true
+		}
+	}
+	return selected, nil
+}
+
+func isHTML(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "html")
+}
+
+func parse(html bool, name, content string) (executor, error) {
+	if html {
+		return htmltemplate.New(name).Option("missingkey=zero").Parse(content)
+	}
+	return texttemplate.New(name).Option("missingkey=zero").Parse(content)
+}
@@ -0,0 +1,165 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+var (
+	ErrNotDraft       = errors.New("only draft templates can be edited, create a new version instead")
+	ErrNotPublished   = errors.New("template has no published version")
+	ErrCommentMissing = errors.New("a comment is required when rejecting a template")
+	ErrStatusChanged  = errors.New("the template status was changed by another request, reload it and try again")
+)
+
+// RequestReview submits a draft for review
+func (a *App) RequestReview(ctx context.Context, req shared.ReviewTemplateRequest) (*entity.Template, error) {
+	return a.transition(ctx, req, entity.TemplateStatusInReview, entity.ReviewActionRequested, nil)
+}
+
+// Approve lets a version in review be published. Approval is an action of the
+// account: any session or key with the templates:approve scope may approve,
+// including the one that submitted the version.
+func (a *App) Approve(ctx context.Context, req shared.ReviewTemplateRequest) (*entity.Template, error) {
+	return a.transition(ctx, req, entity.TemplateStatusApproved, entity.ReviewActionApproved, nil)
+}
+
+// Reject sends a template in review back to draft, the comment tells the author why
+func (a *App) Reject(ctx context.Context, req shared.ReviewTemplateRequest) (*entity.Template, error) {
+	if req.Comment == "" {
+		return nil, ErrCommentMissing
+	}
+	return a.transition(ctx, req, entity.TemplateStatusDraft, entity.ReviewActionRejected, nil)
+}
+
+// Publish makes an approved version the one used for render and send,
+// the previously published version of the template is archived
+func (a *App) Publish(ctx context.Context, req shared.ReviewTemplateRequest) (*entity.Template, error) {
+	template, err := a.transition(ctx, req, entity.TemplateStatusPublished, entity.ReviewActionPublished,
+		func(tx repository.Container, template *entity.Template) error {
+			return tx.TemplateRepository.UpdateMany(ctx,
+				util.Query{
+					Query: "account_id = ? AND slug = ? AND status = ? AND id <> ?",
+					Args:  []any{template.AccountID, template.Slug, entity.TemplateStatusPublished, template.ID},
+				},
+				map[string]any{"status": entity.TemplateStatusArchived, "updated_at": time.Now().UTC()},
+			)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.notifier.Publish(ctx, template.AccountID, entity.WebhookEventTemplateVersionPublished, template)
+	return template, nil
+}
+
+func (a *App) Archive(ctx context.Context, req shared.ReviewTemplateRequest) (*entity.Template, error) {
+	return a.transition(ctx, req, entity.TemplateStatusArchived, entity.ReviewActionArchived, nil)
+}
+
+// ListReviews returns the lifecycle history of a template version, newest first
+func (a *App) ListReviews(ctx context.Context, req shared.GetTemplateRequest) ([]entity.TemplateReview, error) {
+	return a.db.ReviewRepository.Find(ctx, "template_id = ? AND account_id = ?", req.TemplateID, req.AccountID)
+}
+
+// transition moves a version to another status and records the review, after
+// runs in the same transaction once the status changed. The versions of the
+// template are locked first so transitions of one template apply one after
+// the other, and the status is only changed if nobody else changed it since it
+// was read.
+func (a *App) transition(
+	ctx context.Context,
+	req shared.ReviewTemplateRequest,
+	to entity.TemplateStatus,
+	action entity.ReviewAction,
+	after func(tx repository.Container, template *entity.Template) error,
+) (*entity.Template, error) {
+	template, err := a.db.TemplateRepository.Get(ctx, "id = ? AND account_id = ?", req.TemplateID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := template.Status
+	if err := template.Transition(to); err != nil {
+		return nil, err
+	}
+	template.UpdatedAt = time.Now().UTC()
+
+	err = a.db.Transaction(ctx, func(tx repository.Container) error {
+		if _, err := tx.TemplateRepository.FindManyWithOptions(ctx,
+			util.AndQuery(util.Eq("account_id", template.AccountID), util.Eq("slug", template.Slug)),
+			repository.WithLock(),
+		); err != nil {
+			return err
+		}
+
+		updated, err := tx.TemplateRepository.UpdateWhere(ctx,
+			util.Query{Query: "id = ? AND status = ?", Args: []any{template.ID, from}},
+			map[string]any{"status": template.Status, "published_at": template.PublishedAt, "updated_at": template.UpdatedAt},
+		)
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return ErrStatusChanged
+		}
+		if after != nil {
+			if err := after(tx, template); err != nil {
+				return err
+			}
+		}
+
+		return tx.ReviewRepository.Create(ctx, &entity.TemplateReview{
+			AccountID:  req.AccountID,
+			TemplateID: template.ID,
+			Action:     action,
+			From:       from,
+			To:         to,
+			Comment:    req.Comment,
+			Actor:      req.Actor,
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, ErrStatusChanged) {
+			a.logger.ErrorContext(ctx, "failed to change template status", "template_id", template.ID, "to", to, "err", err)
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+// resolve returns the version of a template that should be rendered. Unless a
+// draft is explicitly asked for, that is the published version of the template
+// the given version belongs to.
+func (a *App) resolve(ctx context.Context, accountID, templateID string, draft bool) (*entity.Template, error) {
+	template, err := a.db.TemplateRepository.Get(ctx, "id = ? AND account_id = ?", templateID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if draft || template.Status == entity.TemplateStatusPublished {
+		return template, nil
+	}
+
+	published, err := a.db.TemplateRepository.FindManyWithOptions(ctx,
+		util.AndQuery(
+			util.Eq("account_id", accountID),
+			util.Eq("slug", template.Slug),
+			util.Eq("status", entity.TemplateStatusPublished),
+		),
+		repository.WithOrderBy("published_at", "desc"),
+		repository.WithPagination(1, 1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(published) == 0 {
+		return nil, ErrNotPublished
+	}
+	return &published[0], nil
+}
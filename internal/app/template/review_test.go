@@ -0,0 +1,124 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository"
+)
+
+func newReviewApp(t *testing.T, versions ...entity.Template) (*App, *fakeTemplates, *fakeReviews) {
+	templates := &fakeTemplates{t: t, rows: versions}
+	reviews := &fakeReviews{}
+	app := &App{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		db:       repository.Container{TemplateRepository: templates, ReviewRepository: reviews},
+		notifier: &fakeNotifier{},
+	}
+	return app, templates, reviews
+}
+
+func version(id string, status entity.TemplateStatus) entity.Template {
+	v := entity.Template{ID: id, AccountID: "acc", Slug: "welcome", Status: status}
+	if status == entity.TemplateStatusPublished {
+		publishedAt := time.Now().Add(-time.Hour)
+		v.PublishedAt = &publishedAt
+	}
+	return v
+}
+
+func review(id string) shared.ReviewTemplateRequest {
+	return shared.ReviewTemplateRequest{AccountID: "acc", TemplateID: id, Comment: "looks good"}
+}
+
+func TestPublishArchivesPreviousVersion(t *testing.T) {
+	app, templates, reviews := newReviewApp(t,
+		version("v1", entity.TemplateStatusPublished),
+		version("v2", entity.TemplateStatusApproved),
+	)
+
+	published, err := app.Publish(context.Background(), review("v2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published.Status != entity.TemplateStatusPublished || published.PublishedAt == nil {
+		t.Errorf("Publish() = %+v, want a published version", published)
+	}
+	if got := templates.status("v1"); got != entity.TemplateStatusArchived {
+		t.Errorf("status of v1 = %s, want %s", got, entity.TemplateStatusArchived)
+	}
+	if got := templates.status("v2"); got != entity.TemplateStatusPublished {
+		t.Errorf("status of v2 = %s, want %s", got, entity.TemplateStatusPublished)
+	}
+	if len(reviews.rows) != 1 || reviews.rows[0].From != entity.TemplateStatusApproved {
+		t.Errorf("reviews = %+v, want the publish of v2", reviews.rows)
+	}
+}
+
+func TestTransitionRejectsInvalidMove(t *testing.T) {
+	app, templates, reviews := newReviewApp(t, version("v1", entity.TemplateStatusDraft))
+
+	if _, err := app.Publish(context.Background(), review("v1")); err == nil {
+		t.Fatal("a draft was published without review")
+	}
+	if got := templates.status("v1"); got != entity.TemplateStatusDraft {
+		t.Errorf("status of v1 = %s, want %s", got, entity.TemplateStatusDraft)
+	}
+	if len(reviews.rows) != 0 {
+		t.Errorf("reviews = %+v, want none", reviews.rows)
+	}
+}
+
+func TestTransitionLosesToConcurrentChange(t *testing.T) {
+	app, templates, reviews := newReviewApp(t, version("v1", entity.TemplateStatusInReview))
+	// the version is rejected while the approval waits for the lock
+	templates.onLock = func() {
+		if _, err := app.Reject(context.Background(), review("v1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := app.Approve(context.Background(), review("v1")); !errors.Is(err, ErrStatusChanged) {
+		t.Fatalf("Approve() err = %v, want %v", err, ErrStatusChanged)
+	}
+	if got := templates.status("v1"); got != entity.TemplateStatusDraft {
+		t.Errorf("status of v1 = %s, want %s", got, entity.TemplateStatusDraft)
+	}
+	if len(reviews.rows) != 1 || reviews.rows[0].Action != entity.ReviewActionRejected {
+		t.Errorf("reviews = %+v, want only the rejection", reviews.rows)
+	}
+}
+
+func TestConcurrentPublishesLeaveOnePublished(t *testing.T) {
+	app, templates, _ := newReviewApp(t,
+		version("v1", entity.TemplateStatusPublished),
+		version("v2", entity.TemplateStatusApproved),
+		version("v3", entity.TemplateStatusApproved),
+	)
+	// v2 is published while the publish of v3 waits for the lock
+	templates.onLock = func() {
+		if _, err := app.Publish(context.Background(), review("v2")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := app.Publish(context.Background(), review("v3")); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]entity.TemplateStatus{
+		"v1": entity.TemplateStatusArchived,
+		"v2": entity.TemplateStatusArchived,
+		"v3": entity.TemplateStatusPublished,
+	}
+	for id, status := range want {
+		if got := templates.status(id); got != status {
+			t.Errorf("status of %s = %s, want %s", id, got, status)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+// scheduleLease is how long a scheduler has to publish or revert a schedule
+// it claimed, past that the claim is stale (e.g the instance crashed) and
+// another scheduler picks the schedule up again
+const scheduleLease = 5 * time.Minute
+
+var (
+	ErrScheduleInPast     = errors.New("publish_at must be in the future")
+	ErrRevertBeforeStart  = errors.New("revert_at must be after publish_at")
+	ErrNotSchedulable     = errors.New("only approved templates can be scheduled for publishing")
+	ErrNotCancellable     = errors.New("only pending or active schedules can be cancelled")
+	ErrScheduleSuperseded = errors.New("the scheduled version is no longer published, the revert was skipped")
+)
+
+// Schedule queues an approved version to be published at a future time and
+// optionally reverted to the currently published version at RevertAt
+func (a *App) Schedule(ctx context.Context, req shared.ScheduleTemplateRequest) (*entity.TemplateSchedule, error) {
+	publishAt, revertAt, err := req.Times()
+	if err != nil {
+		return nil, err
+	}
+	if !publishAt.After(time.Now()) {
+		return nil, ErrScheduleInPast
+	}
+	if revertAt != nil && !revertAt.After(publishAt) {
+		return nil, ErrRevertBeforeStart
+	}
+
+	template, err := a.db.TemplateRepository.Get(ctx, "id = ? AND account_id = ?", req.TemplateID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if template.Status != entity.TemplateStatusApproved {
+		return nil, ErrNotSchedulable
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	schedule := entity.TemplateSchedule{
+		AccountID:  req.AccountID,
+		TemplateID: template.ID,
+		Slug:       template.Slug,
+		PublishAt:  publishAt,
+		RevertAt:   revertAt,
+		Timezone:   timezone,
+		Status:     entity.ScheduleStatusPending,
+	}
+	if err := a.db.ScheduleRepository.Create(ctx, &schedule); err != nil {
+		a.logger.ErrorContext(ctx, "failed to create schedule", "template_id", template.ID, "err", err)
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (a *App) ListSchedules(ctx context.Context, req shared.ListSchedulesRequest) ([]entity.TemplateSchedule, error) {
+	if req.Status != "" {
+		return a.db.ScheduleRepository.Find(ctx, "account_id = ? AND status = ?", req.AccountID, req.Status)
+	}
+	return a.db.ScheduleRepository.Find(ctx, "account_id = ?", req.AccountID)
+}
+
+// CancelSchedule stops a schedule from running. Cancelling an active schedule
+// keeps the scheduled version published and drops the pending revert.
+func (a *App) CancelSchedule(ctx context.Context, req shared.CancelScheduleRequest) error {
+	updated, err := a.db.ScheduleRepository.UpdateWhere(ctx,
+		util.Query{
+			Query: "id = ? AND account_id = ? AND status IN (?)",
+			Args:  []any{req.ScheduleID, req.AccountID, []entity.ScheduleStatus{entity.ScheduleStatusPending, entity.ScheduleStatusActive}},
+		},
+		map[string]any{"status": entity.ScheduleStatusCancelled, "updated_at": time.Now().UTC()},
+	)
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrNotCancellable
+	}
+	return nil
+}
+
+// RunScheduler applies due schedules every interval until ctx is done.
+// Schedules are stored in the database, so anything that came due while the
+// service was down is applied on the first tick after a restart.
+func (a *App) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.publishDueSchedules(ctx)
+		a.revertDueSchedules(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) publishDueSchedules(ctx context.Context) {
+	now := time.Now().UTC()
+	due, err := a.db.ScheduleRepository.FindManyWithOptions(ctx,
+		util.Query{
+			Query: "(status = ? AND publish_at <= ?) OR (status = ? AND claimed_at < ?)",
+			Args:  []any{entity.ScheduleStatusPending, now, entity.ScheduleStatusPublishing, now.Add(-scheduleLease)},
+		},
+		repository.WithOrderBy("publish_at", "asc"),
+	)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to find due schedules", "err", err)
+		return
+	}
+
+	for _, schedule := range due {
+		if !a.claimSchedule(ctx, schedule, entity.ScheduleStatusPending, entity.ScheduleStatusPublishing) {
+			continue
+		}
+
+		next := entity.ScheduleStatusCompleted
+		if schedule.RevertAt != nil {
+			next = entity.ScheduleStatusActive
+		}
+
+		current, err := a.resolve(ctx, schedule.AccountID, schedule.TemplateID, false)
+		if err == nil && current.ID == schedule.TemplateID {
+			// a scheduler that crashed after publishing already did the work
+			a.finishSchedule(ctx, schedule, next, nil)
+			continue
+		}
+		if err == nil && schedule.PreviousTemplateID == "" {
+			// recorded before publishing so a retry after a crash still knows it
+			schedule.PreviousTemplateID = current.ID
+			if _, err := a.db.ScheduleRepository.UpdateWhere(ctx, util.Eq("id", schedule.ID),
+				map[string]any{"previous_template_id": current.ID}); err != nil {
+				a.logger.ErrorContext(ctx, "failed to record the previous version", "schedule_id", schedule.ID, "err", err)
+				continue
+			}
+		}
+
+		if _, err := a.Publish(ctx, shared.ReviewTemplateRequest{
+			AccountID:  schedule.AccountID,
+			TemplateID: schedule.TemplateID,
+			Comment:    "published by schedule " + schedule.ID,
+			Actor:      "schedule:" + schedule.ID,
+		}); err != nil {
+			a.finishSchedule(ctx, schedule, entity.ScheduleStatusFailed, map[string]any{"error": err.Error()})
+			continue
+		}
+		a.finishSchedule(ctx, schedule, next, nil)
+	}
+}
+
+func (a *App) revertDueSchedules(ctx context.Context) {
+	now := time.Now().UTC()
+	due, err := a.db.ScheduleRepository.FindManyWithOptions(ctx,
+		util.Query{
+			Query: "(status = ? AND revert_at <= ?) OR (status = ? AND claimed_at < ?)",
+			Args:  []any{entity.ScheduleStatusActive, now, entity.ScheduleStatusReverting, now.Add(-scheduleLease)},
+		},
+		repository.WithOrderBy("revert_at", "asc"),
+	)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to find due reverts", "err", err)
+		return
+	}
+
+	for _, schedule := range due {
+		if !a.claimSchedule(ctx, schedule, entity.ScheduleStatusActive, entity.ScheduleStatusReverting) {
+			continue
+		}
+		if a.reverted(ctx, schedule) {
+			// a scheduler that crashed after reverting already did the work
+			a.finishSchedule(ctx, schedule, entity.ScheduleStatusCompleted, nil)
+			continue
+		}
+		if !a.stillPublished(ctx, schedule) {
+			// another version was published by hand since, reverting would take it down
+			a.finishSchedule(ctx, schedule, entity.ScheduleStatusFailed, map[string]any{"error": ErrScheduleSuperseded.Error()})
+			continue
+		}
+
+		// restore the previous version, or take the scheduled one down if nothing was live before it
+		req := shared.ReviewTemplateRequest{
+			AccountID:  schedule.AccountID,
+			TemplateID: schedule.PreviousTemplateID,
+			Comment:    "reverted by schedule " + schedule.ID,
+			Actor:      "schedule:" + schedule.ID,
+		}
+		transition := a.Publish
+		if schedule.PreviousTemplateID == "" {
+			req.TemplateID = schedule.TemplateID
+			transition = a.Archive
+		}
+
+		if _, err := transition(ctx, req); err != nil {
+			a.finishSchedule(ctx, schedule, entity.ScheduleStatusFailed, map[string]any{"error": err.Error()})
+			continue
+		}
+		a.finishSchedule(ctx, schedule, entity.ScheduleStatusCompleted, nil)
+	}
+}
+
+// reverted reports whether the revert of an active schedule was already applied
+func (a *App) reverted(ctx context.Context, schedule entity.TemplateSchedule) bool {
+	if schedule.PreviousTemplateID != "" {
+		current, err := a.resolve(ctx, schedule.AccountID, schedule.PreviousTemplateID, false)
+		return err == nil && current.ID == schedule.PreviousTemplateID
+	}
+	template, err := a.db.TemplateRepository.Get(ctx, "id = ? AND account_id = ?", schedule.TemplateID, schedule.AccountID)
+	return err == nil && template.Status == entity.TemplateStatusArchived
+}
+
+// stillPublished reports whether the version a schedule published is still the live one
+func (a *App) stillPublished(ctx context.Context, schedule entity.TemplateSchedule) bool {
+	template, err := a.db.TemplateRepository.Get(ctx, "id = ? AND account_id = ?", schedule.TemplateID, schedule.AccountID)
+	return err == nil && template.Status == entity.TemplateStatusPublished
+}
+
+// claimSchedule moves a schedule from one status to another, or takes over a
+// claim older than scheduleLease. It only succeeds for one caller so several
+// instances can run the scheduler side by side.
+func (a *App) claimSchedule(ctx context.Context, schedule entity.TemplateSchedule, from, to entity.ScheduleStatus) bool {
+	now := time.Now().UTC()
+	updated, err := a.db.ScheduleRepository.UpdateWhere(ctx,
+		util.Query{
+			Query: "id = ? AND (status = ? OR (status = ? AND claimed_at < ?))",
+			Args:  []any{schedule.ID, from, to, now.Add(-scheduleLease)},
+		},
+		map[string]any{"status": to, "claimed_at": now, "updated_at": now},
+	)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to claim schedule", "schedule_id", schedule.ID, "err", err)
+		return false
+	}
+	return updated == 1
+}
+
+func (a *App) finishSchedule(ctx context.Context, schedule entity.TemplateSchedule, status entity.ScheduleStatus, fields map[string]any) {
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+	fields["status"] = status
+	fields["updated_at"] = time.Now().UTC()
+	if status == entity.ScheduleStatusFailed {
+		a.logger.ErrorContext(ctx, "schedule failed", "schedule_id", schedule.ID, "err", fields["error"])
+	}
+	if _, err := a.db.ScheduleRepository.UpdateWhere(ctx, util.Eq("id", schedule.ID), fields); err != nil {
+		a.logger.ErrorContext(ctx, "failed to update schedule", "schedule_id", schedule.ID, "err", err)
+	}
+}
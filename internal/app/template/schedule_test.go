@@ -0,0 +1,251 @@
+package template
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"template-manager/internal/entity"
+)
+
+// scheduleCase runs one tick of the scheduler over a schedule of the version
+// v2 and checks where the schedule and the versions end up
+type scheduleCase struct {
+	name         string
+	versions     []entity.Template
+	schedule     entity.TemplateSchedule
+	wantStatus   entity.ScheduleStatus
+	wantError    string
+	wantPrevious string
+	wantVersions map[string]entity.TemplateStatus
+	wantReviews  int
+}
+
+func runScheduleCases(t *testing.T, cases []scheduleCase, tick func(*App, context.Context)) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app, templates, reviews := newReviewApp(t, tc.versions...)
+			schedules := &fakeSchedules{t: t, rows: []entity.TemplateSchedule{tc.schedule}}
+			app.db.ScheduleRepository = schedules
+
+			tick(app, context.Background())
+
+			got := schedules.rows[0]
+			if got.Status != tc.wantStatus {
+				t.Errorf("schedule status = %s, want %s", got.Status, tc.wantStatus)
+			}
+			if got.Error != tc.wantError {
+				t.Errorf("schedule error = %q, want %q", got.Error, tc.wantError)
+			}
+			if got.PreviousTemplateID != tc.wantPrevious {
+				t.Errorf("previous version = %q, want %q", got.PreviousTemplateID, tc.wantPrevious)
+			}
+			for id, want := range tc.wantVersions {
+				if got := templates.status(id); got != want {
+					t.Errorf("status of %s = %s, want %s", id, got, want)
+				}
+			}
+			if len(reviews.rows) != tc.wantReviews {
+				t.Errorf("recorded %d reviews, want %d", len(reviews.rows), tc.wantReviews)
+			}
+		})
+	}
+}
+
+// wasLive returns a version that was published before, so it can be restored
+func wasLive(id string, status entity.TemplateStatus) entity.Template {
+	v := version(id, status)
+	publishedAt := time.Now().Add(-24 * time.Hour)
+	v.PublishedAt = &publishedAt
+	return v
+}
+
+func TestPublishDueSchedules(t *testing.T) {
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	recentClaim, staleClaim := now.Add(-time.Minute), now.Add(-2*scheduleLease)
+
+	pending := entity.TemplateSchedule{ID: "s1", AccountID: "acc", TemplateID: "v2", Slug: "welcome", PublishAt: past, Status: entity.ScheduleStatusPending}
+	withRevert := pending
+	withRevert.RevertAt = &future
+	notDue := pending
+	notDue.PublishAt = future
+	claimed := pending
+	claimed.Status, claimed.ClaimedAt = entity.ScheduleStatusPublishing, &recentClaim
+	stale := pending
+	stale.Status, stale.ClaimedAt, stale.PreviousTemplateID = entity.ScheduleStatusPublishing, &staleClaim, "v1"
+
+	runScheduleCases(t, []scheduleCase{
+		{
+			name:         "publishes a due schedule and completes it",
+			versions:     []entity.Template{version("v1", entity.TemplateStatusPublished), version("v2", entity.TemplateStatusApproved)},
+			schedule:     pending,
+			wantStatus:   entity.ScheduleStatusCompleted,
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusArchived, "v2": entity.TemplateStatusPublished},
+			wantReviews:  1,
+		},
+		{
+			name:         "waits for the revert after publishing",
+			versions:     []entity.Template{version("v1", entity.TemplateStatusPublished), version("v2", entity.TemplateStatusApproved)},
+			schedule:     withRevert,
+			wantStatus:   entity.ScheduleStatusActive,
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusArchived, "v2": entity.TemplateStatusPublished},
+			wantReviews:  1,
+		},
+		{
+			name:         "leaves schedules that are not due",
+			versions:     []entity.Template{version("v1", entity.TemplateStatusPublished), version("v2", entity.TemplateStatusApproved)},
+			schedule:     notDue,
+			wantStatus:   entity.ScheduleStatusPending,
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusPublished, "v2": entity.TemplateStatusApproved},
+		},
+		{
+			name:         "leaves schedules claimed by another scheduler",
+			versions:     []entity.Template{version("v1", entity.TemplateStatusPublished), version("v2", entity.TemplateStatusApproved)},
+			schedule:     claimed,
+			wantStatus:   entity.ScheduleStatusPublishing,
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusPublished, "v2": entity.TemplateStatusApproved},
+		},
+		{
+			name:         "takes over a stale claim",
+			versions:     []entity.Template{version("v1", entity.TemplateStatusPublished), version("v2", entity.TemplateStatusApproved)},
+			schedule:     stale,
+			wantStatus:   entity.ScheduleStatusCompleted,
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusArchived, "v2": entity.TemplateStatusPublished},
+			wantReviews:  1,
+		},
+		{
+			name:         "finishes a publish applied before a crash",
+			versions:     []entity.Template{wasLive("v1", entity.TemplateStatusArchived), version("v2", entity.TemplateStatusPublished)},
+			schedule:     stale,
+			wantStatus:   entity.ScheduleStatusCompleted,
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusArchived, "v2": entity.TemplateStatusPublished},
+		},
+		{
+			name:         "fails when the version can't be published",
+			versions:     []entity.Template{version("v1", entity.TemplateStatusPublished), version("v2", entity.TemplateStatusDraft)},
+			schedule:     pending,
+			wantStatus:   entity.ScheduleStatusFailed,
+			wantError:    "template cannot move from draft to published",
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusPublished, "v2": entity.TemplateStatusDraft},
+		},
+	}, (*App).publishDueSchedules)
+}
+
+func TestRevertDueSchedules(t *testing.T) {
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	recentClaim, staleClaim := now.Add(-time.Minute), now.Add(-2*scheduleLease)
+
+	active := entity.TemplateSchedule{
+		ID: "s1", AccountID: "acc", TemplateID: "v2", Slug: "welcome", PreviousTemplateID: "v1",
+		PublishAt: past.Add(-time.Hour), RevertAt: &past, Status: entity.ScheduleStatusActive,
+	}
+	nothingBefore := active
+	nothingBefore.PreviousTemplateID = ""
+	notDue := active
+	notDue.RevertAt = &future
+	claimed := active
+	claimed.Status, claimed.ClaimedAt = entity.ScheduleStatusReverting, &recentClaim
+	stale := active
+	stale.Status, stale.ClaimedAt = entity.ScheduleStatusReverting, &staleClaim
+
+	runScheduleCases(t, []scheduleCase{
+		{
+			name:         "restores the previous version",
+			versions:     []entity.Template{wasLive("v1", entity.TemplateStatusArchived), version("v2", entity.TemplateStatusPublished)},
+			schedule:     active,
+			wantStatus:   entity.ScheduleStatusCompleted,
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusPublished, "v2": entity.TemplateStatusArchived},
+			wantReviews:  1,
+		},
+		{
+			name:         "archives the version when nothing was live before it",
+			versions:     []entity.Template{version("v2", entity.TemplateStatusPublished)},
+			schedule:     nothingBefore,
+			wantStatus:   entity.ScheduleStatusCompleted,
+			wantVersions: map[string]entity.TemplateStatus{"v2": entity.TemplateStatusArchived},
+			wantReviews:  1,
+		},
+		{
+			name: "skips the revert after a manual publish",
+			versions: []entity.Template{
+				wasLive("v1", entity.TemplateStatusArchived),
+				wasLive("v2", entity.TemplateStatusArchived),
+				version("v3", entity.TemplateStatusPublished),
+			},
+			schedule:     active,
+			wantStatus:   entity.ScheduleStatusFailed,
+			wantError:    ErrScheduleSuperseded.Error(),
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{
+				"v1": entity.TemplateStatusArchived,
+				"v2": entity.TemplateStatusArchived,
+				"v3": entity.TemplateStatusPublished,
+			},
+		},
+		{
+			name:         "finishes a revert applied before a crash",
+			versions:     []entity.Template{version("v1", entity.TemplateStatusPublished), wasLive("v2", entity.TemplateStatusArchived)},
+			schedule:     stale,
+			wantStatus:   entity.ScheduleStatusCompleted,
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusPublished, "v2": entity.TemplateStatusArchived},
+		},
+		{
+			name:         "leaves reverts that are not due",
+			versions:     []entity.Template{wasLive("v1", entity.TemplateStatusArchived), version("v2", entity.TemplateStatusPublished)},
+			schedule:     notDue,
+			wantStatus:   entity.ScheduleStatusActive,
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusArchived, "v2": entity.TemplateStatusPublished},
+		},
+		{
+			name:         "leaves reverts claimed by another scheduler",
+			versions:     []entity.Template{wasLive("v1", entity.TemplateStatusArchived), version("v2", entity.TemplateStatusPublished)},
+			schedule:     claimed,
+			wantStatus:   entity.ScheduleStatusReverting,
+			wantPrevious: "v1",
+			wantVersions: map[string]entity.TemplateStatus{"v1": entity.TemplateStatusArchived, "v2": entity.TemplateStatusPublished},
+		},
+	}, (*App).revertDueSchedules)
+}
+
+func TestClaimSchedule(t *testing.T) {
+	now := time.Now().UTC()
+	recentClaim, staleClaim := now.Add(-time.Minute), now.Add(-2*scheduleLease)
+
+	tests := []struct {
+		name      string
+		status    entity.ScheduleStatus
+		claimedAt *time.Time
+		want      bool
+	}{
+		{"claims a pending schedule", entity.ScheduleStatusPending, nil, true},
+		{"leaves a fresh claim alone", entity.ScheduleStatusPublishing, &recentClaim, false},
+		{"takes over a stale claim", entity.ScheduleStatusPublishing, &staleClaim, true},
+		{"leaves finished schedules alone", entity.ScheduleStatusCompleted, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _, _ := newReviewApp(t)
+			schedule := entity.TemplateSchedule{ID: "s1", Status: tt.status, ClaimedAt: tt.claimedAt}
+			app.db.ScheduleRepository = &fakeSchedules{t: t, rows: []entity.TemplateSchedule{schedule}}
+
+			ctx := context.Background()
+			if got := app.claimSchedule(ctx, schedule, entity.ScheduleStatusPending, entity.ScheduleStatusPublishing); got != tt.want {
+				t.Fatalf("claimSchedule() = %v, want %v", got, tt.want)
+			}
+			if tt.want && app.claimSchedule(ctx, schedule, entity.ScheduleStatusPending, entity.ScheduleStatusPublishing) {
+				t.Error("a second scheduler claimed the schedule too")
+			}
+		})
+	}
+}
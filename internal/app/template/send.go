@@ -0,0 +1,131 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/email"
+)
+
+var (
+	ErrNoCredential      = errors.New("no active email credential found for this account")
+	ErrUnverifiedAccount = errors.New("verify the email of your account before sending")
+)
+
+// Send renders a template and delivers it through one of the account's email credentials
+func (a *App) Send(ctx context.Context, req shared.SendTemplateRequest) (*shared.SendTemplateResponse, error) {
+	start := time.Now()
+	template, resp, err := a.send(ctx, req)
+	a.record(ctx, template, entity.EventKindSend, entity.EMAIL, start, err)
+	if err != nil && template != nil {
+		a.notifier.Publish(ctx, req.AccountID, entity.WebhookEventSendFailed, map[string]any{
+			"template_id":   template.ID,
+			"slug":          template.Slug,
+			"version":       template.Version,
+			"credential_id": req.CredentialID,
+			"to":            req.To,
+			"error":         err.Error(),
+		})
+	}
+	return resp, err
+}
+
+func (a *App) send(ctx context.Context, req shared.SendTemplateRequest) (*entity.Template, *shared.SendTemplateResponse, error) {
+	// test keys never deliver, unverified accounts can use them to try sending
+	if req.TestKeyID == "" {
+		acc, err := a.db.AuthRepository.Get(ctx, "id = ?", req.AccountID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if acc.VerifiedAt == nil {
+			return nil, nil, ErrUnverifiedAccount
+		}
+	}
+
+	template, rendered, err := a.renderTemplate(ctx, shared.RenderTemplateRequest{
+		AccountID:  req.AccountID,
+		TemplateID: req.TemplateID,
+		Vars:       req.Vars,
+		Attributes: req.Attributes,
+		Draft:      req.Draft,
+	})
+	if err != nil {
+		return template, nil, err
+	}
+
+	cred, err := a.credential(ctx, req.AccountID, req.CredentialID)
+	if err != nil {
+		return template, nil, err
+	}
+	sender, ok := a.senders[cred.Platform]
+	if !ok {
+		return template, nil, fmt.Errorf("sending through %s is not supported", cred.Platform)
+	}
+
+	var auth email.AuthCredential
+	if err := cred.Meta.Unmarshal(&auth); err != nil {
+		return template, nil, err
+	}
+	input := &email.MessageInput{
+		From:           req.From,
+		To:             req.To,
+		Subject:        req.Subject,
+		AuthCredential: auth,
+	}
+	if isHTML(rendered.ContentType) {
+		input.HTMLContent = rendered.Content
+	} else {
+		input.TextContent = rendered.Content
+	}
+
+	if req.TestKeyID != "" {
+		captured, err := a.capture(ctx, req, rendered, cred, input)
+		if err != nil {
+			return template, nil, err
+		}
+		return template, &shared.SendTemplateResponse{
+			TemplateID:   rendered.TemplateID,
+			Version:      rendered.Version,
+			CredentialID: cred.ID,
+			Platform:     cred.Platform,
+			To:           req.To,
+			CapturedID:   captured.ID,
+		}, nil
+	}
+
+	if err := sender.SendMessage(ctx, input); err != nil {
+		a.logger.ErrorContext(ctx, "failed to send template", "template_id", rendered.TemplateID, "platform", cred.Platform, "err", err)
+		return template, nil, err
+	}
+
+	return template, &shared.SendTemplateResponse{
+		TemplateID:   rendered.TemplateID,
+		Version:      rendered.Version,
+		CredentialID: cred.ID,
+		Platform:     cred.Platform,
+		To:           req.To,
+	}, nil
+}
+
+func (a *App) credential(ctx context.Context, accountID, credentialID string) (*entity.Credential, error) {
+	if credentialID == "" {
+		cred, err := a.db.CredentialRepository.Get(ctx, "account_id = ? AND type = ? AND is_active = ?", accountID, entity.EMAIL, 1)
+		if err != nil {
+			return nil, ErrNoCredential
+		}
+		return cred, nil
+	}
+
+	cred, err := a.db.CredentialRepository.Get(ctx, "id = ? AND account_id = ?", credentialID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if cred.Type != entity.EMAIL || cred.IsActive != 1 {
+		return nil, ErrNoCredential
+	}
+	return cred, nil
+}
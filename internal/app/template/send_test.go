@@ -0,0 +1,113 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/cache"
+	"template-manager/pkg/email"
+)
+
+// newSendApp is an app that can send the published template v1 of the
+// account acc through a mailgun credential, its content is already compiled
+func newSendApp(t *testing.T, verified bool) (*App, *fakeSender, *fakeCaptures) {
+	t.Helper()
+	template := version("v1", entity.TemplateStatusPublished)
+	template.Version, template.ContentType, template.ContentHash = 1, "text/plain", "hash"
+	c, err := compile(&template, "Hello {{.name}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, _, _ := newReviewApp(t, template)
+	app.cache = cache.NewLRU[cacheKey, *compiled](10, 1<<20, time.Hour)
+	app.cache.Add(cacheKey{TemplateID: template.ID, Version: template.Version, ContentHash: template.ContentHash}, c, c.size)
+
+	account := entity.Account{ID: "acc"}
+	if verified {
+		verifiedAt := time.Now()
+		account.VerifiedAt = &verifiedAt
+	}
+	captures := &fakeCaptures{}
+	sender := &fakeSender{}
+	app.db.AuthRepository = &fakeAccounts{account: account}
+	app.db.CredentialRepository = &fakeCredentials{t: t, cred: entity.Credential{
+		ID: "cred", AccountID: "acc", Platform: entity.MAILGUN, Type: entity.EMAIL, IsActive: 1, Meta: entity.Map{},
+	}}
+	app.db.CaptureRepository = captures
+	app.senders = map[entity.Platform]email.Sender{entity.MAILGUN: sender}
+	return app, sender, captures
+}
+
+func sendRequest(testKeyID string) shared.SendTemplateRequest {
+	return shared.SendTemplateRequest{
+		AccountID:  "acc",
+		TemplateID: "v1",
+		TestKeyID:  testKeyID,
+		From:       "hello@example.com",
+		To:         "someone@example.com",
+		Subject:    "Welcome",
+		Vars:       map[string]any{"name": "Ada"},
+	}
+}
+
+func TestSendWithTestKeyCaptures(t *testing.T) {
+	for _, verified := range []bool{true, false} {
+		t.Run(fmt.Sprintf("verified=%v", verified), func(t *testing.T) {
+			app, sender, captures := newSendApp(t, verified)
+
+			resp, err := app.Send(context.Background(), sendRequest("key1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sender.sent) != 0 {
+				t.Errorf("delivered %d messages, want none", len(sender.sent))
+			}
+			if len(captures.rows) != 1 {
+				t.Fatalf("captured %d messages, want 1", len(captures.rows))
+			}
+			captured := captures.rows[0]
+			if resp.CapturedID != captured.ID {
+				t.Errorf("CapturedID = %q, want %q", resp.CapturedID, captured.ID)
+			}
+			if captured.KeyID != "key1" || captured.AccountID != "acc" || captured.CredentialID != "cred" {
+				t.Errorf("captured %+v, want the key, account and credential of the send", captured)
+			}
+			if captured.To != "someone@example.com" || captured.TextContent != "Hello Ada" {
+				t.Errorf("captured to %q with %q, want the rendered message", captured.To, captured.TextContent)
+			}
+		})
+	}
+}
+
+func TestSendWithLiveKeyDelivers(t *testing.T) {
+	app, sender, captures := newSendApp(t, true)
+
+	resp, err := app.Send(context.Background(), sendRequest(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].TextContent != "Hello Ada" {
+		t.Errorf("delivered %+v, want the rendered message", sender.sent)
+	}
+	if len(captures.rows) != 0 || resp.CapturedID != "" {
+		t.Errorf("captured %d messages, want none", len(captures.rows))
+	}
+}
+
+func TestSendWithLiveKeyNeedsVerifiedAccount(t *testing.T) {
+	app, sender, captures := newSendApp(t, false)
+
+	_, err := app.Send(context.Background(), sendRequest(""))
+	if !errors.Is(err, ErrUnverifiedAccount) {
+		t.Fatalf("Send() err = %v, want %v", err, ErrUnverifiedAccount)
+	}
+	if len(sender.sent) != 0 || len(captures.rows) != 0 {
+		t.Errorf("delivered %d and captured %d messages, want none", len(sender.sent), len(captures.rows))
+	}
+}
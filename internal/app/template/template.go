@@ -4,52 +4,96 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strings"
+	"net/http"
 	"time"
 
 	"template-manager/internal/entity"
 	"template-manager/internal/shared"
+	"template-manager/pkg/cache"
 	"template-manager/pkg/config"
+	"template-manager/pkg/email"
+	"template-manager/pkg/email/mailgun"
+	"template-manager/pkg/email/mailjet"
+	"template-manager/pkg/netguard"
 	"template-manager/pkg/repository"
 	"template-manager/pkg/repository/util"
-	"template-manager/pkg/uploader/s3"
+	"template-manager/pkg/uploader"
 )
 
 type App struct {
-	env    string
-	config *config.Config
-	logger *slog.Logger
-	db     repository.Container // TODO: replace with repository
+	env      string
+	config   *config.Config
+	logger   *slog.Logger
+	db       repository.Container // TODO: replace with repository
+	client   *http.Client
+	storage  uploader.Uploader
+	senders  map[entity.Platform]email.Sender
+	recorder Recorder
+	notifier Notifier
 
+	cache       *cache.LRU[cacheKey, *compiled]
+	broadcaster Broadcaster
 }
 
-func New(config *config.Config, logger *slog.Logger, db repository.Container) *App {
+// Recorder receives an event for every render and send
+type Recorder interface {
+	Record(ctx context.Context, event *entity.TemplateEvent)
+}
+
+// Notifier delivers template events to the webhooks of an account
+type Notifier interface {
+	Publish(ctx context.Context, accountID string, event entity.WebhookEvent, data any)
+}
+
+// PreconditionFailedError is returned when a write is based on a stale ETag,
+// Current is the state the client should merge its changes into
+type PreconditionFailedError struct {
+	Current *entity.Template
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return "template was modified by someone else, reload it and apply your changes again"
+}
+
+func New(config *config.Config, logger *slog.Logger, db repository.Container, storage uploader.Uploader, recorder Recorder, notifier Notifier, broadcaster Broadcaster) *App {
+	env := config.GetString("ENVIRONMENT")
+	if env == "" {
+		env = "development"
+	}
 	return &App{
+		env:    env,
 		config: config,
 		db:     db,
 		logger: logger,
+		client: netguard.NewClient(10 * time.Second),
+		senders: map[entity.Platform]email.Sender{
+			entity.MAILJET: mailjet.New(),
+			entity.MAILGUN: mailgun.New(context.Background()),
+		},
+		storage:  storage,
+		recorder: recorder,
+		notifier: notifier,
+
+		cache:       newCompiledCache(config),
+		broadcaster: broadcaster,
 	}
 }
 
 func (a *App) GetUploadURL(ctx context.Context, req shared.GetUploadURLRequest) (*shared.UploadURLResponse, error) {
-
-	env := strings.ToLower(a.env)
-	//upload to s3 e.g /template/production/<account_id>
-	s3Folder := fmt.Sprintf("/template/%s/%s", env, req.AccountID)
-	s3, err := s3.NewS3("template-manager-service", "us-east-1", "text/html", s3Folder)
-	if err != nil {
-		a.logger.ErrorContext(ctx, "failed to create s3 client %+v", err)
-		return nil, err
+	if !allowedContentType(req.ContentType) {
+		return nil, ErrContentTypeNotAllowed
 	}
 
-	preSigned, err := s3.UploadPresignedURL(ctx, shared.GenerateSlug(req.Name), time.Hour/6) // 10 minutes
+	filename := a.uploadFilename(req.AccountID, req.Name)
+	preSigned, err := a.storage.UploadPresignedURL(ctx, filename, req.ContentType, time.Hour/6) // 10 minutes
 	if err != nil {
-		a.logger.ErrorContext(ctx, "failed to create presigned url %+v", err)
+		a.logger.ErrorContext(ctx, "failed to create presigned url", "err", err)
 		return nil, err
 	}
 
 	return &shared.UploadURLResponse{
 		URL:         preSigned,
+		Location:    a.storage.GetPublicURl(filename),
 		ExpireAt:    time.Now().Add(time.Hour / 6),
 		AccountID:   req.AccountID,
 		ContentType: req.ContentType,
@@ -57,17 +101,29 @@ func (a *App) GetUploadURL(ctx context.Context, req shared.GetUploadURLRequest)
 }
 
 func (a *App) Create(ctx context.Context, req shared.CreateTemplateRequest) error {
+	content, err := a.inspectUpload(ctx, req.AccountID, req.Location, req.ContentType)
+	if err != nil {
+		return err
+	}
 	var template = entity.Template{
 		AccountID:   req.AccountID,
 		Name:        req.Name,
 		Slug:        shared.GenerateSlug(req.Name),
 		Version:     1,
 		ContentType: req.ContentType,
+		ContentHash: content.Hash,
+		ContentSize: content.Size,
 		Location:    req.Location,
 		Vars:        req.Vars,
+		Blocks:      req.Blocks,
 		Active:      true,
+		Status:      entity.TemplateStatusDraft,
+	}
+	if err := a.db.TemplateRepository.Create(ctx, &template); err != nil {
+		return err
 	}
-	return a.db.TemplateRepository.Create(ctx, &template)
+	a.notifier.Publish(ctx, template.AccountID, entity.WebhookEventTemplateCreated, template)
+	return nil
 }
 
 func (a *App) Update(ctx context.Context, req shared.UpdateTemplateRequest) error {
@@ -75,21 +131,47 @@ func (a *App) Update(ctx context.Context, req shared.UpdateTemplateRequest) erro
 	if err != nil {
 		return err
 	}
+	if req.IfMatch != "" {
+		if !existing.MatchesETag(req.IfMatch) {
+			return &PreconditionFailedError{Current: existing}
+		}
+		// someone already created a version on top of the one the client edited
+		if latest, err := a.latestVersion(ctx, existing); err != nil {
+			return err
+		} else if latest.ID != existing.ID {
+			return &PreconditionFailedError{Current: latest}
+		}
+	}
+	content, err := a.inspectUpload(ctx, req.AccountID, req.Location, "")
+	if err != nil {
+		return err
+	}
 	newVersion := existing.Version + 1
 	if req.Vars == nil {
 		req.Vars = make(entity.Map)
 		req.Vars["version"] = newVersion
 	}
-	return a.db.TemplateRepository.Create(ctx, &entity.Template{
+	if req.Blocks == nil {
+		req.Blocks = existing.Blocks
+	}
+	if err := a.db.TemplateRepository.Create(ctx, &entity.Template{
 		AccountID:   req.AccountID,
 		Name:        fmt.Sprintf("%s-v%d", existing.Name, newVersion),
 		Slug:        existing.Slug,
 		Version:     newVersion,
 		ContentType: existing.ContentType,
+		ContentHash: content.Hash,
+		ContentSize: content.Size,
 		Location:    req.Location,
 		Vars:        req.Vars,
+		Blocks:      req.Blocks,
 		Active:      existing.Active,
-	})
+		Status:      entity.TemplateStatusDraft,
+	}); err != nil {
+		return err
+	}
+	a.invalidate(ctx, existing.ID)
+	return nil
 }
 
 func (a *App) Edit(ctx context.Context, req shared.UpdateTemplateRequest) error {
@@ -97,32 +179,85 @@ func (a *App) Edit(ctx context.Context, req shared.UpdateTemplateRequest) error
 	if err != nil {
 		return err
 	}
+	// anything past draft has been (or is being) reviewed, changes must go into a new version
+	if existing.Status != entity.TemplateStatusDraft {
+		return ErrNotDraft
+	}
+	if req.IfMatch != "" && !existing.MatchesETag(req.IfMatch) {
+		return &PreconditionFailedError{Current: existing}
+	}
 	if req.Vars == nil {
 		req.Vars = make(entity.Map)
 		req.Vars["version"] = existing.Version
 	}
-	return a.db.TemplateRepository.Update(ctx, &entity.Template{
-		ID:          req.TemplateID,
-		AccountID:   req.AccountID,
-		Name:        existing.Name,
-		Slug:        existing.Slug,
-		Version:     existing.Version,
-		Location:    req.Location,
-		ContentType: existing.ContentType,
-		Vars:        req.Vars,
-		Active:      existing.Active,
-	})
+	if req.Blocks == nil {
+		req.Blocks = existing.Blocks
+	}
+	content, err := a.inspectUpload(ctx, req.AccountID, req.Location, "")
+	if err != nil {
+		return err
+	}
+	// only write if nobody else did since we read the row
+	updated, err := a.db.TemplateRepository.UpdateWhere(ctx,
+		util.Query{Query: "id = ? AND updated_at = ?", Args: []any{existing.ID, existing.UpdatedAt}},
+		&entity.Template{
+			ID:          req.TemplateID,
+			AccountID:   req.AccountID,
+			Name:        existing.Name,
+			Slug:        existing.Slug,
+			Version:     existing.Version,
+			Location:    req.Location,
+			ContentType: existing.ContentType,
+			ContentHash: content.Hash,
+			ContentSize: content.Size,
+			Vars:        req.Vars,
+			Blocks:      req.Blocks,
+			Active:      existing.Active,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		current, err := a.db.TemplateRepository.Get(ctx, "id = ? AND account_id = ?", req.TemplateID, req.AccountID)
+		if err != nil {
+			return err
+		}
+		return &PreconditionFailedError{Current: current}
+	}
+	a.invalidate(ctx, existing.ID)
+	return nil
+}
+
+func (a *App) latestVersion(ctx context.Context, template *entity.Template) (*entity.Template, error) {
+	latest, err := a.db.TemplateRepository.FindManyWithOptions(ctx,
+		util.AndQuery(util.Eq("account_id", template.AccountID), util.Eq("slug", template.Slug)),
+		repository.WithOrderBy("version", "desc"),
+		repository.WithPagination(1, 1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(latest) == 0 {
+		return template, nil
+	}
+	return &latest[0], nil
 }
 
 func (a *App) Delete(ctx context.Context, req shared.DeleteTemplateRequest) error {
-	if err := a.db.TemplateRepository.Delete(ctx, &entity.Template{
-		ID:        req.TemplateID,
-		AccountID: req.AccountID,
-		Version:   req.Version,
+	// soft delete, the template stays in the trash until it is restored or purged
+	if err := a.db.TemplateRepository.DeleteByFieldName(ctx, util.Query{
+		Query: "id = ? AND account_id = ? AND version = ?",
+		Args:  []any{req.TemplateID, req.AccountID, req.Version},
 	}); err != nil {
-		a.logger.ErrorContext(ctx, "failed to create account %+v", err)
+		a.logger.ErrorContext(ctx, "failed to delete template", "err", err)
 		return err
 	}
+	a.invalidate(ctx, req.TemplateID)
+	a.notifier.Publish(ctx, req.AccountID, entity.WebhookEventTemplateDeleted, map[string]any{
+		"id":      req.TemplateID,
+		"version": req.Version,
+	})
 	return nil
 }
 
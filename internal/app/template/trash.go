@@ -0,0 +1,158 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+	"template-manager/pkg/uploader"
+)
+
+const defaultTrashRetentionDays = 30
+
+var ErrNotInTrash = errors.New("template is not in the trash")
+
+// ListTrash returns the soft deleted templates of an account, most recently deleted first
+func (a *App) ListTrash(ctx context.Context, req shared.ListTemplatesRequest) (*util.PaginationT[[]entity.Template], error) {
+	return a.db.TemplateRepository.FindWithPagination(
+		ctx,
+		util.Query{Query: "account_id = ? AND deleted_at IS NOT NULL", Args: []any{req.AccountID}},
+		repository.WithDeleted(),
+		repository.WithPagination(req.Page, req.PageSize),
+		repository.WithOrderBy("deleted_at", "desc"),
+	)
+}
+
+func (a *App) Restore(ctx context.Context, req shared.GetTemplateRequest) error {
+	restored, err := a.db.TemplateRepository.Restore(ctx, trashed(req.AccountID, req.TemplateID))
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to restore template", "template_id", req.TemplateID, "err", err)
+		return err
+	}
+	if restored == 0 {
+		return ErrNotInTrash
+	}
+	return nil
+}
+
+// Purge permanently deletes a template from the trash along with its content
+func (a *App) Purge(ctx context.Context, req shared.GetTemplateRequest) error {
+	purged, err := a.purge(ctx, trashed(req.AccountID, req.TemplateID))
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to purge template", "template_id", req.TemplateID, "err", err)
+		return err
+	}
+	if purged == 0 {
+		return ErrNotInTrash
+	}
+	return nil
+}
+
+// RunTrashPurger permanently deletes templates that have been in the trash for
+// longer than TEMPLATE_TRASH_RETENTION_DAYS (30 by default), every interval until ctx is done
+func (a *App) RunTrashPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.purgeExpiredTrash(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) purgeExpiredTrash(ctx context.Context) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -a.trashRetentionDays())
+	purged, err := a.purge(ctx, util.Query{
+		Query: "deleted_at IS NOT NULL AND deleted_at < ?",
+		Args:  []any{cutoff},
+	})
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to purge expired templates", "err", err)
+		return
+	}
+	if purged > 0 {
+		a.logger.InfoContext(ctx, "purged expired templates from the trash", "count", purged)
+	}
+}
+
+// purge permanently deletes the trashed templates matching query, then the
+// content they stored. Content still used by another version is kept.
+func (a *App) purge(ctx context.Context, query util.Query) (int64, error) {
+	templates, err := a.db.TemplateRepository.FindManyWithOptions(ctx, query, repository.WithDeleted())
+	if err != nil {
+		return 0, err
+	}
+	if len(templates) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, 0, len(templates))
+	for _, template := range templates {
+		ids = append(ids, template.ID)
+	}
+	// only the templates found, the content of anything trashed meanwhile isn't known
+	purged, err := a.db.TemplateRepository.Purge(ctx, util.Query{
+		Query: "id IN (?) AND deleted_at IS NOT NULL",
+		Args:  []any{ids},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := make(map[string]bool, len(templates))
+	for _, template := range templates {
+		if template.Location == "" || deleted[template.Location] {
+			continue
+		}
+		deleted[template.Location] = true
+		a.deleteContent(ctx, template.Location)
+	}
+	return purged, nil
+}
+
+// deleteContent removes the content at location from the storage unless a
+// template still points to it, versions can be saved with the same upload
+func (a *App) deleteContent(ctx context.Context, location string) {
+	filename, err := a.storage.FilenameFromLocation(location)
+	if err != nil {
+		// saved before uploads were verified, it isn't ours to delete
+		return
+	}
+	remaining, err := a.db.TemplateRepository.FindManyWithOptions(ctx,
+		util.Eq("location", location),
+		repository.WithDeleted(),
+		repository.WithPagination(1, 1),
+	)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to check the content is unused, keeping it", "location", location, "err", err)
+		return
+	}
+	if len(remaining) > 0 {
+		return
+	}
+	if err := a.storage.DeleteFile(ctx, filename); err != nil && !errors.Is(err, uploader.ErrNotFound) {
+		a.logger.ErrorContext(ctx, "failed to delete purged content", "location", location, "err", err)
+	}
+}
+
+func (a *App) trashRetentionDays() int {
+	days, err := strconv.Atoi(a.config.GetString("TEMPLATE_TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return defaultTrashRetentionDays
+	}
+	return days
+}
+
+func trashed(accountID, templateID string) util.Query {
+	return util.Query{
+		Query: "id = ? AND account_id = ? AND deleted_at IS NOT NULL",
+		Args:  []any{templateID, accountID},
+	}
+}
@@ -0,0 +1,123 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+	"template-manager/pkg/uploader"
+	"template-manager/pkg/uploader/local"
+)
+
+func newTrashApp(t *testing.T) (*App, *fakeTemplates, *local.Local) {
+	storage, err := local.New(t.TempDir(), "http://localhost:8080", []byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := &fakeTemplates{t: t}
+	app := &App{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		db:      repository.Container{TemplateRepository: templates},
+		storage: storage,
+	}
+	return app, templates, storage
+}
+
+func upload(t *testing.T, storage *local.Local, filename string) string {
+	if _, err := storage.UploadFile(context.Background(), filename, bytes.NewBufferString("<p>hi</p>")); err != nil {
+		t.Fatal(err)
+	}
+	return storage.GetPublicURl(filename)
+}
+
+func trashedAt(at time.Time) gorm.DeletedAt {
+	return gorm.DeletedAt{Time: at, Valid: true}
+}
+
+func TestPurgeDeletesContent(t *testing.T) {
+	app, templates, storage := newTrashApp(t)
+	ctx := context.Background()
+	location := upload(t, storage, "template/test/acc/welcome-1")
+	templates.rows = []entity.Template{
+		{ID: "t1", AccountID: "acc", Location: location, DeletedAt: trashedAt(time.Now())},
+	}
+
+	if err := app.Purge(ctx, shared.GetTemplateRequest{AccountID: "acc", TemplateID: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(templates.rows) != 0 {
+		t.Fatalf("template was not purged: %+v", templates.rows)
+	}
+	if _, err := storage.GetFileDetails(ctx, "template/test/acc/welcome-1"); !errors.Is(err, uploader.ErrNotFound) {
+		t.Fatalf("content is still stored, GetFileDetails error = %v", err)
+	}
+}
+
+func TestPurgeKeepsSharedContent(t *testing.T) {
+	app, templates, storage := newTrashApp(t)
+	ctx := context.Background()
+	location := upload(t, storage, "template/test/acc/welcome-1")
+	templates.rows = []entity.Template{
+		{ID: "t1", AccountID: "acc", Location: location, DeletedAt: trashedAt(time.Now())},
+		{ID: "t2", AccountID: "acc", Location: location},
+	}
+
+	if err := app.Purge(ctx, shared.GetTemplateRequest{AccountID: "acc", TemplateID: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.GetFileDetails(ctx, "template/test/acc/welcome-1"); err != nil {
+		t.Fatalf("content used by another version was deleted: %v", err)
+	}
+}
+
+func TestPurgeNotInTrash(t *testing.T) {
+	app, templates, storage := newTrashApp(t)
+	ctx := context.Background()
+	location := upload(t, storage, "template/test/acc/welcome-1")
+	templates.rows = []entity.Template{{ID: "t1", AccountID: "acc", Location: location}}
+
+	if err := app.Purge(ctx, shared.GetTemplateRequest{AccountID: "acc", TemplateID: "t1"}); !errors.Is(err, ErrNotInTrash) {
+		t.Fatalf("Purge error = %v, want %v", err, ErrNotInTrash)
+	}
+	if _, err := storage.GetFileDetails(ctx, "template/test/acc/welcome-1"); err != nil {
+		t.Fatalf("content of a live template was deleted: %v", err)
+	}
+}
+
+func TestPurgeExpiredTrashDeletesContent(t *testing.T) {
+	app, templates, storage := newTrashApp(t)
+	ctx := context.Background()
+	expired := upload(t, storage, "template/test/acc/old-1")
+	recent := upload(t, storage, "template/test/acc/new-1")
+	templates.rows = []entity.Template{
+		{ID: "old", AccountID: "acc", Location: expired, DeletedAt: trashedAt(time.Now().AddDate(0, 0, -defaultTrashRetentionDays-1))},
+		{ID: "new", AccountID: "acc", Location: recent, DeletedAt: trashedAt(time.Now())},
+	}
+
+	purged, err := app.purge(ctx, util.Query{
+		Query: "deleted_at IS NOT NULL AND deleted_at < ?",
+		Args:  []any{time.Now().AddDate(0, 0, -defaultTrashRetentionDays)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 || len(templates.rows) != 1 || templates.rows[0].ID != "new" {
+		t.Fatalf("purged %d, left %+v", purged, templates.rows)
+	}
+	if _, err := storage.GetFileDetails(ctx, "template/test/acc/old-1"); !errors.Is(err, uploader.ErrNotFound) {
+		t.Fatalf("expired content is still stored, GetFileDetails error = %v", err)
+	}
+	if _, err := storage.GetFileDetails(ctx, "template/test/acc/new-1"); err != nil {
+		t.Fatalf("content still in the trash was deleted: %v", err)
+	}
+}
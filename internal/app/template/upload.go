@@ -0,0 +1,104 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"template-manager/internal/shared"
+	"template-manager/pkg/uploader"
+)
+
+var (
+	ErrUploadNotFound        = errors.New("nothing was uploaded to this location, upload the content with the upload url first")
+	ErrContentTypeNotAllowed = errors.New("content type must be text/html or text/plain")
+	ErrContentTypeMismatch   = errors.New("uploaded content is not text")
+)
+
+var allowedContentTypes = map[string]bool{
+	"text/html":  true,
+	"text/plain": true,
+}
+
+// uploadedContent is what was found at a location when a version was saved
+type uploadedContent struct {
+	Hash string
+	Size int64
+}
+
+// folder is where the uploads of an account are stored, e.g template/production/<account_id>
+func (a *App) folder(accountID string) string {
+	return fmt.Sprintf("template/%s/%s", strings.ToLower(a.env), accountID)
+}
+
+// uploadFilename returns a new file in the folder of the account, every upload
+// gets its own file so older versions keep their content
+func (a *App) uploadFilename(accountID, name string) string {
+	return fmt.Sprintf("%s/%s-%s", a.folder(accountID), shared.Slugify(name), uuid.NewString()[:8])
+}
+
+// filename returns the name of the file location points to in the storage,
+// locations of other accounts and outside of the storage are rejected
+func (a *App) filename(accountID, location string) (string, error) {
+	filename, err := a.storage.FilenameFromLocation(location)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(filename, a.folder(accountID)+"/") {
+		return "", uploader.ErrOutsideFolder
+	}
+	return filename, nil
+}
+
+// inspectUpload makes sure location points to an object uploaded to the
+// account's folder, that it is within the size limit and that it is text. The
+// declared content type is checked as well when it is not empty.
+func (a *App) inspectUpload(ctx context.Context, accountID, location, declared string) (*uploadedContent, error) {
+	if declared != "" && !allowedContentType(declared) {
+		return nil, ErrContentTypeNotAllowed
+	}
+
+	filename, err := a.filename(accountID, location)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := a.storage.GetFileDetails(ctx, filename)
+	if err != nil {
+		a.logger.WarnContext(ctx, "uploaded file not found", "location", location, "err", err)
+		return nil, ErrUploadNotFound
+	}
+	if details.Size > maxContentSize {
+		return nil, ErrContentTooLarge
+	}
+	if details.ContentType != "" && !allowedContentType(details.ContentType) {
+		return nil, ErrContentTypeNotAllowed
+	}
+
+	content, err := a.fetchContent(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template content: %w", err)
+	}
+	// the stored content type is whatever the uploader said, check what the bytes actually are
+	if !allowedContentType(http.DetectContentType(content)) {
+		return nil, ErrContentTypeMismatch
+	}
+
+	return &uploadedContent{
+		Hash: contentHash(content),
+		Size: int64(len(content)),
+	}, nil
+}
+
+func allowedContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return allowedContentTypes[mediaType]
+}
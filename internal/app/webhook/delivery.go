@@ -0,0 +1,272 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+const (
+	maxAttempts     = 8
+	baseBackoff     = 30 * time.Second
+	maxBackoff      = 6 * time.Hour
+	maxResponseBody = 4 << 10 // 4KB read so the connection can be reused, it isn't kept
+	dispatchBatch   = 100
+)
+
+// Headers sent with every delivery. The signature is the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event is the body of a delivery
+type Event struct {
+	ID        string              `json:"id"`
+	Type      entity.WebhookEvent `json:"type"`
+	AccountID string              `json:"account_id"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      any                 `json:"data"`
+}
+
+// Sign returns the signature of a delivery body, receivers compute the same
+// value from the timestamp header and compare it to the signature header
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues an event for every active webhook of the account that
+// subscribed to it and makes the first delivery attempt in the background.
+// Failures are logged, they never fail the operation that raised the event.
+func (a *App) Publish(ctx context.Context, accountID string, event entity.WebhookEvent, data any) {
+	webhooks, err := a.db.WebhookRepository.Find(ctx, "account_id = ? AND active = ?", accountID, true)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to find webhooks", "account_id", accountID, "err", err)
+		return
+	}
+
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.Events.Has(event) {
+			continue
+		}
+		if payload == nil {
+			payload, err = json.Marshal(Event{
+				ID:        uuid.New().String(),
+				Type:      event,
+				AccountID: accountID,
+				CreatedAt: time.Now().UTC(),
+				Data:      data,
+			})
+			if err != nil {
+				a.logger.ErrorContext(ctx, "failed to encode webhook event", "event", event, "err", err)
+				return
+			}
+		}
+
+		now := time.Now().UTC()
+		delivery := entity.WebhookDelivery{
+			AccountID:     accountID,
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        entity.DeliveryStatusPending,
+			NextAttemptAt: &now,
+		}
+		if err := a.db.DeliveryRepository.Create(ctx, &delivery); err != nil {
+			a.logger.ErrorContext(ctx, "failed to queue webhook delivery", "webhook_id", webhook.ID, "event", event, "err", err)
+			continue
+		}
+		webhook := webhook
+		// the request context ends before the endpoint answers
+		go a.attempt(context.Background(), &webhook, delivery)
+	}
+}
+
+func (a *App) ListDeliveries(ctx context.Context, req shared.ListDeliveriesRequest) (*util.PaginationT[[]entity.WebhookDelivery], error) {
+	conditions := []util.Query{util.Eq("account_id", req.AccountID), util.Eq("webhook_id", req.WebhookID)}
+	if req.Status != "" {
+		conditions = append(conditions, util.Eq("status", req.Status))
+	}
+	return a.db.DeliveryRepository.FindWithPagination(ctx,
+		util.AndQuery(conditions...),
+		repository.WithOrderBy("created_at", "desc"),
+		repository.WithPagination(req.Page, req.PageSize),
+	)
+}
+
+// Redeliver sends the payload of a previous delivery again as a new delivery
+// and returns it once the first attempt finished
+func (a *App) Redeliver(ctx context.Context, req shared.RedeliverRequest) (*entity.WebhookDelivery, error) {
+	original, err := a.db.DeliveryRepository.Get(ctx, "id = ? AND account_id = ?", req.DeliveryID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := a.db.WebhookRepository.Get(ctx, "id = ? AND account_id = ?", original.WebhookID, req.AccountID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	now := time.Now().UTC()
+	delivery := entity.WebhookDelivery{
+		AccountID:     original.AccountID,
+		WebhookID:     original.WebhookID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        entity.DeliveryStatusPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  original.ID,
+	}
+	if err := a.db.DeliveryRepository.Create(ctx, &delivery); err != nil {
+		return nil, err
+	}
+	a.attempt(ctx, webhook, delivery)
+	return a.db.DeliveryRepository.Get(ctx, "id = ?", delivery.ID)
+}
+
+// RunDispatcher retries due deliveries every interval until ctx is done
+func (a *App) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.retryDueDeliveries(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) retryDueDeliveries(ctx context.Context) {
+	due, err := a.db.DeliveryRepository.FindManyWithOptions(ctx,
+		util.Query{Query: "status = ? AND next_attempt_at <= ?", Args: []any{entity.DeliveryStatusPending, time.Now().UTC()}},
+		repository.WithOrderBy("next_attempt_at", "asc"),
+		repository.WithPagination(1, dispatchBatch),
+	)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to find due webhook deliveries", "err", err)
+		return
+	}
+
+	for _, delivery := range due {
+		webhook, err := a.db.WebhookRepository.Get(ctx, "id = ?", delivery.WebhookID)
+		if err != nil || !webhook.Active {
+			a.finish(ctx, delivery, map[string]any{
+				"status":          entity.DeliveryStatusFailed,
+				"next_attempt_at": nil,
+				"error":           "webhook was deleted or disabled",
+			})
+			continue
+		}
+		a.attempt(ctx, webhook, delivery)
+	}
+}
+
+// attempt makes one delivery attempt. The attempt is claimed by pushing
+// next_attempt_at back first, so only one instance sends it and a crash in
+// between leaves it to be retried after the backoff.
+func (a *App) attempt(ctx context.Context, webhook *entity.Webhook, delivery entity.WebhookDelivery) {
+	attempts := delivery.Attempts + 1
+	claimed, err := a.db.DeliveryRepository.UpdateWhere(ctx,
+		util.Query{Query: "id = ? AND status = ? AND attempts = ?", Args: []any{delivery.ID, entity.DeliveryStatusPending, delivery.Attempts}},
+		map[string]any{"attempts": attempts, "next_attempt_at": time.Now().UTC().Add(backoff(attempts)), "updated_at": time.Now().UTC()},
+	)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to claim webhook delivery", "delivery_id", delivery.ID, "err", err)
+		return
+	}
+	if claimed == 0 {
+		return
+	}
+
+	status, err := a.post(ctx, webhook, delivery)
+	fields := map[string]any{"response_status": status, "error": ""}
+	switch {
+	case err == nil:
+		now := time.Now().UTC()
+		fields["status"] = entity.DeliveryStatusSucceeded
+		fields["delivered_at"] = &now
+		fields["next_attempt_at"] = nil
+	case attempts >= maxAttempts:
+		fields["status"] = entity.DeliveryStatusFailed
+		fields["next_attempt_at"] = nil
+		fields["error"] = err.Error()
+	default:
+		// stays pending, next_attempt_at was already moved when claiming
+		fields["error"] = err.Error()
+	}
+	a.finish(ctx, delivery, fields)
+}
+
+// post sends a delivery and returns the status the endpoint responded with.
+// The response body isn't returned: the delivery log would otherwise let an
+// account read whatever the url points to.
+func (a *App) post(ctx context.Context, webhook *entity.Webhook, delivery entity.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "template-manager-webhooks/1.0")
+	request.Header.Set(HeaderEvent, string(delivery.Event))
+	request.Header.Set(HeaderDelivery, delivery.ID)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := a.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode <= 399:
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s, redirects are not followed", resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (a *App) finish(ctx context.Context, delivery entity.WebhookDelivery, fields map[string]any) {
+	fields["updated_at"] = time.Now().UTC()
+	if _, err := a.db.DeliveryRepository.UpdateWhere(ctx, util.Eq("id", delivery.ID), fields); err != nil {
+		a.logger.ErrorContext(ctx, "failed to update webhook delivery", "delivery_id", delivery.ID, "err", err)
+	}
+}
+
+// backoff doubles the wait after every attempt, starting at 30 seconds
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff << (attempts - 1)
+	if wait <= 0 || wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/netguard"
+	"template-manager/pkg/repository"
+)
+
+func newTestApp() *App {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), repository.Container{})
+}
+
+func TestCreateRejectsInternalURLs(t *testing.T) {
+	app := newTestApp()
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/hook",
+		"http://[::1]/hook",
+	} {
+		_, err := app.Create(context.Background(), shared.CreateWebhookRequest{AccountID: "acc", URL: url})
+		if !errors.Is(err, netguard.ErrForbiddenAddress) {
+			t.Errorf("Create(%s) error = %v, want %v", url, err, netguard.ErrForbiddenAddress)
+		}
+	}
+}
+
+func TestPostRefusesInternalEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the internal endpoint")
+	}))
+	defer server.Close()
+
+	_, err := newTestApp().post(context.Background(), &entity.Webhook{URL: server.URL}, entity.WebhookDelivery{Payload: "{}"})
+	if !errors.Is(err, netguard.ErrForbiddenAddress) {
+		t.Fatalf("post error = %v, want %v", err, netguard.ErrForbiddenAddress)
+	}
+}
+
+func TestPostDoesNotFollowRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect was followed")
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	app := newTestApp()
+	// the test servers listen on loopback, only the redirect policy is tested here
+	app.client.Transport = http.DefaultTransport
+	status, err := app.post(context.Background(), &entity.Webhook{URL: server.URL}, entity.WebhookDelivery{Payload: "{}"})
+	if err == nil || status != http.StatusTemporaryRedirect {
+		t.Fatalf("post = %d, %v, want %d and an error", status, err, http.StatusTemporaryRedirect)
+	}
+}
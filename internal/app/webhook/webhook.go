@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/netguard"
+	"template-manager/pkg/repository"
+	"template-manager/pkg/repository/util"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type App struct {
+	logger *slog.Logger
+	db     repository.Container
+	client *http.Client
+}
+
+func New(logger *slog.Logger, db repository.Container) *App {
+	return &App{
+		logger: logger,
+		db:     db,
+		// endpoints are chosen by the accounts, they must not reach the internal network
+		client: netguard.NewClient(10 * time.Second),
+	}
+}
+
+// Create registers a webhook, the returned secret is used to verify deliveries
+// and is not shown again
+func (a *App) Create(ctx context.Context, req shared.CreateWebhookRequest) (*entity.Webhook, error) {
+	if err := netguard.CheckURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook := entity.Webhook{
+		AccountID:   req.AccountID,
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Secret:      secret,
+		Active:      true,
+	}
+	if err := a.db.WebhookRepository.Create(ctx, &webhook); err != nil {
+		a.logger.ErrorContext(ctx, "failed to create webhook", "err", err)
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (a *App) List(ctx context.Context, accountID string) ([]entity.Webhook, error) {
+	webhooks, err := a.db.WebhookRepository.Find(ctx, "account_id = ?", accountID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (a *App) Get(ctx context.Context, req shared.WebhookRequest) (*entity.Webhook, error) {
+	webhook, err := a.db.WebhookRepository.Get(ctx, "id = ? AND account_id = ?", req.WebhookID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (a *App) Update(ctx context.Context, req shared.UpdateWebhookRequest) (*entity.Webhook, error) {
+	fields := map[string]any{"updated_at": time.Now().UTC()}
+	if req.URL != "" {
+		if err := netguard.CheckURL(ctx, req.URL); err != nil {
+			return nil, err
+		}
+		fields["url"] = req.URL
+	}
+	if req.Description != nil {
+		fields["description"] = *req.Description
+	}
+	if req.Events != nil {
+		fields["events"] = entity.WebhookEventSet(req.Events)
+	}
+	if req.Active != nil {
+		fields["active"] = *req.Active
+	}
+
+	updated, err := a.db.WebhookRepository.UpdateWhere(ctx,
+		util.Query{Query: "id = ? AND account_id = ?", Args: []any{req.WebhookID, req.AccountID}},
+		fields,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if updated == 0 {
+		return nil, ErrWebhookNotFound
+	}
+	return a.Get(ctx, shared.WebhookRequest{AccountID: req.AccountID, WebhookID: req.WebhookID})
+}
+
+func (a *App) Delete(ctx context.Context, req shared.WebhookRequest) error {
+	webhook, err := a.db.WebhookRepository.Get(ctx, "id = ? AND account_id = ?", req.WebhookID, req.AccountID)
+	if err != nil {
+		return err
+	}
+	return a.db.WebhookRepository.Delete(ctx, webhook)
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -1,10 +1,11 @@
 package entity
 
 import (
+	"crypto/rand"
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"math/rand"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -37,8 +38,16 @@ func (a *Account) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// PasswordSaltLength is the length of the salts of SetPassword, bcrypt only
+// hashes the first 72 bytes of the salt and password
+const PasswordSaltLength = 16
+
 func (a *Account) SetPassword(password string) (err error) {
-	a.HashSalt = time.Now().Format(time.RFC3339Nano)
+	salt := make([]byte, PasswordSaltLength*3/4)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	a.HashSalt = base64.RawURLEncoding.EncodeToString(salt)
 	saltedPassword := a.HashSalt + password
 	hashedPasswordByte, err := bycrypt.GenerateFromPassword([]byte(saltedPassword), bycrypt.DefaultCost)
 	if err != nil {
@@ -48,21 +57,16 @@ func (a *Account) SetPassword(password string) (err error) {
 	return nil
 }
 
+// ComparePassword reports whether password is the password of the account,
+// never for accounts that didn't choose one yet
 func (c Account) ComparePassword(password string) bool {
+	if c.HashedPassword == "" {
+		return false
+	}
 	saltedPassword := c.HashSalt + password
 	return bycrypt.CompareHashAndPassword([]byte(c.HashedPassword), []byte(saltedPassword)) == nil
 }
 
-func GenerateRandomPassword() string {
-	// generate random password of length 8
-	long := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, 8)
-	for i := range b {
-		b[i] = long[rand.Int63()%int64(len(long))]
-	}
-	return string(b)
-}
-
 type Device struct {
 	IP             string `json:"ip" gorm:"column:ip"`
 	UserAgent      string `json:"user_agent" gorm:"column:user_agent"`
@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TokenPurpose string
+
+const (
+	TokenPurposeVerifyEmail   TokenPurpose = "verify_email"
+	TokenPurposeResetPassword TokenPurpose = "reset_password"
+)
+
+const accountTokenBytes = 32
+
+// AccountToken is a single-use secret emailed to the owner of an account to
+// verify its email address or reset its password. Only its hash is stored, like the keys'.
+type AccountToken struct {
+	ID        string       `json:"id" gorm:"primaryKey;column:id"`
+	AccountID string       `json:"account_id" gorm:"column:account_id;not null;index"`
+	Purpose   TokenPurpose `json:"purpose" gorm:"column:purpose;not null"`
+	TokenHash string       `json:"-" gorm:"column:token_hash;type:text;not null;uniqueIndex"`
+	ExpiresAt time.Time    `json:"expires_at" gorm:"column:expires_at;type:timestamptz;not null"`
+	UsedAt    *time.Time   `json:"used_at" gorm:"column:used_at;type:timestamptz"`
+	CreatedAt time.Time    `json:"created_at" gorm:"column:created_at;type:timestamptz"`
+}
+
+func (AccountToken) TableName() string {
+	return "account_tokens"
+}
+
+func (t *AccountToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// NewAccountToken returns a token valid for ttl along with its secret, which
+// is only available here
+func NewAccountToken(accountID string, purpose TokenPurpose, ttl time.Duration) (*AccountToken, string, error) {
+	b := make([]byte, accountTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := hex.EncodeToString(b)
+	return &AccountToken{
+		AccountID: accountID,
+		Purpose:   purpose,
+		TokenHash: HashKeySecret(secret),
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}, secret, nil
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EventKind string
+
+const (
+	EventKindRender EventKind = "render"
+	EventKindSend   EventKind = "send"
+)
+
+type EventOutcome string
+
+const (
+	EventOutcomeSuccess EventOutcome = "success"
+	EventOutcomeFailure EventOutcome = "failure"
+)
+
+// TemplateEvent is recorded for every render and send of a template
+type TemplateEvent struct {
+	ID         string       `json:"id" gorm:"primaryKey;column:id"`
+	AccountID  string       `json:"account_id" gorm:"column:account_id;not null;index:idx_template_events_account_created"`
+	TemplateID string       `json:"template_id" gorm:"column:template_id;not null"`
+	Slug       string       `json:"slug" gorm:"column:slug;not null;index"`
+	Version    uint64       `json:"version" gorm:"column:version;not null"`
+	Kind       EventKind    `json:"kind" gorm:"column:kind;not null"`
+	Channel    PlatformType `json:"channel" gorm:"column:channel"` // empty for renders
+	LatencyMs  float64      `json:"latency_ms" gorm:"column:latency_ms;not null"`
+	Outcome    EventOutcome `json:"outcome" gorm:"column:outcome;not null"`
+	Error      string       `json:"error,omitempty" gorm:"column:error;type:text"`
+	CreatedAt  time.Time    `json:"created_at" gorm:"column:created_at;type:timestamptz;index:idx_template_events_account_created"`
+}
+
+func (TemplateEvent) TableName() string {
+	return "template_events"
+}
+
+func (e *TemplateEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
@@ -1,19 +1,59 @@
 package entity
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// KeyScope is a permission of an API key, the routes of the API require one
+// each and keys can only call the routes of their scopes
+type KeyScope string
+
+const (
+	KeyScopeTemplatesRead     KeyScope = "templates:read"
+	KeyScopeTemplatesWrite    KeyScope = "templates:write"
+	KeyScopeTemplatesApprove  KeyScope = "templates:approve" // approve, reject, publish and schedule versions
+	KeyScopeRender            KeyScope = "render"
+	KeyScopeSend              KeyScope = "send"
+	KeyScopeCredentialsManage KeyScope = "credentials:manage"
+)
+
+var KeyScopes = []KeyScope{
+	KeyScopeTemplatesRead,
+	KeyScopeTemplatesWrite,
+	KeyScopeTemplatesApprove,
+	KeyScopeRender,
+	KeyScopeSend,
+	KeyScopeCredentialsManage,
+}
+
 type Key struct {
 	ID        string `json:"id" gorm:"primaryKey;column:id"`
 	AccountID string `json:"account_id" gorm:"column:account_id;not null"`
 	Name      string `json:"name" gorm:"column:name;not null"`
 
-	Secret    string    `json:"secret" gorm:"column:secret;type:text;not null"`
-	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamptz"`
+	Prefix     string      `json:"prefix" gorm:"column:prefix;not null"`                       // start of the secret, identifies the key in listings
+	SecretHash string      `json:"-" gorm:"column:secret_hash;type:text;not null;uniqueIndex"` // see HashKeySecret
+	Secret     string      `json:"secret,omitempty" gorm:"-"`                                  // only set when the key is created
+	Scopes     KeyScopeSet `json:"scopes" gorm:"column:scopes;type:jsonb;not null;default:'[]'"`
+	Test       bool        `json:"test" gorm:"column:test;not null;default:false"`       // sends are captured instead of delivered, see CapturedMessage
+	ExpiresAt  *time.Time  `json:"expires_at" gorm:"column:expires_at;type:timestamptz"` // never expires when nil
+	LastUsedAt *time.Time  `json:"last_used_at" gorm:"column:last_used_at;type:timestamptz"`
+	LastUsedIP string      `json:"last_used_ip" gorm:"column:last_used_ip"`
+	CreatedAt  time.Time   `json:"created_at" gorm:"column:created_at;type:timestamptz"`
+
+	// the secret replaced by the last rotation stays valid until PreviousExpiresAt
+	PreviousSecretHash string     `json:"-" gorm:"column:previous_secret_hash;type:text;index"`
+	PreviousExpiresAt  *time.Time `json:"previous_expires_at,omitempty" gorm:"column:previous_expires_at;type:timestamptz"`
+	RotatedAt          *time.Time `json:"rotated_at" gorm:"column:rotated_at;type:timestamptz"`
 
 	Account *Account `json:"-" gorm:"foreignKey:AccountID"`
 }
@@ -32,8 +72,93 @@ func (k *Key) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// I'm not comfortable with this method of generating keys
+const (
+	KeyPrefix      = "tm_live_"
+	TestKeyPrefix  = "tm_test_"
+	keyPrefixChars = 8 // random characters of the secret shown in Prefix
+	keySecretBytes = 32
+)
+
+// GenerateKey sets a new random secret, only its hash is stored so the secret
+// can't be shown again once the key is created
 func (k *Key) GenerateKey() error {
-	k.Secret = "secret-" + time.Now().Format("20060102150405MonMSTJan") + "-" + k.AccountID
+	b := make([]byte, keySecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	prefix := KeyPrefix
+	if k.Test {
+		prefix = TestKeyPrefix
+	}
+	k.Secret = prefix + hex.EncodeToString(b)
+	k.Prefix = k.Secret[:len(prefix)+keyPrefixChars]
+	k.SecretHash = HashKeySecret(k.Secret)
 	return nil
 }
+
+// Rotate replaces the secret of the key, the old one remains valid for the
+// grace period so clients can switch over
+func (k *Key) Rotate(grace time.Duration) error {
+	now := time.Now().UTC()
+	k.PreviousSecretHash, k.PreviousExpiresAt = "", nil
+	if grace > 0 {
+		graceEnd := now.Add(grace)
+		k.PreviousSecretHash, k.PreviousExpiresAt = k.SecretHash, &graceEnd
+	}
+	k.RotatedAt = &now
+	return k.GenerateKey()
+}
+
+// Expired reports whether the key can no longer be used
+func (k Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// HashKeySecret is what keys and account tokens are stored and looked up by.
+// Secrets are random so a plain sha256 is enough, unlike passwords.
+func HashKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type KeyScopeSet []KeyScope
+
+func (s KeyScopeSet) Has(scope KeyScope) bool {
+	for _, sc := range s {
+		if sc == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrDraftScope is returned when a key that can't read templates renders or
+// sends a version that isn't published
+var ErrDraftScope = errors.New("rendering or sending drafts needs the templates:read or templates:write scope")
+
+// ReadsDrafts reports whether keys of the scopes may render and send versions
+// that aren't published, only keys that can read the templates may
+func (s KeyScopeSet) ReadsDrafts() bool {
+	return s.Has(KeyScopeTemplatesRead) || s.Has(KeyScopeTemplatesWrite)
+}
+
+func (s KeyScopeSet) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *KeyScopeSet) Scan(src any) error {
+	if src == nil {
+		return nil
+	}
+	switch srcType := src.(type) {
+	case []byte:
+		return json.Unmarshal(srcType, s)
+	case string:
+		return json.Unmarshal([]byte(srcType), s)
+	default:
+		return errors.New("incompatible type for key scopes")
+	}
+}
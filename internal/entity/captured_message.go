@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CapturedMessage is a message sent with a test API key, it is stored for
+// inspection instead of being delivered by the provider
+type CapturedMessage struct {
+	ID           string    `json:"id" gorm:"primaryKey;column:id"`
+	AccountID    string    `json:"account_id" gorm:"column:account_id;not null;index"`
+	KeyID        string    `json:"key_id" gorm:"column:key_id;not null"`
+	TemplateID   string    `json:"template_id" gorm:"column:template_id;not null"`
+	Version      uint64    `json:"version" gorm:"column:version;not null"`
+	CredentialID string    `json:"credential_id" gorm:"column:credential_id;not null"`
+	Platform     Platform  `json:"platform" gorm:"column:platform;not null"`
+	From         string    `json:"from" gorm:"column:from_address;not null"`
+	To           string    `json:"to" gorm:"column:to_address;not null;index"`
+	Subject      string    `json:"subject" gorm:"column:subject;not null"`
+	HTMLContent  string    `json:"html_content,omitempty" gorm:"column:html_content;type:text"`
+	TextContent  string    `json:"text_content,omitempty" gorm:"column:text_content;type:text"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;type:timestamptz"`
+}
+
+func (CapturedMessage) TableName() string {
+	return "captured_messages"
+}
+
+func (m *CapturedMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
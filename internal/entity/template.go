@@ -1,6 +1,11 @@
 package entity
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,9 +21,15 @@ type Template struct {
 	Version     uint64 `json:"version" gorm:"column:version;not null;default:1"`
 	Location    string `json:"location" gorm:"column:location;not null"` // location of the template [url link]
 	ContentType string `json:"content_type" gorm:"column:content_type;not null"`
+	ContentHash string `json:"content_hash" gorm:"column:content_hash"` // hex encoded sha256 of the content at Location when the version was saved
+	ContentSize int64  `json:"content_size" gorm:"column:content_size"`
 	Vars        Map    `json:"vars" gorm:"column:vars;type:jsonb;not null"` // pre-existing values are treated as default values
 
-	Active bool `json:"active" gorm:"column:active;not null"`
+	Blocks ContentBlocks `json:"blocks" gorm:"column:blocks;type:jsonb;not null;default:'[]'"` // audience specific content, selected at render time
+
+	Active      bool           `json:"active" gorm:"column:active;not null"`
+	Status      TemplateStatus `json:"status" gorm:"column:status;not null;default:'draft'"`
+	PublishedAt *time.Time     `json:"published_at" gorm:"column:published_at;type:timestamptz"`
 
 	CreatedAt time.Time      `json:"created_at" gorm:"column:created_at;type:timestamptz"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"column:updated_at;type:timestamptz"`
@@ -44,6 +55,188 @@ func (t *Template) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// ETag identifies the current state of a template version, it changes on every write
+func (t Template) ETag() string {
+	return fmt.Sprintf(`"%d-%x"`, t.Version, t.UpdatedAt.UnixNano())
+}
+
+// MatchesETag reports whether an If-Match header value matches the template
+func (t Template) MatchesETag(ifMatch string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == t.ETag() {
+			return true
+		}
+	}
+	return false
+}
+
+type TemplateStatus string
+
+// a template version moves draft -> in_review -> approved -> published -> archived,
+// a rejected review sends it back to draft
+const (
+	TemplateStatusDraft     TemplateStatus = "draft"
+	TemplateStatusInReview  TemplateStatus = "in_review"
+	TemplateStatusApproved  TemplateStatus = "approved"
+	TemplateStatusPublished TemplateStatus = "published"
+	TemplateStatusArchived  TemplateStatus = "archived"
+)
+
+var templateTransitions = map[TemplateStatus][]TemplateStatus{
+	TemplateStatusDraft:     {TemplateStatusInReview, TemplateStatusArchived},
+	TemplateStatusInReview:  {TemplateStatusApproved, TemplateStatusDraft},
+	TemplateStatusApproved:  {TemplateStatusPublished, TemplateStatusDraft, TemplateStatusArchived},
+	TemplateStatusPublished: {TemplateStatusArchived},
+	TemplateStatusArchived:  {},
+}
+
+// Transition moves the template to the given status if the lifecycle allows it
+func (t *Template) Transition(to TemplateStatus) error {
+	from := t.Status
+	if from == "" {
+		from = TemplateStatusDraft
+	}
+	// versions that were live before can be restored e.g. when a scheduled publish is reverted
+	if from == TemplateStatusArchived && to == TemplateStatusPublished && t.PublishedAt != nil {
+		now := time.Now().UTC()
+		t.Status, t.PublishedAt = to, &now
+		return nil
+	}
+	for _, allowed := range templateTransitions[from] {
+		if allowed == to {
+			t.Status = to
+			if to == TemplateStatusPublished {
+				now := time.Now().UTC()
+				t.PublishedAt = &now
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("template cannot move from %s to %s", from, to)
+}
+
+type ReviewAction string
+
+const (
+	ReviewActionRequested ReviewAction = "requested"
+	ReviewActionApproved  ReviewAction = "approved"
+	ReviewActionRejected  ReviewAction = "rejected"
+	ReviewActionPublished ReviewAction = "published"
+	ReviewActionArchived  ReviewAction = "archived"
+)
+
+// TemplateReview records every lifecycle change of a template version along
+// with the comment left by the reviewer
+type TemplateReview struct {
+	ID         string         `json:"id" gorm:"primaryKey;column:id"`
+	AccountID  string         `json:"account_id" gorm:"column:account_id;not null"`
+	TemplateID string         `json:"template_id" gorm:"column:template_id;not null;index"`
+	Action     ReviewAction   `json:"action" gorm:"column:action;not null"`
+	From       TemplateStatus `json:"from" gorm:"column:from_status;not null"`
+	To         TemplateStatus `json:"to" gorm:"column:to_status;not null"`
+	Comment    string         `json:"comment" gorm:"column:comment;type:text"`
+	Actor      string         `json:"actor" gorm:"column:actor;not null;default:''"` // see shared.ReviewTemplateRequest.Actor
+	CreatedAt  time.Time      `json:"created_at" gorm:"column:created_at;type:timestamptz"`
+}
+
+func (TemplateReview) TableName() string {
+	return "template_reviews"
+}
+
+func (r *TemplateReview) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusPending    ScheduleStatus = "pending"    // waiting for PublishAt
+	ScheduleStatusPublishing ScheduleStatus = "publishing" // claimed by a scheduler
+	ScheduleStatusActive     ScheduleStatus = "active"     // published, waiting for RevertAt
+	ScheduleStatusReverting  ScheduleStatus = "reverting"  // claimed by a scheduler
+	ScheduleStatusCompleted  ScheduleStatus = "completed"
+	ScheduleStatusCancelled  ScheduleStatus = "cancelled"
+	ScheduleStatusFailed     ScheduleStatus = "failed"
+)
+
+// TemplateSchedule publishes a template version at PublishAt and, when RevertAt
+// is set, restores the version that was published before it at RevertAt. The
+// revert is skipped if another version was published in the meantime.
+type TemplateSchedule struct {
+	ID         string `json:"id" gorm:"primaryKey;column:id"`
+	AccountID  string `json:"account_id" gorm:"column:account_id;not null"`
+	TemplateID string `json:"template_id" gorm:"column:template_id;not null"`
+	Slug       string `json:"slug" gorm:"column:slug;not null"`
+
+	PublishAt          time.Time      `json:"publish_at" gorm:"column:publish_at;type:timestamptz;not null;index"`
+	RevertAt           *time.Time     `json:"revert_at" gorm:"column:revert_at;type:timestamptz;index"`
+	Timezone           string         `json:"timezone" gorm:"column:timezone;not null;default:'UTC'"`
+	PreviousTemplateID string         `json:"previous_template_id" gorm:"column:previous_template_id"` // the version restored at RevertAt
+	Status             ScheduleStatus `json:"status" gorm:"column:status;not null;default:'pending'"`
+	Error              string         `json:"error,omitempty" gorm:"column:error;type:text"`
+	ClaimedAt          *time.Time     `json:"-" gorm:"column:claimed_at;type:timestamptz"` // when a scheduler took the schedule to publish or revert it
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamptz"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamptz"`
+}
+
+func (TemplateSchedule) TableName() string {
+	return "template_schedules"
+}
+
+func (s *TemplateSchedule) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+	if s.UpdatedAt.IsZero() {
+		s.UpdatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// ContentBlock is a named piece of content that is only rendered for recipients
+// whose attributes satisfy Condition. Blocks sharing a name are evaluated in
+// order and the first match wins, so a block with an empty condition placed
+// last acts as the fallback for that name.
+type ContentBlock struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"` // see pkg/expression e.g country == "NG" && plan in ["pro", "team"]
+	Content   string `json:"content"`
+}
+
+type ContentBlocks []ContentBlock
+
+func (b ContentBlocks) Value() (driver.Value, error) {
+	if b == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(b)
+}
+
+func (b *ContentBlocks) Scan(src any) error {
+	if src == nil {
+		return nil
+	}
+	switch srcType := src.(type) {
+	case []byte:
+		return json.Unmarshal(srcType, b)
+	case string:
+		return json.Unmarshal([]byte(srcType), b)
+	default:
+		return errors.New("incompatible type for content blocks")
+	}
+}
+
 type TemplateSync struct {
 	ID       string `json:"id" gorm:"primaryKey;column:id"`
 	Provider string `json:"provider" gorm:"column:provider;not null"`
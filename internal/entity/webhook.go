@@ -0,0 +1,141 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookEvent string
+
+const (
+	WebhookEventTemplateCreated          WebhookEvent = "template.created"
+	WebhookEventTemplateVersionPublished WebhookEvent = "template.version_published"
+	WebhookEventTemplateDeleted          WebhookEvent = "template.deleted"
+	WebhookEventCredentialUpdated        WebhookEvent = "credential.updated"
+	WebhookEventSendFailed               WebhookEvent = "send.failed"
+)
+
+var WebhookEvents = []WebhookEvent{
+	WebhookEventTemplateCreated,
+	WebhookEventTemplateVersionPublished,
+	WebhookEventTemplateDeleted,
+	WebhookEventCredentialUpdated,
+	WebhookEventSendFailed,
+}
+
+// Webhook is an endpoint of an account that is notified about the events it subscribed to
+type Webhook struct {
+	ID          string          `json:"id" gorm:"primaryKey;column:id"`
+	AccountID   string          `json:"account_id" gorm:"column:account_id;not null;index"`
+	URL         string          `json:"url" gorm:"column:url;not null"`
+	Description string          `json:"description" gorm:"column:description"`
+	Events      WebhookEventSet `json:"events" gorm:"column:events;type:jsonb;not null;default:'[]'"`
+	Secret      string          `json:"secret,omitempty" gorm:"column:secret;type:text;not null"` // only returned when the webhook is created
+	Active      bool            `json:"active" gorm:"column:active;not null"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"column:created_at;type:timestamptz"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"column:updated_at;type:timestamptz"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"column:deleted_at;type:timestamptz"`
+
+	Account *Account `json:"-" gorm:"foreignKey:AccountID"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == "" {
+		w.ID = uuid.New().String()
+	}
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now().UTC()
+	}
+	if w.UpdatedAt.IsZero() {
+		w.UpdatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+type WebhookEventSet []WebhookEvent
+
+func (s WebhookEventSet) Has(event WebhookEvent) bool {
+	for _, e := range s {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (s WebhookEventSet) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *WebhookEventSet) Scan(src any) error {
+	if src == nil {
+		return nil
+	}
+	switch srcType := src.(type) {
+	case []byte:
+		return json.Unmarshal(srcType, s)
+	case string:
+		return json.Unmarshal([]byte(srcType), s)
+	default:
+		return errors.New("incompatible type for webhook events")
+	}
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	DeliveryStatusFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent (or to be sent) to a webhook, along with
+// the outcome of the last attempt
+type WebhookDelivery struct {
+	ID             string         `json:"id" gorm:"primaryKey;column:id"`
+	AccountID      string         `json:"account_id" gorm:"column:account_id;not null"`
+	WebhookID      string         `json:"webhook_id" gorm:"column:webhook_id;not null;index"`
+	Event          WebhookEvent   `json:"event" gorm:"column:event;not null"`
+	Payload        string         `json:"payload" gorm:"column:payload;type:jsonb;not null"`
+	Status         DeliveryStatus `json:"status" gorm:"column:status;not null;default:'pending'"`
+	Attempts       int            `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	NextAttemptAt  *time.Time     `json:"next_attempt_at" gorm:"column:next_attempt_at;type:timestamptz;index"`
+	ResponseStatus int            `json:"response_status" gorm:"column:response_status"`
+	Error          string         `json:"error,omitempty" gorm:"column:error;type:text"`
+	RedeliveryOf   string         `json:"redelivery_of,omitempty" gorm:"column:redelivery_of"`
+	DeliveredAt    *time.Time     `json:"delivered_at" gorm:"column:delivered_at;type:timestamptz"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"column:created_at;type:timestamptz"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"column:updated_at;type:timestamptz"`
+
+	Webhook *Webhook `json:"-" gorm:"foreignKey:WebhookID"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	if d.UpdatedAt.IsZero() {
+		d.UpdatedAt = time.Now().UTC()
+	}
+	return nil
+}
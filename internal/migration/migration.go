@@ -0,0 +1,138 @@
+// Package migration brings the database schema up to date at startup.
+//
+// Tables and columns are created by gorm's AutoMigrate from the entities. The
+// data of databases created before a column existed is backfilled by the
+// steps below, which run once each and are recorded in schema_migrations:
+//
+//   - publish_existing_templates: templates had no status, the latest version
+//     of every slug is published and the older versions archived so
+//     rendering keeps working.
+//   - hash_key_secrets: keys stored their secret in plain text, it's replaced
+//     by the secret_hash of entity.HashKeySecret. The old secrets were
+//     predictable and never authenticated, so those keys are retired: they
+//     are expired and new keys have to be created.
+//   - verify_existing_accounts: sending requires a verified email, accounts
+//     that signed up before that are marked verified so they can keep
+//     sending. Only accounts created afterwards have to verify.
+package migration
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockID is the postgres advisory lock held while migrating, so instances
+// starting together don't migrate at the same time
+const lockID = 7260125
+
+type step struct {
+	id  string
+	run func(tx *gorm.DB) error
+}
+
+// steps run in order and are never renamed or removed, add new ones at the end
+var steps = []step{
+	{"publish_existing_templates", publishExistingTemplates},
+	{"hash_key_secrets", hashKeySecrets},
+	{"verify_existing_accounts", verifyExistingAccounts},
+}
+
+type schemaMigration struct {
+	ID        string    `gorm:"primaryKey;column:id"`
+	AppliedAt time.Time `gorm:"column:applied_at;type:timestamptz;not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Run backfills the data of existing databases and then migrates the tables of
+// models. A step of an empty database has nothing to backfill, it's only
+// recorded.
+func Run(db *gorm.DB, models ...any) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockID).Error; err != nil {
+			return err
+		}
+		if err := tx.AutoMigrate(&schemaMigration{}); err != nil {
+			return err
+		}
+		var applied []string
+		if err := tx.Model(&schemaMigration{}).Pluck("id", &applied).Error; err != nil {
+			return err
+		}
+		done := make(map[string]bool, len(applied))
+		for _, id := range applied {
+			done[id] = true
+		}
+		for _, s := range steps {
+			if done[s.id] {
+				continue
+			}
+			if err := s.run(tx); err != nil {
+				return fmt.Errorf("migration %s: %w", s.id, err)
+			}
+			if err := tx.Create(&schemaMigration{ID: s.id, AppliedAt: time.Now().UTC()}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.AutoMigrate(models...)
+	})
+}
+
+func publishExistingTemplates(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable("templates") || tx.Migrator().HasColumn("templates", "status") {
+		return nil
+	}
+	return execAll(tx,
+		`ALTER TABLE templates
+			ADD COLUMN status text NOT NULL DEFAULT 'draft',
+			ADD COLUMN IF NOT EXISTS published_at timestamptz`,
+		`UPDATE templates SET status = 'archived'`,
+		`UPDATE templates SET status = 'published', published_at = created_at
+			WHERE id IN (
+				SELECT DISTINCT ON (account_id, slug) id FROM templates
+				WHERE deleted_at IS NULL
+				ORDER BY account_id, slug, version DESC, created_at DESC
+			)`,
+	)
+}
+
+func hashKeySecrets(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn("keys", "secret") {
+		return nil
+	}
+	return execAll(tx,
+		`ALTER TABLE keys
+			ADD COLUMN IF NOT EXISTS prefix text,
+			ADD COLUMN IF NOT EXISTS secret_hash text,
+			ADD COLUMN IF NOT EXISTS expires_at timestamptz`,
+		// the id keeps the hashes unique and the old secret from matching
+		`UPDATE keys SET
+			prefix = left(secret, 15),
+			secret_hash = encode(sha256(convert_to(id || ':' || secret, 'UTF8')), 'hex'),
+			expires_at = now()`,
+		`ALTER TABLE keys
+			ALTER COLUMN prefix SET NOT NULL,
+			ALTER COLUMN secret_hash SET NOT NULL,
+			DROP COLUMN secret`,
+	)
+}
+
+func verifyExistingAccounts(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable("accounts") {
+		return nil
+	}
+	return tx.Exec(`UPDATE accounts SET verified_at = coalesce(created_at, now()) WHERE verified_at IS NULL`).Error
+}
+
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ type TemplateID string
 
 const (
 	TemplateIDSignupVerification TemplateID = "signup_verification"
+	TemplateIDPasswordReset      TemplateID = "password_reset"
 )
 
 type Provider interface {
@@ -30,6 +30,7 @@ func New(domain, apiKeys, from string) *Mailgun {
 
 var templateIDMap = map[email.TemplateID]string{
 	email.TemplateIDSignupVerification: "signup_verification",
+	email.TemplateIDPasswordReset:      "password_reset",
 }
 
 func (m *Mailgun) Send(ctx context.Context, id email.TemplateID, vars map[string]any) error {
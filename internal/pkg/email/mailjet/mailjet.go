@@ -17,6 +17,7 @@ type (
 		mj         *mailjet.Client
 		publicKey  string
 		privateKey string
+		templates  map[email.TemplateID]int
 	}
 	Option func(m *Mailjet)
 )
@@ -32,6 +33,14 @@ func WithName(name string) Option {
 	}
 }
 
+// WithTemplate sets the mailjet template of an email e.g one created in
+// another mailjet account than the default ones
+func WithTemplate(id email.TemplateID, templateID int) Option {
+	return func(m *Mailjet) {
+		m.templates[id] = templateID
+	}
+}
+
 func New(publicKey, privateKey, from string, opts ...Option) *Mailjet {
 	mailjetClient := mailjet.NewMailjetClient(publicKey, privateKey)
 	client := &Mailjet{
@@ -39,6 +48,10 @@ func New(publicKey, privateKey, from string, opts ...Option) *Mailjet {
 		mj:         mailjetClient,
 		publicKey:  publicKey,
 		privateKey: privateKey,
+		templates:  map[email.TemplateID]int{},
+	}
+	for id, templateID := range templateIDMap {
+		client.templates[id] = templateID
 	}
 	for _, opt := range opts {
 		opt(client)
@@ -54,7 +67,11 @@ func (m *Mailjet) Send(ctx context.Context, id email.TemplateID, vars map[string
 	subject := vars["subject"].(string)
 	vars["company_email"] = m.from
 	vars["logo"] = "https://www.templafy.com/wp-content/uploads/2020/02/corporate-management-templafy.png"
-	return sendTemplateEmail(ctx, m.mj, templateIDMap[id], m.from, to, subject, vars)
+	templateID, ok := m.templates[id]
+	if !ok {
+		return fmt.Errorf("no mailjet template is set for %s", id)
+	}
+	return sendTemplateEmail(ctx, m.mj, templateID, m.from, to, subject, vars)
 }
 
 func validateVars(vars map[string]any) error {
@@ -0,0 +1,53 @@
+package shared
+
+import (
+	"time"
+
+	"template-manager/internal/entity"
+)
+
+// BundleFormatVersion is the version of the bundle layout written by exports,
+// imports reject bundles of other versions
+const BundleFormatVersion = 1
+
+// BundleManifestFile is the name of the manifest inside a bundle, the content
+// of every version is stored next to it under ContentFile
+const BundleManifestFile = "manifest.json"
+
+// BundleManifest describes the templates of a bundle. Templates have no
+// locale here, every language is a template of its own, so the manifest has no
+// locales and a localized template is exported under one key per language.
+type BundleManifest struct {
+	FormatVersion int              `json:"format_version"`
+	ExportedAt    time.Time        `json:"exported_at"`
+	AccountID     string           `json:"account_id"` // the account the bundle was exported from
+	Templates     []BundleTemplate `json:"templates"`
+}
+
+// BundleTemplate is a template and its versions, Key is the slug shared by them
+type BundleTemplate struct {
+	Key      string          `json:"key"`
+	Versions []BundleVersion `json:"versions"`
+}
+
+type BundleVersion struct {
+	Version     uint64                `json:"version"`
+	Name        string                `json:"name"`
+	Status      entity.TemplateStatus `json:"status"`
+	ContentType string                `json:"content_type"`
+	Engine      string                `json:"engine"` // how the content is rendered, html/template or text/template
+	Vars        entity.Map            `json:"vars"`
+	Blocks      entity.ContentBlocks  `json:"blocks"`
+	ContentFile string                `json:"content_file"`
+	ContentHash string                `json:"content_hash"`
+	ContentSize int64                 `json:"content_size"`
+	PublishedAt *time.Time            `json:"published_at,omitempty"`
+}
+
+// ImportBundleResult lists the keys of a bundle by what the import did with them
+type ImportBundleResult struct {
+	Created     []string `json:"created"`
+	Overwritten []string `json:"overwritten"`
+	Versioned   []string `json:"versioned"` // got the latest version of the bundle as a new version
+	Skipped     []string `json:"skipped"`
+}
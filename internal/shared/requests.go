@@ -1,6 +1,8 @@
 package shared
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -8,12 +10,21 @@ import (
 	"github.com/mileusna/useragent"
 
 	"template-manager/internal/entity"
+	"template-manager/pkg/expression"
 )
 
+// SignUpRequest has no password, it is chosen at verification so only the
+// owner of the email can set it
 type SignUpRequest struct {
 	Email string `json:"email"`
 }
 
+func (r SignUpRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Email, validation.Required, is.EmailFormat),
+	)
+}
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -26,6 +37,28 @@ type LogoutRequest struct {
 	Token     string `json:"token"`
 }
 
+type VerifyEmailRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (r VerifyEmailRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Token, validation.Required),
+		validation.Field(&r.Password, validation.Required),
+	)
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+func (r ResendVerificationRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Email, validation.Required, is.EmailFormat),
+	)
+}
+
 type InitiateResetPasswordRequest struct {
 	Email string `json:"email"`
 }
@@ -36,6 +69,14 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
+func (r ResetPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Email, validation.Required, is.EmailFormat),
+		validation.Field(&r.Token, validation.Required),
+		validation.Field(&r.NewPassword, validation.Required),
+	)
+}
+
 type DeleteAccessKeyRequest struct {
 	AccountID   string `json:"account_id"`
 	AccessKeyID string `json:"access_key_id"`
@@ -46,8 +87,74 @@ type ListAccessKeysRequest struct {
 }
 
 type CreateAccessKeyRequest struct {
-	AccountID     string `json:"account_id"`
-	AccessKeyName string `json:"name"`
+	AccountID     string            `json:"account_id"`
+	AccessKeyName string            `json:"name"`
+	Scopes        []entity.KeyScope `json:"scopes"`
+	ExpiresAt     *time.Time        `json:"expires_at"` // optional, the key never expires by default
+	Test          bool              `json:"test"`       // sends made with the key are captured instead of delivered
+}
+
+func (r CreateAccessKeyRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.AccessKeyName, validation.Required),
+		validation.Field(&r.Scopes, validation.Required, validation.Each(validation.In(keyScopes()...))),
+		validation.Field(&r.ExpiresAt, validation.By(func(value any) error {
+			if expiresAt, _ := value.(*time.Time); expiresAt != nil && !expiresAt.After(time.Now()) {
+				return errors.New("must be in the future")
+			}
+			return nil
+		})),
+	)
+}
+
+// MaxKeyGracePeriod is the longest the old secret of a rotated key stays valid
+const MaxKeyGracePeriod = 7 * 24 * time.Hour
+
+type RotateAccessKeyRequest struct {
+	AccountID   string `json:"account_id"`
+	AccessKeyID string `json:"access_key_id"`
+	GracePeriod string `json:"grace_period"` // e.g 24h, how long the old secret stays valid, 0 revokes it immediately
+}
+
+func (r RotateAccessKeyRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.AccessKeyID, validation.Required),
+		validation.Field(&r.GracePeriod, validation.Required, validation.By(func(value any) error {
+			grace, err := time.ParseDuration(value.(string))
+			if err != nil {
+				return errors.New("must be a duration e.g 24h")
+			}
+			if grace < 0 || grace > MaxKeyGracePeriod {
+				return fmt.Errorf("must be between 0 and %s", MaxKeyGracePeriod)
+			}
+			return nil
+		})),
+	)
+}
+
+// DefaultStaleKeyDays is the Days of StaleAccessKeysRequest when none is given
+const DefaultStaleKeyDays = 30
+
+type StaleAccessKeysRequest struct {
+	AccountID string `json:"account_id"`
+	Days      int    `json:"days"` // keys unused for this many days are stale
+}
+
+func (r StaleAccessKeysRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.Days, validation.Required, validation.Min(1)),
+	)
+}
+
+func keyScopes() []any {
+	scopes := make([]any, len(entity.KeyScopes))
+	for i, scope := range entity.KeyScopes {
+		scopes[i] = scope
+	}
+	return scopes
 }
 
 type Device struct {
@@ -84,16 +191,18 @@ func (r GetUploadURLRequest) Validate() error {
 type UploadURLResponse struct {
 	AccountID   string    `json:"account_id"`
 	ContentType string    `json:"content_type"`
-	URL         string    `json:"url"`
+	URL         string    `json:"url"`      // presigned url to PUT the content to, with the same Content-Type
+	Location    string    `json:"location"` // where the content can be read once uploaded, used when creating the template
 	ExpireAt    time.Time `json:"expire_at"`
 }
 
 type CreateTemplateRequest struct {
-	AccountID   string     `json:"account_id"`
-	Name        string     `json:"name"`
-	ContentType string     `json:"content_type"`
-	Location    string     `json:"location"`
-	Vars        entity.Map `json:"vars"`
+	AccountID   string               `json:"account_id"`
+	Name        string               `json:"name"`
+	ContentType string               `json:"content_type"`
+	Location    string               `json:"location"`
+	Vars        entity.Map           `json:"vars"`
+	Blocks      entity.ContentBlocks `json:"blocks"`
 }
 
 func (r CreateTemplateRequest) Validate() error {
@@ -102,14 +211,17 @@ func (r CreateTemplateRequest) Validate() error {
 		validation.Field(&r.Name, validation.Required),
 		validation.Field(&r.ContentType, validation.Required),
 		validation.Field(&r.Location, validation.Required, is.URL),
+		validation.Field(&r.Blocks, validation.By(validateContentBlocks)),
 	)
 }
 
 type UpdateTemplateRequest struct {
-	AccountID  string     `json:"account_id"`
-	TemplateID string     `json:"template_id"`
-	Location   string     `json:"location"`
-	Vars       entity.Map `json:"vars"`
+	AccountID  string               `json:"account_id"`
+	TemplateID string               `json:"template_id"`
+	Location   string               `json:"location"`
+	Vars       entity.Map           `json:"vars"`
+	Blocks     entity.ContentBlocks `json:"blocks"` // when omitted the blocks of the existing template are kept
+	IfMatch    string               `json:"-"`      // the ETag the client last saw, the write is rejected if it is stale
 }
 
 func (r UpdateTemplateRequest) Validate() error {
@@ -117,9 +229,45 @@ func (r UpdateTemplateRequest) Validate() error {
 		validation.Field(&r.AccountID, validation.Required),
 		validation.Field(&r.TemplateID, validation.Required),
 		validation.Field(&r.Location, validation.Required, is.URL),
+		validation.Field(&r.Blocks, validation.By(validateContentBlocks)),
+	)
+}
+
+func validateContentBlocks(value any) error {
+	blocks, _ := value.(entity.ContentBlocks)
+	for i, block := range blocks {
+		if block.Name == "" {
+			return fmt.Errorf("block %d: name is required", i)
+		}
+		if err := expression.Validate(block.Condition); err != nil {
+			return fmt.Errorf("block %q: invalid condition: %w", block.Name, err)
+		}
+	}
+	return nil
+}
+
+type RenderTemplateRequest struct {
+	AccountID  string         `json:"account_id"`
+	TemplateID string         `json:"template_id"`
+	Vars       entity.Map     `json:"vars"`
+	Attributes map[string]any `json:"attributes"` // recipient attributes the block conditions are evaluated against
+	Draft      bool           `json:"draft"`      // render the given version as is instead of the published one
+}
+
+func (r RenderTemplateRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.TemplateID, validation.Required),
 	)
 }
 
+type RenderTemplateResponse struct {
+	TemplateID  string `json:"template_id"`
+	Version     uint64 `json:"version"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+}
+
 type DeleteTemplateRequest struct {
 	AccountID  string `json:"account_id"`
 	TemplateID string `json:"template_id"`
@@ -130,14 +278,14 @@ func (r DeleteTemplateRequest) Validate() error {
 	return validation.ValidateStruct(&r,
 		validation.Field(&r.AccountID, validation.Required),
 		validation.Field(&r.TemplateID, validation.Required),
-		validation.Field(&r.Version, validation.Required, is.Digit),
+		validation.Field(&r.Version, validation.Required),
 	)
 }
 
 type ListTemplatesRequest struct {
-	AccountID string
-	Page      int
-	PageSize  int
+	AccountID string `json:"account_id"`
+	Page      int    `json:"page"`
+	PageSize  int    `json:"page_size"`
 }
 
 func (r ListTemplatesRequest) Validate() error {
@@ -160,6 +308,238 @@ func (r GetTemplateRequest) Validate() error {
 	)
 }
 
+type TemplateContentRequest struct {
+	AccountID  string `json:"account_id"`
+	TemplateID string `json:"template_id"`
+	Version    uint64 `json:"version"` // optional, another version of the same template
+}
+
+func (r TemplateContentRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.TemplateID, validation.Required),
+	)
+}
+
+type SendTemplateRequest struct {
+	AccountID    string         `json:"account_id"`
+	TemplateID   string         `json:"template_id"`
+	CredentialID string         `json:"credential_id"` // defaults to the first active email credential of the account
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Subject      string         `json:"subject"`
+	Vars         entity.Map     `json:"vars"`
+	Attributes   map[string]any `json:"attributes"`
+	Draft        bool           `json:"draft"`
+
+	TestKeyID string `json:"-"` // set for test API keys, the message is captured instead of sent
+}
+
+func (r SendTemplateRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.TemplateID, validation.Required),
+		validation.Field(&r.From, validation.Required, is.EmailFormat),
+		validation.Field(&r.To, validation.Required, is.EmailFormat),
+		validation.Field(&r.Subject, validation.Required),
+	)
+}
+
+type SendTemplateResponse struct {
+	TemplateID   string          `json:"template_id"`
+	Version      uint64          `json:"version"`
+	CredentialID string          `json:"credential_id"`
+	Platform     entity.Platform `json:"platform"`
+	To           string          `json:"to"`
+	CapturedID   string          `json:"captured_id,omitempty"` // the captured message of sends with a test key
+}
+
+type ReviewTemplateRequest struct {
+	AccountID  string `json:"account_id"`
+	TemplateID string `json:"template_id"`
+	Comment    string `json:"comment"`
+	// Actor is who applies the transition, recorded in the review history:
+	// "key:<id>" for API keys, "session" for the account itself and
+	// "schedule:<id>" for the scheduler
+	Actor string `json:"-"`
+}
+
+func (r ReviewTemplateRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.TemplateID, validation.Required),
+	)
+}
+
+type ScheduleTemplateRequest struct {
+	AccountID  string `json:"account_id"`
+	TemplateID string `json:"template_id"`
+	PublishAt  string `json:"publish_at"` // RFC3339, or a wall clock time (2006-01-02T15:04:05) in Timezone
+	RevertAt   string `json:"revert_at"`  // optional, same format as PublishAt
+	Timezone   string `json:"timezone"`   // IANA name e.g Africa/Lagos, defaults to UTC
+}
+
+func (r ScheduleTemplateRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.TemplateID, validation.Required),
+		validation.Field(&r.PublishAt, validation.Required),
+		validation.Field(&r.Timezone, validation.By(func(value any) error {
+			_, err := time.LoadLocation(value.(string))
+			return err
+		})),
+	)
+}
+
+// Times converts PublishAt and RevertAt to UTC
+func (r ScheduleTemplateRequest) Times() (publishAt time.Time, revertAt *time.Time, err error) {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return publishAt, nil, err
+	}
+	if publishAt, err = parseScheduleTime(r.PublishAt, loc); err != nil {
+		return publishAt, nil, fmt.Errorf("publish_at: %w", err)
+	}
+	if r.RevertAt == "" {
+		return publishAt, nil, nil
+	}
+	revert, err := parseScheduleTime(r.RevertAt, loc)
+	if err != nil {
+		return publishAt, nil, fmt.Errorf("revert_at: %w", err)
+	}
+	return publishAt, &revert, nil
+}
+
+func parseScheduleTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", value, loc)
+	if err != nil {
+		return t, errors.New("must be RFC3339 or 2006-01-02T15:04:05")
+	}
+	return t.UTC(), nil
+}
+
+type ListSchedulesRequest struct {
+	AccountID string `json:"account_id"`
+	Status    string `json:"status"`
+}
+
+type CancelScheduleRequest struct {
+	AccountID  string `json:"account_id"`
+	ScheduleID string `json:"schedule_id"`
+}
+
+func (r CancelScheduleRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.ScheduleID, validation.Required),
+	)
+}
+
+type AnalyticsRequest struct {
+	AccountID  string           `json:"account_id"`
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	TemplateID string           `json:"template_id"` // optional, covers every version of the template
+	Kind       entity.EventKind `json:"kind"`        // optional, render or send
+	Limit      int              `json:"limit"`
+}
+
+func (r AnalyticsRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.From, validation.Required),
+		validation.Field(&r.To, validation.Required, validation.Min(r.From)),
+		validation.Field(&r.Kind, validation.In(entity.EventKindRender, entity.EventKindSend)),
+		validation.Field(&r.Limit, validation.Min(0), validation.Max(100)),
+	)
+}
+
+type CreateWebhookRequest struct {
+	AccountID   string                `json:"account_id"`
+	URL         string                `json:"url"`
+	Description string                `json:"description"`
+	Events      []entity.WebhookEvent `json:"events"`
+}
+
+func (r CreateWebhookRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.URL, validation.Required, is.URL),
+		validation.Field(&r.Events, validation.Required, validation.Each(validation.In(webhookEvents()...))),
+	)
+}
+
+type UpdateWebhookRequest struct {
+	AccountID   string                `json:"account_id"`
+	WebhookID   string                `json:"webhook_id"`
+	URL         string                `json:"url"`
+	Description *string               `json:"description"`
+	Events      []entity.WebhookEvent `json:"events"`
+	Active      *bool                 `json:"active"`
+}
+
+func (r UpdateWebhookRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.WebhookID, validation.Required),
+		validation.Field(&r.URL, is.URL),
+		validation.Field(&r.Events, validation.Each(validation.In(webhookEvents()...))),
+	)
+}
+
+type WebhookRequest struct {
+	AccountID string `json:"account_id"`
+	WebhookID string `json:"webhook_id"`
+}
+
+func (r WebhookRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.WebhookID, validation.Required),
+	)
+}
+
+type ListDeliveriesRequest struct {
+	AccountID string
+	WebhookID string
+	Status    entity.DeliveryStatus
+	Page      int
+	PageSize  int
+}
+
+func (r ListDeliveriesRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.WebhookID, validation.Required),
+		validation.Field(&r.Status, validation.In(entity.DeliveryStatusPending, entity.DeliveryStatusSucceeded, entity.DeliveryStatusFailed)),
+		validation.Field(&r.Page, validation.Required),
+		validation.Field(&r.PageSize, validation.Required, validation.Max(100)),
+	)
+}
+
+type RedeliverRequest struct {
+	AccountID  string `json:"account_id"`
+	DeliveryID string `json:"delivery_id"`
+}
+
+func (r RedeliverRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.DeliveryID, validation.Required),
+	)
+}
+
+func webhookEvents() []any {
+	events := make([]any, len(entity.WebhookEvents))
+	for i, event := range entity.WebhookEvents {
+		events[i] = event
+	}
+	return events
+}
+
 type ImportTemplateRequest struct {
 	AccountID          string     `json:"account_id"`
 	Provider           string     `json:"provider"`
@@ -192,6 +572,40 @@ func (r ExportTemplateRequest) Validate() error {
 	)
 }
 
+type ExportBundleRequest struct {
+	AccountID  string   `json:"account_id"`
+	Keys       []string `json:"keys"`        // slugs of the templates to export, all templates when empty
+	LatestOnly bool     `json:"latest_only"` // only export the latest version of every template
+}
+
+func (r ExportBundleRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.Keys, validation.Each(validation.Required)),
+	)
+}
+
+// what an import does with templates whose key already exists in the account
+const (
+	ConflictSkip       = "skip"
+	ConflictOverwrite  = "overwrite"
+	ConflictNewVersion = "new_version"
+)
+
+type ImportBundleRequest struct {
+	AccountID string `json:"account_id"`
+	Conflict  string `json:"conflict"` // skip by default
+	Bundle    []byte `json:"-"`
+}
+
+func (r ImportBundleRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.Conflict, validation.In(ConflictSkip, ConflictOverwrite, ConflictNewVersion)),
+		validation.Field(&r.Bundle, validation.Required),
+	)
+}
+
 type CredentialInput struct {
 	ID string `json:"id"`
 	// AccountID string `json:"account_id"`
@@ -217,3 +631,18 @@ func (c *CredentialInput) ValidateUpdate() error {
 		validation.Field(&c.Type, validation.In(entity.EMAIL, entity.SMS, entity.PUSH)),
 	)
 }
+
+type ListCapturedMessagesRequest struct {
+	AccountID string
+	To        string
+	Page      int
+	PageSize  int
+}
+
+func (r ListCapturedMessagesRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.AccountID, validation.Required),
+		validation.Field(&r.Page, validation.Required),
+		validation.Field(&r.PageSize, validation.Required, validation.Max(100)),
+	)
+}
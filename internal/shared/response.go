@@ -1,10 +1,68 @@
 package shared
 
 import (
+	"time"
+
 	"template-manager/internal/entity"
+	"template-manager/pkg/cache"
 )
 
+// APIVersion is the version of the REST and gRPC APIs
+const APIVersion = "v1.0.0"
+
 type LoginResponse struct {
 	Account *entity.Account `json:"account"`
 	Session *entity.Session `json:"session"`
 }
+
+type DailyStat struct {
+	Day          time.Time        `json:"day"`
+	Kind         entity.EventKind `json:"kind"`
+	Count        int64            `json:"count"`
+	Failures     int64            `json:"failures"`
+	ErrorRate    float64          `json:"error_rate"`
+	P95LatencyMs float64          `json:"p95_latency_ms"`
+}
+
+type TemplateStat struct {
+	Slug         string    `json:"slug"`
+	TemplateID   string    `json:"template_id"` // the most recently used version
+	Count        int64     `json:"count"`
+	Failures     int64     `json:"failures"`
+	ErrorRate    float64   `json:"error_rate"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+}
+
+type UsageSummary struct {
+	Since        time.Time `json:"since"`
+	Until        time.Time `json:"until"` // exclusive
+	Renders      int64     `json:"renders"`
+	Sends        int64     `json:"sends"`
+	Failures     int64     `json:"failures"`
+	ErrorRate    float64   `json:"error_rate"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+}
+
+// ServerStats is the public state of a server, the usage of an account is
+// behind authentication, see UsageSummary
+type ServerStats struct {
+	GRPC    bool        `json:"grpc"` // the gRPC API is served, the REST API always is
+	Version string      `json:"version"`
+	Open    bool        `json:"open"` // open source version
+	Cache   cache.Stats `json:"cache"`
+}
+
+type KeyStaleness string
+
+const (
+	KeyStalenessExpired   KeyStaleness = "expired"
+	KeyStalenessExpiring  KeyStaleness = "expiring"   // expires within the requested days
+	KeyStalenessUnused    KeyStaleness = "unused"     // not used within the requested days
+	KeyStalenessNeverUsed KeyStaleness = "never_used" // created before the requested days and never used
+)
+
+type StaleKey struct {
+	entity.Key
+	Reason KeyStaleness `json:"reason"`
+}
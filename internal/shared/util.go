@@ -6,16 +6,38 @@ import (
 	"time"
 )
 
+var (
+	nonAlphanumeric = regexp.MustCompile("[^a-z0-9]+")
+	// generatedSlug matches the slugs of GenerateSlug
+	generatedSlug = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}<>\d{2}:\d{2}:\d{2}\.\d{2}-[a-z0-9-]*$`)
+)
+
+// maxSlugLength is the longest slug accepted from outside, e.g. the keys of an
+// imported bundle
+const maxSlugLength = 255
+
 func GenerateSlug(name string) string {
+	return time.Now().Format("2006-01-02<>15:04:05.00") + "-" + Slugify(name)
+}
+
+// ValidSlug reports whether slug is one GenerateSlug produces or a name that is
+// already in the form of Slugify, e.g. the key of a template directory
+func ValidSlug(slug string) bool {
+	if slug == "" || len(slug) > maxSlugLength {
+		return false
+	}
+	return Slugify(slug) == slug || generatedSlug.MatchString(slug)
+}
+
+// Slugify lowercases name and replaces everything but letters and digits with
+// hyphens, the result is safe to use in file names and URLs
+func Slugify(name string) string {
 	// Convert the name to lowercase
 	slug := strings.ToLower(name)
 
 	// Remove non-alphanumeric characters
-	reg := regexp.MustCompile("[^a-z0-9]+")
-	slug = reg.ReplaceAllString(slug, "-")
+	slug = nonAlphanumeric.ReplaceAllString(slug, "-")
 
 	// Remove leading and trailing hyphens
-	slug = strings.Trim(slug, "-")
-
-	return time.Now().Format("2006-01-02<>15:04:05.00") + "-" + slug
+	return strings.Trim(slug, "-")
 }
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a cache since it was created
+type Stats struct {
+	Entries     int   `json:"entries"`
+	Bytes       int64 `json:"bytes"`
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Evictions   int64 `json:"evictions"`   // removed to make room
+	Expirations int64 `json:"expirations"` // removed because the ttl passed
+	Removals    int64 `json:"removals"`    // removed by invalidation
+}
+
+// LRU is a least recently used cache bounded by a number of entries and by
+// the total size the caller reports for them. Entries expire after ttl.
+type LRU[K comparable, V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	ttl        time.Duration
+	bytes      int64
+	items      map[K]*list.Element
+	order      *list.List // front is the most recently used
+
+	hits, misses, evictions, expirations, removals atomic.Int64
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	size      int64
+	expiresAt time.Time
+}
+
+// NewLRU returns a cache, a limit of zero means no limit
+func NewLRU[K comparable, V any](maxEntries int, maxBytes int64, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ttl:        ttl,
+		items:      make(map[K]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return zero, false
+	}
+	e := element.Value.(*entry[K, V])
+	if c.ttl > 0 && time.Now().After(e.expiresAt) {
+		c.remove(element)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	c.hits.Add(1)
+	return e.value, true
+}
+
+// Add stores value under key, size is what it counts against maxBytes.
+// Values larger than maxBytes are not stored.
+func (c *LRU[K, V]) Add(key K, value V, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{
+		key:       key,
+		value:     value,
+		size:      size,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	c.bytes += size
+
+	for (c.maxEntries > 0 && c.order.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.order.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// RemoveFunc removes every entry whose key matches and returns how many were removed
+func (c *LRU[K, V]) RemoveFunc(match func(K) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, element := range c.items {
+		if match(key) {
+			c.remove(element)
+			removed++
+		}
+	}
+	c.removals.Add(int64(removed))
+	return removed
+}
+
+// Purge removes every entry
+func (c *LRU[K, V]) Purge() {
+	c.RemoveFunc(func(K) bool { return true })
+}
+
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	entries, bytes := c.order.Len(), c.bytes
+	c.mu.Unlock()
+	return Stats{
+		Entries:     entries,
+		Bytes:       bytes,
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Removals:    c.removals.Load(),
+	}
+}
+
+func (c *LRU[K, V]) remove(element *list.Element) {
+	e := c.order.Remove(element).(*entry[K, V])
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
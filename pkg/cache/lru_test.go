@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keys returns the keys of the cache, most recently used first
+func keys[K comparable, V any](c *LRU[K, V]) []K {
+	var found []K
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		found = append(found, element.Value.(*entry[K, V]).key)
+	}
+	return found
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](3, 0, time.Hour)
+	c.Add("a", 1, 1)
+	c.Add("b", 2, 1)
+	c.Add("c", 3, 1)
+	// reading a makes b the least recently used
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v", v, ok)
+	}
+	c.Add("d", 4, 1)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b wasn't evicted")
+	}
+	if got, want := keys(c), []string{"d", "a", "c"}; !slices.Equal(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+	if got := c.Stats().Evictions; got != 1 {
+		t.Errorf("evictions = %d, want 1", got)
+	}
+}
+
+func TestLRULimitsBytes(t *testing.T) {
+	c := NewLRU[string, string](0, 10, time.Hour)
+	c.Add("a", "a", 4)
+	c.Add("b", "b", 4)
+	c.Add("c", "c", 4)
+
+	if got, want := keys(c), []string{"c", "b"}; !slices.Equal(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+	if got := c.Stats().Bytes; got != 8 {
+		t.Errorf("bytes = %d, want 8", got)
+	}
+
+	// a value larger than the whole cache is not stored and evicts nothing
+	c.Add("big", "big", 11)
+	if _, ok := c.Get("big"); ok {
+		t.Error("stored a value larger than maxBytes")
+	}
+	if got := c.Stats().Entries; got != 2 {
+		t.Errorf("entries = %d, want 2", got)
+	}
+}
+
+func TestLRUReplacesValue(t *testing.T) {
+	c := NewLRU[string, string](0, 10, time.Hour)
+	c.Add("a", "old", 6)
+	c.Add("a", "new", 3)
+
+	if v, _ := c.Get("a"); v != "new" {
+		t.Errorf("Get(a) = %q, want new", v)
+	}
+	if stats := c.Stats(); stats.Entries != 1 || stats.Bytes != 3 {
+		t.Errorf("stats = %+v, want 1 entry of 3 bytes", stats)
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	c := NewLRU[string, int](0, 0, time.Minute)
+	c.Add("a", 1, 1)
+	c.items["a"].Value.(*entry[string, int]).expiresAt = time.Now().Add(-time.Second)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() returned an expired entry")
+	}
+	if stats := c.Stats(); stats.Entries != 0 || stats.Expirations != 1 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want the entry expired", stats)
+	}
+}
+
+func TestLRURemoveFunc(t *testing.T) {
+	c := NewLRU[string, int](0, 0, time.Hour)
+	c.Add("welcome/1", 1, 1)
+	c.Add("welcome/2", 2, 1)
+	c.Add("reset/1", 3, 1)
+
+	removed := c.RemoveFunc(func(key string) bool { return strings.HasPrefix(key, "welcome/") })
+	if removed != 2 {
+		t.Errorf("RemoveFunc() = %d, want 2", removed)
+	}
+	if got, want := keys(c), []string{"reset/1"}; !slices.Equal(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+
+	c.Purge()
+	if stats := c.Stats(); stats.Entries != 0 || stats.Bytes != 0 || stats.Removals != 3 {
+		t.Errorf("stats = %+v, want an empty cache after 3 removals", stats)
+	}
+}
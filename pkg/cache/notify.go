@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Broadcaster tells the other instances of the service which cache entries
+// to drop, through postgres LISTEN/NOTIFY
+type Broadcaster struct {
+	db      *gorm.DB
+	dsn     string
+	channel string
+	logger  *slog.Logger
+}
+
+func NewBroadcaster(db *gorm.DB, dsn, channel string, logger *slog.Logger) *Broadcaster {
+	return &Broadcaster{
+		db:      db,
+		dsn:     dsn,
+		channel: channel,
+		logger:  logger,
+	}
+}
+
+// Publish sends key to every listener, including the one of this instance
+func (b *Broadcaster) Publish(ctx context.Context, key string) error {
+	return b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", b.channel, key).Error
+}
+
+// Listen calls invalidate with every key published until ctx is done. After
+// the connection was lost notifications may have been missed, so invalidate
+// is called with an empty key which means everything.
+func (b *Broadcaster) Listen(ctx context.Context, invalidate func(key string)) error {
+	listener := pq.NewListener(b.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			b.logger.ErrorContext(ctx, "cache invalidation listener", "channel", b.channel, "err", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(b.channel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case notification := <-listener.Notify:
+			if notification == nil {
+				// reconnected
+				invalidate("")
+				continue
+			}
+			invalidate(notification.Extra)
+		case <-time.After(90 * time.Second):
+			go func() {
+				if err := listener.Ping(); err != nil {
+					b.logger.ErrorContext(ctx, "cache invalidation listener ping failed", "err", err)
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// analyticsDateLayout is the format of the from and to dates of the analytics endpoints
+const analyticsDateLayout = "2006-01-02"
+
+// AnalyticsSummary returns the renders, sends, error rate and p95 latency of
+// the account between req.From and req.To, the API defaults to the last 30 days
+func (c *Client) AnalyticsSummary(ctx context.Context, req AnalyticsRequest) (*UsageSummary, error) {
+	var summary UsageSummary
+	if err := c.do(ctx, http.MethodGet, "/api/analytics/summary", analyticsQuery(req), nil, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// DailyAnalytics returns the renders and sends per day between req.From and
+// req.To (inclusive days), the API defaults to the last 30 days
+func (c *Client) DailyAnalytics(ctx context.Context, req AnalyticsRequest) ([]DailyStat, error) {
+	var stats []DailyStat
+	if err := c.do(ctx, http.MethodGet, "/api/analytics/daily", analyticsQuery(req), nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (c *Client) TopTemplates(ctx context.Context, req AnalyticsRequest) ([]TemplateStat, error) {
+	var stats []TemplateStat
+	if err := c.do(ctx, http.MethodGet, "/api/analytics/templates/top", analyticsQuery(req), nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// UnusedTemplates returns the templates that were not rendered or sent in the range
+func (c *Client) UnusedTemplates(ctx context.Context, req AnalyticsRequest) ([]Template, error) {
+	var templates []Template
+	if err := c.do(ctx, http.MethodGet, "/api/analytics/templates/unused", analyticsQuery(req), nil, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func analyticsQuery(req AnalyticsRequest) url.Values {
+	query := url.Values{}
+	if !req.From.IsZero() {
+		query.Set("from", req.From.Format(analyticsDateLayout))
+	}
+	if !req.To.IsZero() {
+		query.Set("to", req.To.Format(analyticsDateLayout))
+	}
+	if req.TemplateID != "" {
+		query.Set("template_id", req.TemplateID)
+	}
+	if req.Kind != "" {
+		query.Set("kind", req.Kind)
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	return query
+}
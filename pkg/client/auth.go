@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Signup creates an account and emails a verification link, the password is
+// chosen with VerifyEmail
+func (c *Client) Signup(ctx context.Context, req SignUpRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/users/signup", nil, req, nil)
+}
+
+// VerifyEmail verifies the email of an account with the token of the emailed
+// link and sets its password
+func (c *Client) VerifyEmail(ctx context.Context, req VerifyEmailRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/users/verify", nil, req, nil)
+}
+
+// ResendVerification emails a new verification link, at most once a minute
+func (c *Client) ResendVerification(ctx context.Context, email string) error {
+	return c.do(ctx, http.MethodPost, "/api/users/verify/resend", nil, map[string]string{"email": email}, nil)
+}
+
+// Login starts a session, use the token of the session with WithToken
+func (c *Client) Login(ctx context.Context, email, password string) (*LoginResponse, error) {
+	var response LoginResponse
+	err := c.do(ctx, http.MethodPost, "/api/users/login", nil, map[string]string{"email": email, "password": password}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Logout ends the session of token
+func (c *Client) Logout(ctx context.Context, token string) error {
+	return c.do(ctx, http.MethodPost, "/api/users/logout", nil, map[string]string{"token": token}, nil)
+}
+
+// InitiateResetPassword emails a link with the token of ResetPassword
+func (c *Client) InitiateResetPassword(ctx context.Context, req InitiateResetPasswordRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/users/reset-password", nil, req, nil)
+}
+
+// ResetPassword sets a new password with the emailed token, the sessions of
+// the account end
+func (c *Client) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/users/reset-password/complete", nil, req, nil)
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ExportBundle downloads the templates of the account as a zip bundle, all
+// templates when keys is empty
+func (c *Client) ExportBundle(ctx context.Context, keys []string, latestOnly bool) ([]byte, error) {
+	query := url.Values{}
+	if len(keys) > 0 {
+		query.Set("keys", strings.Join(keys, ","))
+	}
+	if latestOnly {
+		query.Set("latest_only", "true")
+	}
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/bundles/export", query: query})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// ImportBundle imports a zip bundle, conflict is one of the Conflict* values
+func (c *Client) ImportBundle(ctx context.Context, bundle []byte, conflict string) (*ImportBundleResult, error) {
+	var result ImportBundleResult
+	err := c.call(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/bundles/import",
+		query:       url.Values{"conflict": {conflict}},
+		contentType: "application/zip",
+		body:        bundle,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
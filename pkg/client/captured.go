@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListCapturedMessages returns the messages sent with test API keys, to
+// filters them by recipient when set
+func (c *Client) ListCapturedMessages(ctx context.Context, to string, page, pageSize int) (*Page[[]CapturedMessage], error) {
+	query := pageQuery(page, pageSize)
+	if to != "" {
+		query.Set("to", to)
+	}
+	var messages Page[[]CapturedMessage]
+	if err := c.do(ctx, http.MethodGet, "/api/test/messages", query, nil, &messages); err != nil {
+		return nil, err
+	}
+	return &messages, nil
+}
+
+func (c *Client) GetCapturedMessage(ctx context.Context, id string) (*CapturedMessage, error) {
+	var message CapturedMessage
+	if err := c.do(ctx, http.MethodGet, "/api/test/messages/"+url.PathEscape(id), nil, nil, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (c *Client) ClearCapturedMessages(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/api/test/messages", nil, nil, nil)
+}
@@ -0,0 +1,233 @@
+// Package client calls the REST API of the template manager. It only depends
+// on the standard library, the request and response types mirror the JSON of
+// the API. Failed requests return an *Error.
+//
+//	api := client.New("https://templates.example.com", client.WithAPIKey(key))
+//	rendered, err := api.RenderTemplate(ctx, client.RenderTemplateRequest{TemplateID: id, Vars: vars})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader is the header API keys are sent in
+const APIKeyHeader = "X-API-Key"
+
+const (
+	defaultRetries = 2
+	retryBackoff   = 250 * time.Millisecond
+	maxRetryWait   = 10 * time.Second
+)
+
+// Client is safe for concurrent use
+type Client struct {
+	baseURL string
+	token   string
+	apiKey  string
+	retries int
+	http    *http.Client
+}
+
+type Option func(*Client)
+
+// WithToken authenticates requests with a session token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithAPIKey authenticates requests with an API key instead of a session
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// WithRetries sets how often requests that can safely be repeated are retried
+// after a network error or a 429, 502, 503 or 504 response, 2 by default.
+// Requests that create something or send messages are never retried, template
+// updates only when they have an IfMatch.
+func WithRetries(retries int) Option {
+	return func(c *Client) {
+		c.retries = retries
+	}
+}
+
+// New returns a client of the API at baseURL e.g http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		retries: defaultRetries,
+		http:    &http.Client{Timeout: time.Minute},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// envelope is the body of the JSON responses of the API
+type envelope struct {
+	Status  bool            `json:"status"`
+	Message json.RawMessage `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// request is a call to the API, body is kept as bytes so it can be retried
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	contentType string
+	body        []byte
+	idempotent  bool // safe to retry, GET and DELETE always are
+}
+
+// do sends body as JSON and decodes the data of the response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	req, err := jsonRequest(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, req, out)
+}
+
+// call sends req and decodes the data of the response into out
+func (c *Client) call(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp.Body, out)
+}
+
+func jsonRequest(method, path string, query url.Values, body any) (request, error) {
+	req := request{method: method, path: path, query: query}
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return req, err
+		}
+		req.body, req.contentType = raw, "application/json"
+	}
+	return req, nil
+}
+
+// decode reads the data of a response envelope into out, nil skips the body
+func decode(body io.Reader, out any) error {
+	if out == nil {
+		return nil
+	}
+	var env envelope
+	if err := json.NewDecoder(body).Decode(&env); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
+
+// send makes the request, retrying it when that is safe, and turns
+// unsuccessful responses into an *Error. The caller closes the body of the
+// returned response.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	retries := 0
+	if req.idempotent || req.method == http.MethodGet || req.method == http.MethodDelete {
+		retries = c.retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		var retryAfter string
+		if err == nil {
+			retryAfter = resp.Header.Get("Retry-After")
+			err = responseError(resp)
+			resp.Body.Close()
+		}
+		if attempt >= retries || !retryable(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait(attempt, retryAfter)):
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req request) (*http.Response, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set(APIKeyHeader, c.apiKey)
+	} else if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return c.http.Do(httpReq)
+}
+
+// retryable reports whether a request that failed with err may succeed when
+// repeated, errors of the context are final
+func retryable(err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// wait is how long to wait before the next attempt, the Retry-After header of
+// the response wins over the exponential backoff
+func wait(attempt int, retryAfter string) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, maxRetryWait)
+	}
+	backoff := retryBackoff << attempt
+	return min(backoff+time.Duration(rand.Int63n(int64(backoff))), maxRetryWait)
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+	"template-manager/pkg/cache"
+)
+
+// newFailingServer answers every request with 503 and counts them
+func newFailingServer(t *testing.T) (*Client, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	return New(server.URL, WithRetries(2)), &calls
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name  string
+		call  func(*Client) error
+		calls int32
+	}{
+		{"get", func(c *Client) error {
+			_, err := c.GetTemplate(context.Background(), "tpl")
+			return err
+		}, 3},
+		{"update without if-match", func(c *Client) error {
+			return c.UpdateTemplate(context.Background(), UpdateTemplateRequest{TemplateID: "tpl"})
+		}, 1},
+		{"update with if-match", func(c *Client) error {
+			return c.UpdateTemplate(context.Background(), UpdateTemplateRequest{TemplateID: "tpl", IfMatch: `"1-0"`})
+		}, 3},
+		{"edit in place", func(c *Client) error {
+			return c.EditTemplate(context.Background(), UpdateTemplateRequest{TemplateID: "tpl"})
+		}, 3},
+		{"create", func(c *Client) error {
+			return c.CreateTemplate(context.Background(), CreateTemplateRequest{Name: "welcome"})
+		}, 1},
+		{"send", func(c *Client) error {
+			_, err := c.SendTemplate(context.Background(), SendTemplateRequest{TemplateID: "tpl"})
+			return err
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, calls := newFailingServer(t)
+			if err := tt.call(api); err == nil {
+				t.Fatal("call succeeded against a failing server")
+			}
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("server got %d requests, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+// jsonFields returns the JSON names of the fields of a struct type, including
+// the ones of embedded structs
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !field.IsExported():
+		case field.Anonymous && name == "":
+			fields = append(fields, jsonFields(field.Type)...)
+		case name == "":
+			fields = append(fields, field.Name)
+		default:
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// TestTypesMatchAPI keeps the types of the client in line with the JSON of the
+// server. The account of requests comes from the credentials, the client
+// doesn't send it.
+func TestTypesMatchAPI(t *testing.T) {
+	tests := []struct {
+		client, server any
+	}{
+		{Account{}, entity.Account{}},
+		{Session{}, entity.Session{}},
+		{Device{}, entity.Device{}},
+		{Key{}, entity.Key{}},
+		{StaleKey{}, shared.StaleKey{}},
+		{Template{}, entity.Template{}},
+		{ContentBlock{}, entity.ContentBlock{}},
+		{TemplateReview{}, entity.TemplateReview{}},
+		{TemplateSchedule{}, entity.TemplateSchedule{}},
+		{CapturedMessage{}, entity.CapturedMessage{}},
+		{Credential{}, entity.Credential{}},
+		{Webhook{}, entity.Webhook{}},
+		{WebhookDelivery{}, entity.WebhookDelivery{}},
+		{CacheStats{}, cache.Stats{}},
+		{ServerStats{}, shared.ServerStats{}},
+		{UsageSummary{}, shared.UsageSummary{}},
+		{DailyStat{}, shared.DailyStat{}},
+		{TemplateStat{}, shared.TemplateStat{}},
+		{ImportBundleResult{}, shared.ImportBundleResult{}},
+		{SignUpRequest{}, shared.SignUpRequest{}},
+		{VerifyEmailRequest{}, shared.VerifyEmailRequest{}},
+		{InitiateResetPasswordRequest{}, shared.InitiateResetPasswordRequest{}},
+		{ResetPasswordRequest{}, shared.ResetPasswordRequest{}},
+		{CreateKeyRequest{}, shared.CreateAccessKeyRequest{}},
+		{GetUploadURLRequest{}, shared.GetUploadURLRequest{}},
+		{UploadURLResponse{}, shared.UploadURLResponse{}},
+		{CreateTemplateRequest{}, shared.CreateTemplateRequest{}},
+		{UpdateTemplateRequest{}, shared.UpdateTemplateRequest{}},
+		{RenderTemplateRequest{}, shared.RenderTemplateRequest{}},
+		{RenderTemplateResponse{}, shared.RenderTemplateResponse{}},
+		{SendTemplateRequest{}, shared.SendTemplateRequest{}},
+		{SendTemplateResponse{}, shared.SendTemplateResponse{}},
+		{ImportTemplateRequest{}, shared.ImportTemplateRequest{}},
+		{ExportTemplateRequest{}, shared.ExportTemplateRequest{}},
+		{ReviewTemplateRequest{}, shared.ReviewTemplateRequest{}},
+		{ScheduleTemplateRequest{}, shared.ScheduleTemplateRequest{}},
+		{CredentialInput{}, shared.CredentialInput{}},
+		{CreateWebhookRequest{}, shared.CreateWebhookRequest{}},
+		{UpdateWebhookRequest{}, shared.UpdateWebhookRequest{}},
+	}
+	for _, tt := range tests {
+		clientType, serverType := reflect.TypeOf(tt.client), reflect.TypeOf(tt.server)
+		t.Run(clientType.Name(), func(t *testing.T) {
+			var want []string
+			for _, field := range jsonFields(serverType) {
+				if field != "account_id" || !strings.HasSuffix(serverType.Name(), "Request") {
+					want = append(want, field)
+				}
+			}
+			if got := jsonFields(clientType); !reflect.DeepEqual(got, want) {
+				t.Errorf("fields of client.%s = %v, %s has %v", clientType.Name(), got, serverType, want)
+			}
+		})
+	}
+}
+
+func TestTemplateETag(t *testing.T) {
+	server := entity.Template{Version: 3, UpdatedAt: time.Now()}
+	if got := (Template{Version: server.Version, UpdatedAt: server.UpdatedAt}).ETag(); got != server.ETag() {
+		t.Errorf("ETag() = %s, want %s", got, server.ETag())
+	}
+}
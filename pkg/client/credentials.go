@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) CreateCredential(ctx context.Context, input CredentialInput) error {
+	return c.do(ctx, http.MethodPost, "/api/credentials", nil, input, nil)
+}
+
+func (c *Client) ListCredentials(ctx context.Context) ([]Credential, error) {
+	var credentials []Credential
+	if err := c.do(ctx, http.MethodGet, "/api/credentials", nil, nil, &credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (c *Client) GetCredential(ctx context.Context, id string) (*Credential, error) {
+	var credential Credential
+	if err := c.do(ctx, http.MethodGet, "/api/credentials/"+url.PathEscape(id), nil, nil, &credential); err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// UpdateCredential updates the credential with the ID of input
+func (c *Client) UpdateCredential(ctx context.Context, input CredentialInput) error {
+	req, err := jsonRequest(http.MethodPut, "/api/credentials", nil, input)
+	if err != nil {
+		return err
+	}
+	req.idempotent = true // replaces the credential in place
+	return c.call(ctx, req, nil)
+}
+
+func (c *Client) DeleteCredential(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/credentials/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error is returned for responses that are not successful. Compare it with
+// the Err* values using errors.Is to check the kind of failure.
+type Error struct {
+	StatusCode int
+	Message    string
+	// Fields holds the validation errors of a 400 response by field
+	Fields map[string]string
+	// Data is the data of the response if it had any
+	Data json.RawMessage
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("template manager: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("template manager: %d %s", e.StatusCode, e.Message)
+}
+
+// Is matches the Err* values by status code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.StatusCode == e.StatusCode
+}
+
+var (
+	ErrBadRequest         = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized       = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden          = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound           = &Error{StatusCode: http.StatusNotFound}
+	ErrPreconditionFailed = &Error{StatusCode: http.StatusPreconditionFailed}
+	ErrUnprocessable      = &Error{StatusCode: http.StatusUnprocessableEntity} // the API rejected the request, see Message
+	ErrTooManyRequests    = &Error{StatusCode: http.StatusTooManyRequests}
+)
+
+// PreconditionFailedError is returned when an update was based on a stale
+// version of a template, Current is the template to apply the changes to
+type PreconditionFailedError struct {
+	Err     *Error
+	Current *Template
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PreconditionFailedError) Unwrap() error {
+	return e.Err
+}
+
+func responseError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		apiErr.Message = strings.TrimSpace(string(raw))
+		return apiErr
+	}
+	// validation errors are an object of field errors instead of a string
+	if err := json.Unmarshal(env.Message, &apiErr.Message); err != nil {
+		if json.Unmarshal(env.Message, &apiErr.Fields) == nil {
+			apiErr.Message = "invalid request"
+		} else {
+			apiErr.Message = string(env.Message)
+		}
+	}
+	if len(env.Data) > 0 && string(env.Data) != "null" {
+		apiErr.Data = env.Data
+	}
+
+	if apiErr.StatusCode == http.StatusPreconditionFailed && apiErr.Data != nil {
+		var current Template
+		if json.Unmarshal(apiErr.Data, &current) == nil {
+			return &PreconditionFailedError{Err: apiErr, Current: &current}
+		}
+	}
+	return apiErr
+}
+
+// IsNotFound reports whether err means the requested resource does not exist.
+// The API answers lookups of missing records with 422 "record not found".
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound ||
+		(apiErr.StatusCode == http.StatusUnprocessableEntity && strings.Contains(apiErr.Message, "not found"))
+}
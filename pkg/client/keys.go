@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateKey returns the key with its secret, the only time the secret is shown.
+// The key never expires when req.ExpiresAt is nil.
+func (c *Client) CreateKey(ctx context.Context, req CreateKeyRequest) (*Key, error) {
+	var key Key
+	if err := c.do(ctx, http.MethodPost, "/api/keys", nil, req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (c *Client) ListKeys(ctx context.Context) ([]Key, error) {
+	var keys []Key
+	if err := c.do(ctx, http.MethodGet, "/api/keys", nil, nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RotateKey returns the key with its new secret, the old secret remains valid
+// for the grace period e.g 24h
+func (c *Client) RotateKey(ctx context.Context, id, gracePeriod string) (*Key, error) {
+	var key Key
+	req := map[string]string{"grace_period": gracePeriod}
+	if err := c.do(ctx, http.MethodPost, "/api/keys/"+url.PathEscape(id)+"/rotate", nil, req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// StaleKeys lists the keys that expire or weren't used within days
+func (c *Client) StaleKeys(ctx context.Context, days int) ([]StaleKey, error) {
+	var keys []StaleKey
+	query := url.Values{"days": {strconv.Itoa(days)}}
+	if err := c.do(ctx, http.MethodGet, "/api/keys/stale", query, nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (c *Client) DeleteKey(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/keys/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+func (c *Client) ListTemplateReviews(ctx context.Context, templateID string) ([]TemplateReview, error) {
+	var reviews []TemplateReview
+	if err := c.do(ctx, http.MethodGet, templatePath(templateID)+"/reviews", nil, nil, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// RequestReview submits a draft for review
+func (c *Client) RequestReview(ctx context.Context, req ReviewTemplateRequest) (*Template, error) {
+	return c.review(ctx, "review", req)
+}
+
+func (c *Client) ApproveTemplate(ctx context.Context, req ReviewTemplateRequest) (*Template, error) {
+	return c.review(ctx, "approve", req)
+}
+
+// RejectTemplate sends a version in review back to draft
+func (c *Client) RejectTemplate(ctx context.Context, req ReviewTemplateRequest) (*Template, error) {
+	return c.review(ctx, "reject", req)
+}
+
+// PublishTemplate makes an approved version the one that is rendered and sent
+func (c *Client) PublishTemplate(ctx context.Context, req ReviewTemplateRequest) (*Template, error) {
+	return c.review(ctx, "publish", req)
+}
+
+func (c *Client) ArchiveTemplate(ctx context.Context, req ReviewTemplateRequest) (*Template, error) {
+	return c.review(ctx, "archive", req)
+}
+
+func (c *Client) review(ctx context.Context, action string, req ReviewTemplateRequest) (*Template, error) {
+	var template Template
+	if err := c.do(ctx, http.MethodPost, templatePath(req.TemplateID)+"/"+action, nil, req, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ScheduleTemplate publishes an approved version at req.PublishAt
+func (c *Client) ScheduleTemplate(ctx context.Context, req ScheduleTemplateRequest) (*TemplateSchedule, error) {
+	var schedule TemplateSchedule
+	if err := c.do(ctx, http.MethodPost, templatePath(req.TemplateID)+"/schedules", nil, req, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListSchedules returns the schedules of the account, all of them when status is empty
+func (c *Client) ListSchedules(ctx context.Context, status string) ([]TemplateSchedule, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	var schedules []TemplateSchedule
+	if err := c.do(ctx, http.MethodGet, "/api/schedules", query, nil, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (c *Client) CancelSchedule(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/schedules/"+url.PathEscape(id), nil, nil, nil)
+}
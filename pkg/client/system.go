@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Health returns an error unless the server is up
+func (c *Client) Health(ctx context.Context) error {
+	return c.raw(ctx, "/health", nil)
+}
+
+func (c *Client) Stats(ctx context.Context) (*ServerStats, error) {
+	var stats ServerStats
+	if err := c.raw(ctx, "/stats", &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// raw gets a route that responds without the envelope
+func (c *Client) raw(ctx context.Context, path string, out any) error {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: path})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) ListTemplates(ctx context.Context, page, pageSize int) (*Page[[]Template], error) {
+	var templates Page[[]Template]
+	if err := c.do(ctx, http.MethodGet, "/api/templates", pageQuery(page, pageSize), nil, &templates); err != nil {
+		return nil, err
+	}
+	return &templates, nil
+}
+
+// GetTemplate returns a template version, its ETag() can be used as the
+// IfMatch of updates
+func (c *Client) GetTemplate(ctx context.Context, id string) (*Template, error) {
+	var template Template
+	if err := c.do(ctx, http.MethodGet, templatePath(id), nil, nil, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// TemplateContent returns the body of a template, or of another version of
+// it when version is not zero
+func (c *Client) TemplateContent(ctx context.Context, id string, version uint64) ([]byte, error) {
+	path := templatePath(id) + "/content"
+	if version != 0 {
+		path = fmt.Sprintf("%s/versions/%d/content", templatePath(id), version)
+	}
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: path})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// GetUploadURL returns a signed url to PUT the body of a template to, see
+// UploadContent for doing both at once
+func (c *Client) GetUploadURL(ctx context.Context, req GetUploadURLRequest) (*UploadURLResponse, error) {
+	call, err := jsonRequest(http.MethodPost, "/api/templates/upload-url", nil, req)
+	if err != nil {
+		return nil, err
+	}
+	call.idempotent = true // only signs a new url
+	var upload UploadURLResponse
+	if err := c.call(ctx, call, &upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// UploadContent uploads the body of a template and returns the location to
+// create or update the template with
+func (c *Client) UploadContent(ctx context.Context, name, contentType string, content []byte) (string, error) {
+	upload, err := c.GetUploadURL(ctx, GetUploadURLRequest{Name: name, ContentType: contentType})
+	if err != nil {
+		return "", err
+	}
+
+	// the upload url is signed, it must not carry the credentials of the client
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, upload.URL, bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", responseError(resp)
+	}
+	return upload.Location, nil
+}
+
+func (c *Client) CreateTemplate(ctx context.Context, req CreateTemplateRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/templates", nil, req, nil)
+}
+
+// UpdateTemplate creates a new version of the template. When req.IfMatch is
+// set a stale update fails with a *PreconditionFailedError.
+func (c *Client) UpdateTemplate(ctx context.Context, req UpdateTemplateRequest) error {
+	return c.write(ctx, templatePath(req.TemplateID), req, false)
+}
+
+// EditTemplate changes a draft version in place, see UpdateTemplate for req.IfMatch
+func (c *Client) EditTemplate(ctx context.Context, req UpdateTemplateRequest) error {
+	return c.write(ctx, "/api/templates/edit/"+url.PathEscape(req.TemplateID), req, true)
+}
+
+// write sends an update of a template. Updates create a new version, a retry
+// after a lost response would create another one unless the If-Match makes it
+// fail as stale, edits in place can always be retried.
+func (c *Client) write(ctx context.Context, path string, req UpdateTemplateRequest, inPlace bool) error {
+	call, err := jsonRequest(http.MethodPut, path, nil, req)
+	if err != nil {
+		return err
+	}
+	call.idempotent = inPlace || req.IfMatch != ""
+	if req.IfMatch != "" {
+		call.header = http.Header{"If-Match": {req.IfMatch}}
+	}
+	return c.call(ctx, call, nil)
+}
+
+// DeleteTemplate moves a version of a template to the trash
+func (c *Client) DeleteTemplate(ctx context.Context, id string, version uint64) error {
+	return c.do(ctx, http.MethodDelete, templatePath(id), nil, map[string]uint64{"version": version}, nil)
+}
+
+func (c *Client) RenderTemplate(ctx context.Context, req RenderTemplateRequest) (*RenderTemplateResponse, error) {
+	var rendered RenderTemplateResponse
+	call, err := jsonRequest(http.MethodPost, templatePath(req.TemplateID)+"/render", nil, req)
+	if err != nil {
+		return nil, err
+	}
+	call.idempotent = true // rendering has no side effects
+	if err := c.call(ctx, call, &rendered); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+// SendTemplate renders a template and sends it, it is never retried
+func (c *Client) SendTemplate(ctx context.Context, req SendTemplateRequest) (*SendTemplateResponse, error) {
+	var sent SendTemplateResponse
+	if err := c.do(ctx, http.MethodPost, templatePath(req.TemplateID)+"/send", nil, req, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// ImportTemplate imports a template from an email provider
+func (c *Client) ImportTemplate(ctx context.Context, req ImportTemplateRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/templates/import", nil, req, nil)
+}
+
+// ExportTemplate exports a template to an email provider
+func (c *Client) ExportTemplate(ctx context.Context, req ExportTemplateRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/templates/export", nil, req, nil)
+}
+
+func templatePath(id string) string {
+	return "/api/templates/" + url.PathEscape(id)
+}
+
+func pageQuery(page, pageSize int) url.Values {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
+	}
+	return query
+}
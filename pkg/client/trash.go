@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListTrash returns the deleted templates of the account, most recently deleted first
+func (c *Client) ListTrash(ctx context.Context, page, pageSize int) (*Page[[]Template], error) {
+	var templates Page[[]Template]
+	if err := c.do(ctx, http.MethodGet, "/api/trash/templates", pageQuery(page, pageSize), nil, &templates); err != nil {
+		return nil, err
+	}
+	return &templates, nil
+}
+
+func (c *Client) RestoreTemplate(ctx context.Context, id string) error {
+	req := request{method: http.MethodPost, path: "/api/trash/templates/" + url.PathEscape(id) + "/restore", idempotent: true}
+	return c.call(ctx, req, nil)
+}
+
+// PurgeTemplate permanently deletes a template from the trash
+func (c *Client) PurgeTemplate(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/trash/templates/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,412 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// The types below mirror the JSON of the API so the client can be used
+// outside this module, the server side models are internal.
+
+// Page is a page of a paginated list
+type Page[T any] struct {
+	Page        int   `json:"page"`
+	PageSize    int   `json:"page_size"`
+	PageCount   int   `json:"page_count"`
+	RecordCount int64 `json:"record_count"`
+	Data        T     `json:"data"`
+}
+
+// Conflict values of ImportBundle, what happens to templates that already exist
+const (
+	ConflictSkip       = "skip"
+	ConflictOverwrite  = "overwrite"
+	ConflictNewVersion = "new_version" // the latest version of the bundle is added as a new version
+)
+
+type Account struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+type Device struct {
+	IP             string `json:"ip"`
+	UserAgent      string `json:"user_agent"`
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version"`
+	OS             string `json:"os"`
+	OSVersion      string `json:"os_version"`
+}
+
+type Session struct {
+	ID         string    `json:"id"`
+	AccountID  string    `json:"account_id"`
+	Device     Device    `json:"device"`
+	Token      string    `json:"token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastActive time.Time `json:"last_active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type LoginResponse struct {
+	Account *Account `json:"account"`
+	Session *Session `json:"session"`
+}
+
+type SignUpRequest struct {
+	Email string `json:"email"`
+}
+
+type VerifyEmailRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type InitiateResetPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Email       string `json:"email"`
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// Key is an API key, Secret is only set when the key is created or rotated
+type Key struct {
+	ID                string     `json:"id"`
+	AccountID         string     `json:"account_id"`
+	Name              string     `json:"name"`
+	Prefix            string     `json:"prefix"`
+	Secret            string     `json:"secret,omitempty"`
+	Scopes            []string   `json:"scopes"` // e.g templates:read, see the API documentation
+	Test              bool       `json:"test"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	LastUsedAt        *time.Time `json:"last_used_at"`
+	LastUsedIP        string     `json:"last_used_ip"`
+	CreatedAt         time.Time  `json:"created_at"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+	RotatedAt         *time.Time `json:"rotated_at"`
+}
+
+// StaleKey is a key that expires or wasn't used, Reason is expired,
+// expiring, unused or never_used
+type StaleKey struct {
+	Key
+	Reason string `json:"reason"`
+}
+
+type CreateKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"` // optional, the key never expires by default
+	Test      bool       `json:"test"`       // sends made with the key are captured instead of delivered
+}
+
+// ContentBlock is content only rendered for recipients whose attributes
+// satisfy Condition
+type ContentBlock struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"`
+	Content   string `json:"content"`
+}
+
+// Template is a version of a template, Status is draft, in_review, approved,
+// published or archived
+type Template struct {
+	ID          string         `json:"id"`
+	AccountID   string         `json:"account_id"`
+	Name        string         `json:"name"`
+	Slug        string         `json:"slug"`
+	Version     uint64         `json:"version"`
+	Location    string         `json:"location"`
+	ContentType string         `json:"content_type"`
+	ContentHash string         `json:"content_hash"`
+	ContentSize int64          `json:"content_size"`
+	Vars        map[string]any `json:"vars"`
+	Blocks      []ContentBlock `json:"blocks"`
+	Active      bool           `json:"active"`
+	Status      string         `json:"status"`
+	PublishedAt *time.Time     `json:"published_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   *time.Time     `json:"deleted_at"`
+}
+
+// ETag identifies the state of the template, it is the IfMatch of updates
+func (t Template) ETag() string {
+	return fmt.Sprintf(`"%d-%x"`, t.Version, t.UpdatedAt.UnixNano())
+}
+
+type GetUploadURLRequest struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+}
+
+type UploadURLResponse struct {
+	AccountID   string    `json:"account_id"`
+	ContentType string    `json:"content_type"`
+	URL         string    `json:"url"`      // signed url to PUT the content to, with the same Content-Type
+	Location    string    `json:"location"` // where the content can be read once uploaded
+	ExpireAt    time.Time `json:"expire_at"`
+}
+
+type CreateTemplateRequest struct {
+	Name        string         `json:"name"`
+	ContentType string         `json:"content_type"`
+	Location    string         `json:"location"`
+	Vars        map[string]any `json:"vars"`
+	Blocks      []ContentBlock `json:"blocks"`
+}
+
+type UpdateTemplateRequest struct {
+	TemplateID string         `json:"template_id"`
+	Location   string         `json:"location"`
+	Vars       map[string]any `json:"vars"`
+	Blocks     []ContentBlock `json:"blocks"` // when omitted the blocks of the existing template are kept
+	IfMatch    string         `json:"-"`      // the ETag the client last saw, the write is rejected if it is stale
+}
+
+type RenderTemplateRequest struct {
+	TemplateID string         `json:"template_id"`
+	Vars       map[string]any `json:"vars"`
+	Attributes map[string]any `json:"attributes"` // recipient attributes the block conditions are evaluated against
+	Draft      bool           `json:"draft"`      // render the given version as is instead of the published one
+}
+
+type RenderTemplateResponse struct {
+	TemplateID  string `json:"template_id"`
+	Version     uint64 `json:"version"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+}
+
+type SendTemplateRequest struct {
+	TemplateID   string         `json:"template_id"`
+	CredentialID string         `json:"credential_id"` // defaults to the first active email credential of the account
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Subject      string         `json:"subject"`
+	Vars         map[string]any `json:"vars"`
+	Attributes   map[string]any `json:"attributes"`
+	Draft        bool           `json:"draft"`
+}
+
+type SendTemplateResponse struct {
+	TemplateID   string `json:"template_id"`
+	Version      uint64 `json:"version"`
+	CredentialID string `json:"credential_id"`
+	Platform     string `json:"platform"`
+	To           string `json:"to"`
+	CapturedID   string `json:"captured_id,omitempty"` // the captured message of sends with a test key
+}
+
+// ImportTemplateRequest imports a template from an email provider
+type ImportTemplateRequest struct {
+	Provider           string         `json:"provider"`
+	ProviderTemplateID string         `json:"provider_template_id"`
+	Credentials        map[string]any `json:"credentials"`
+}
+
+// ExportTemplateRequest exports a template to an email provider
+type ExportTemplateRequest struct {
+	TemplateID  string         `json:"template_id"`
+	Provider    string         `json:"provider"`
+	Credentials map[string]any `json:"credentials"`
+}
+
+type ImportBundleResult struct {
+	Created     []string `json:"created"`
+	Overwritten []string `json:"overwritten"`
+	Versioned   []string `json:"versioned"`
+	Skipped     []string `json:"skipped"`
+}
+
+type ReviewTemplateRequest struct {
+	TemplateID string `json:"template_id"`
+	Comment    string `json:"comment"`
+}
+
+type TemplateReview struct {
+	ID         string    `json:"id"`
+	AccountID  string    `json:"account_id"`
+	TemplateID string    `json:"template_id"`
+	Action     string    `json:"action"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Comment    string    `json:"comment"`
+	Actor      string    `json:"actor"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ScheduleTemplateRequest struct {
+	TemplateID string `json:"template_id"`
+	PublishAt  string `json:"publish_at"` // RFC3339, or a wall clock time (2006-01-02T15:04:05) in Timezone
+	RevertAt   string `json:"revert_at"`  // optional, same format as PublishAt
+	Timezone   string `json:"timezone"`   // IANA name e.g Africa/Lagos, defaults to UTC
+}
+
+type TemplateSchedule struct {
+	ID                 string     `json:"id"`
+	AccountID          string     `json:"account_id"`
+	TemplateID         string     `json:"template_id"`
+	Slug               string     `json:"slug"`
+	PublishAt          time.Time  `json:"publish_at"`
+	RevertAt           *time.Time `json:"revert_at"`
+	Timezone           string     `json:"timezone"`
+	PreviousTemplateID string     `json:"previous_template_id"`
+	Status             string     `json:"status"`
+	Error              string     `json:"error,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type CapturedMessage struct {
+	ID           string    `json:"id"`
+	AccountID    string    `json:"account_id"`
+	KeyID        string    `json:"key_id"`
+	TemplateID   string    `json:"template_id"`
+	Version      uint64    `json:"version"`
+	CredentialID string    `json:"credential_id"`
+	Platform     string    `json:"platform"`
+	From         string    `json:"from"`
+	To           string    `json:"to"`
+	Subject      string    `json:"subject"`
+	HTMLContent  string    `json:"html_content,omitempty"`
+	TextContent  string    `json:"text_content,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Credential of an email, sms or push provider
+type Credential struct {
+	ID        string         `json:"id"`
+	AccountID string         `json:"account_id"`
+	Platform  string         `json:"platform"`
+	Type      string         `json:"type"`
+	IsActive  int            `json:"is_active"`
+	Meta      map[string]any `json:"meta"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt *time.Time     `json:"deleted_at"`
+}
+
+type CredentialInput struct {
+	ID       string         `json:"id"`
+	Platform string         `json:"platform"`
+	Type     string         `json:"type"` // email, sms or push
+	Meta     map[string]any `json:"meta"`
+}
+
+// Webhook is an endpoint events are delivered to, Secret is only set when
+// the webhook is created
+type Webhook struct {
+	ID          string     `json:"id"`
+	AccountID   string     `json:"account_id"`
+	URL         string     `json:"url"`
+	Description string     `json:"description"`
+	Events      []string   `json:"events"`
+	Secret      string     `json:"secret,omitempty"`
+	Active      bool       `json:"active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"`
+}
+
+// UpdateWebhookRequest changes the fields that are set
+type UpdateWebhookRequest struct {
+	WebhookID   string   `json:"webhook_id"`
+	URL         string   `json:"url"`
+	Description *string  `json:"description"`
+	Events      []string `json:"events"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookDelivery is an attempt to deliver an event, Status is pending,
+// succeeded or failed
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	AccountID      string     `json:"account_id"`
+	WebhookID      string     `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	Error          string     `json:"error,omitempty"`
+	RedeliveryOf   string     `json:"redelivery_of,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type ListDeliveriesRequest struct {
+	WebhookID string
+	Status    string // optional
+	Page      int
+	PageSize  int
+}
+
+// AnalyticsRequest filters the analytics, every field is optional
+type AnalyticsRequest struct {
+	From       time.Time
+	To         time.Time
+	TemplateID string
+	Kind       string // render or send
+	Limit      int
+}
+
+type UsageSummary struct {
+	Since        time.Time `json:"since"`
+	Until        time.Time `json:"until"` // exclusive
+	Renders      int64     `json:"renders"`
+	Sends        int64     `json:"sends"`
+	Failures     int64     `json:"failures"`
+	ErrorRate    float64   `json:"error_rate"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+}
+
+type DailyStat struct {
+	Day          time.Time `json:"day"`
+	Kind         string    `json:"kind"`
+	Count        int64     `json:"count"`
+	Failures     int64     `json:"failures"`
+	ErrorRate    float64   `json:"error_rate"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+}
+
+type TemplateStat struct {
+	Slug         string    `json:"slug"`
+	TemplateID   string    `json:"template_id"` // the most recently used version
+	Count        int64     `json:"count"`
+	Failures     int64     `json:"failures"`
+	ErrorRate    float64   `json:"error_rate"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+}
+
+type CacheStats struct {
+	Entries     int   `json:"entries"`
+	Bytes       int64 `json:"bytes"`
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Evictions   int64 `json:"evictions"`
+	Expirations int64 `json:"expirations"`
+	Removals    int64 `json:"removals"`
+}
+
+type ServerStats struct {
+	GRPC    bool       `json:"grpc"`
+	Version string     `json:"version"`
+	Open    bool       `json:"open"`
+	Cache   CacheStats `json:"cache"`
+}
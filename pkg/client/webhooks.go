@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateWebhook returns the webhook with its signing secret, the only time the secret is shown
+func (c *Client) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (*Webhook, error) {
+	var webhook Webhook
+	if err := c.do(ctx, http.MethodPost, "/api/webhooks", nil, req, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := c.do(ctx, http.MethodGet, "/api/webhooks", nil, nil, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (c *Client) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	var webhook Webhook
+	if err := c.do(ctx, http.MethodGet, webhookPath(id), nil, nil, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (c *Client) UpdateWebhook(ctx context.Context, req UpdateWebhookRequest) (*Webhook, error) {
+	call, err := jsonRequest(http.MethodPut, webhookPath(req.WebhookID), nil, req)
+	if err != nil {
+		return nil, err
+	}
+	call.idempotent = true // changes the webhook in place
+	var webhook Webhook
+	if err := c.call(ctx, call, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, webhookPath(id), nil, nil, nil)
+}
+
+func (c *Client) ListWebhookDeliveries(ctx context.Context, req ListDeliveriesRequest) (*Page[[]WebhookDelivery], error) {
+	query := pageQuery(req.Page, req.PageSize)
+	if req.Status != "" {
+		query.Set("status", string(req.Status))
+	}
+	var deliveries Page[[]WebhookDelivery]
+	if err := c.do(ctx, http.MethodGet, webhookPath(req.WebhookID)+"/deliveries", query, nil, &deliveries); err != nil {
+		return nil, err
+	}
+	return &deliveries, nil
+}
+
+// RedeliverWebhook sends a delivery again and returns the new delivery
+func (c *Client) RedeliverWebhook(ctx context.Context, deliveryID string) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	path := "/api/webhooks/deliveries/" + url.PathEscape(deliveryID) + "/redeliver"
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func webhookPath(id string) string {
+	return "/api/webhooks/" + url.PathEscape(id)
+}
@@ -1,6 +1,7 @@
 package email
 
 import (
+	"context"
 	"errors"
 	"template-manager/internal/entity"
 )
@@ -15,6 +16,11 @@ type Provider interface {
 	GetTemplates(input *TemplateQuery) (*TemplateResponse, error)
 }
 
+// Sender delivers a fully rendered message through the provider
+type Sender interface {
+	SendMessage(ctx context.Context, input *MessageInput) error
+}
+
 type Template struct {
 	ID          int64       `mailjet:"ID" mailgun:"-" json:"id,omitempty"`
 	Name        string      `mailjet:"Name" mailgun:"name" json:"name,omitempty"`
@@ -78,3 +84,12 @@ type TemplateInput struct {
 	// Active    bool           `json:"active"`
 	AuthCredential
 }
+
+type MessageInput struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Subject     string `json:"subject"`
+	HTMLContent string `json:"html_content"`
+	TextContent string `json:"text_content"`
+	AuthCredential
+}
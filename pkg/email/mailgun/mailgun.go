@@ -139,3 +139,18 @@ func (m *Mailgun) DeleteTemplate(input *email.TemplateQuery) error {
 	}
 	return client.DeleteTemplate(m.ctx, input.Name)
 }
+
+func (m *Mailgun) SendMessage(ctx context.Context, input *email.MessageInput) error {
+	client, err := m.initMailgunClient(&input.AuthCredential)
+	if err != nil {
+		return err
+	}
+	message := client.NewMessage(input.From, input.Subject, input.TextContent, input.To)
+	if input.HTMLContent != "" {
+		message.SetHtml(input.HTMLContent)
+	}
+	_, _, err = client.Send(ctx, message)
+	return err
+}
+
+var _ email.Sender = (*Mailgun)(nil)
@@ -1,6 +1,8 @@
 package mailjet
 
 import (
+	"context"
+
 	jsoniter "github.com/json-iterator/go"
 	"template-manager/pkg/email"
 
@@ -253,3 +255,29 @@ func (m *Mailjet) UpdateTemplateContent(input *email.TemplateInput) error {
 	}
 	return nil
 }
+
+func (m *Mailjet) SendMessage(ctx context.Context, input *email.MessageInput) error {
+	client, err := m.initMailjetClient(&input.AuthCredential)
+	if err != nil {
+		return err
+	}
+	messages := mailjet.MessagesV31{Info: []mailjet.InfoMessagesV31{
+		{
+			From: &mailjet.RecipientV31{
+				Email: input.From,
+			},
+			To: &mailjet.RecipientsV31{
+				mailjet.RecipientV31{
+					Email: input.To,
+				},
+			},
+			Subject:  input.Subject,
+			HTMLPart: input.HTMLContent,
+			TextPart: input.TextContent,
+		},
+	}}
+	_, err = client.SendMailV31(&messages)
+	return err
+}
+
+var _ email.Sender = (*Mailjet)(nil)
@@ -0,0 +1,443 @@
+// Package expression implements a small, side-effect free condition language
+// used to decide which content blocks of a template apply to a recipient.
+//
+//	country == "NG" && plan in ["pro", "team"]
+//	days_since(signup_date) < 30 or not verified
+//
+// Expressions can only read the attributes they are given, call a fixed set of
+// built-in functions and compare values; they cannot loop, assign or reach
+// anything outside of the attribute map.
+package expression
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxLength is the longest condition accepted by Compile
+const MaxLength = 1024
+
+var ErrTooLong = fmt.Errorf("expression is longer than %d characters", MaxLength)
+
+// Expression is a compiled condition, safe for concurrent use
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile parses src into an Expression. An empty src compiles to an
+// expression that always evaluates to true.
+func Compile(src string) (*Expression, error) {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return &Expression{root: literal{value: true}}, nil
+	}
+	if len(src) > MaxLength {
+		return nil, ErrTooLong
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+	}
+	return &Expression{src: src, root: root}, nil
+}
+
+// Validate reports whether src is a valid expression
+func Validate(src string) error {
+	_, err := Compile(src)
+	return err
+}
+
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against attrs and reports whether it holds.
+// Attributes that are missing evaluate to null.
+func (e *Expression) Eval(attrs map[string]any) (bool, error) {
+	v, err := e.root.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+type node interface {
+	eval(attrs map[string]any) (any, error)
+}
+
+type literal struct {
+	value any
+}
+
+func (l literal) eval(map[string]any) (any, error) {
+	return l.value, nil
+}
+
+// ident looks up an attribute, dots walk into nested objects e.g. address.country
+type ident struct {
+	name string
+}
+
+func (i ident) eval(attrs map[string]any) (any, error) {
+	var current any = attrs
+	for _, part := range strings.Split(i.name, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			rv := reflect.ValueOf(current)
+			if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+				return nil, nil
+			}
+			value := rv.MapIndex(reflect.ValueOf(part).Convert(rv.Type().Key()))
+			if !value.IsValid() {
+				return nil, nil
+			}
+			current = value.Interface()
+			continue
+		}
+		current = m[part]
+	}
+	return normalize(current), nil
+}
+
+type list struct {
+	items []node
+}
+
+func (l list) eval(attrs map[string]any) (any, error) {
+	values := make([]any, 0, len(l.items))
+	for _, item := range l.items {
+		v, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+func (u unary) eval(attrs map[string]any) (any, error) {
+	v, err := u.x.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (b binary) eval(attrs map[string]any) (any, error) {
+	left, err := b.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	// short circuit the logical operators
+	switch b.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := b.right.eval(attrs)
+		return truthy(right), err
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := b.right.eval(attrs)
+		return truthy(right), err
+	}
+
+	right, err := b.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left), nil
+	}
+
+	cmp, ok := compare(left, right)
+	if !ok {
+		// values that cannot be ordered (e.g. a missing attribute) never match
+		return false, nil
+	}
+	switch b.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", b.op)
+}
+
+type call struct {
+	name string
+	fn   func(args []any) (any, error)
+	args []node
+}
+
+func (c call) eval(attrs map[string]any) (any, error) {
+	args := make([]any, 0, len(c.args))
+	for _, arg := range c.args {
+		v, err := arg.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	v, err := c.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.name, err)
+	}
+	return v, nil
+}
+
+type function struct {
+	arity int
+	call  func(args []any) (any, error)
+}
+
+// functions is the complete set of callables available to an expression
+var functions = map[string]function{
+	"lower": {arity: 1, call: func(args []any) (any, error) {
+		return strings.ToLower(toString(args[0])), nil
+	}},
+	"upper": {arity: 1, call: func(args []any) (any, error) {
+		return strings.ToUpper(toString(args[0])), nil
+	}},
+	"len": {arity: 1, call: func(args []any) (any, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(len(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, errors.New("argument has no length")
+	}},
+	"contains": {arity: 2, call: func(args []any) (any, error) {
+		return contains(args[0], args[1]), nil
+	}},
+	"starts_with": {arity: 2, call: func(args []any) (any, error) {
+		return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+	}},
+	"ends_with": {arity: 2, call: func(args []any) (any, error) {
+		return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+	}},
+	"days_since": {arity: 1, call: func(args []any) (any, error) {
+		t, ok := toTime(args[0])
+		if !ok {
+			return nil, nil
+		}
+		return math.Floor(time.Since(t).Hours() / 24), nil
+	}},
+}
+
+// normalize converts attribute values into the handful of types the
+// evaluator works with: nil, bool, float64, string, time.Time, []any and map[string]any
+func normalize(v any) any {
+	switch x := v.(type) {
+	case nil, bool, float64, string, time.Time, []any, map[string]any:
+		return x
+	case json.Number:
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+		return x.String()
+	case *time.Time:
+		if x == nil {
+			return nil
+		}
+		return *x
+	case []string:
+		values := make([]any, 0, len(x))
+		for _, s := range x {
+			values = append(values, s)
+		}
+		return values
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return fmt.Sprint(v)
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	case []any:
+		return len(x) > 0
+	}
+	return true
+}
+
+func toString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		return x.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+func toNumber(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+var timeLayouts = []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+func toTime(v any) (time.Time, bool) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, true
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, x); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func equal(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders two values. Numbers compare numerically, dates chronologically
+// and strings lexically; ok is false when the values cannot be ordered.
+func compare(a, b any) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		x, okA := toNumber(a)
+		y, okB := toNumber(b)
+		if !okA || !okB {
+			return 0, false
+		}
+		return order(x < y, x > y), true
+	}
+	_, aTime := a.(time.Time)
+	_, bTime := b.(time.Time)
+	as, aStr := a.(string)
+	bs, bStr := b.(string)
+	if aTime || bTime || (aStr && bStr) {
+		x, okA := toTime(a)
+		y, okB := toTime(b)
+		if okA && okB {
+			return order(x.Before(y), x.After(y)), true
+		}
+	}
+	if aStr && bStr {
+		return strings.Compare(as, bs), true
+	}
+	if x, ok := a.(bool); ok {
+		if y, ok := b.(bool); ok && x == y {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+func order(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// contains reports whether needle is an element of haystack, a substring of it
+// when haystack is a string, or a key of it when haystack is an object
+func contains(haystack, needle any) bool {
+	switch h := haystack.(type) {
+	case []any:
+		for _, item := range h {
+			if equal(item, needle) {
+				return true
+			}
+		}
+	case string:
+		return needle != nil && strings.Contains(h, toString(needle))
+	case map[string]any:
+		_, ok := h[toString(needle)]
+		return ok
+	}
+	return false
+}
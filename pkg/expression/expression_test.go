@@ -0,0 +1,98 @@
+package expression
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEval(t *testing.T) {
+	attrs := map[string]any{
+		"country":     "NG",
+		"plan":        "pro",
+		"age":         30,
+		"score":       7.5,
+		"verified":    false,
+		"tags":        []string{"beta", "vip"},
+		"address":     map[string]string{"city": "Lagos"},
+		"signup_date": time.Now().Add(-48 * time.Hour).Format(time.RFC3339),
+		"prénom":      "Zoë",
+	}
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{src: ``, want: true},
+		{src: `country == "NG" && plan in ["pro", "team"]`, want: true},
+		{src: `country == "NG" && plan in ["team"]`, want: false},
+		{src: `age >= 30 and score < 8`, want: true},
+		{src: `age == "30"`, want: true},
+		{src: `not verified`, want: true},
+		{src: `"vip" in tags`, want: true},
+		{src: `"free" not in tags`, want: true},
+		{src: `address.city == "Lagos"`, want: true},
+		{src: `missing == null`, want: true},
+		{src: `missing > 1`, want: false},
+		{src: `days_since(signup_date) < 30`, want: true},
+		{src: `days_since(signup_date) == 2`, want: true},
+		{src: `upper(country) == "NG" && len(tags) == 2`, want: true},
+		{src: `starts_with(plan, "p") && ends_with(plan, "o")`, want: true},
+		{src: `contains(country, "G")`, want: true},
+		{src: `prénom == "Zoë"`, want: true},
+		{src: `len(prénom) == 4`, want: true}, // bytes, Zoë is 4 bytes
+		{src: `"2024-01-01" < "2024-02-01"`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			got, err := e.Eval(attrs)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Eval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		src   string
+		attrs map[string]any
+		err   string
+	}{
+		{src: `len(n) > 1`, attrs: map[string]any{"n": 5}, err: "len: argument has no length"},
+		{src: `a && len(b)`, attrs: map[string]any{"a": true, "b": true}, err: "len: argument has no length"},
+		{src: `not len(b)`, attrs: map[string]any{"b": 1.5}, err: "len: argument has no length"},
+		{src: `[len(b)] == []`, attrs: map[string]any{"b": false}, err: "len: argument has no length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			_, err = e.Eval(tt.attrs)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Eval error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestEvalShortCircuit(t *testing.T) {
+	// the right side would fail, it must not be evaluated
+	for _, src := range []string{`false && len(n)`, `true || len(n)`} {
+		e, err := Compile(src)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", src, err)
+		}
+		if _, err := e.Eval(map[string]any{"n": 1}); err != nil {
+			t.Errorf("Eval(%q): %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOperator
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// operators are matched longest first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!"}
+
+// tokenize splits src into tokens, positions are byte offsets into src
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		ch, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case ch == utf8.RuneError && size == 1:
+			return nil, fmt.Errorf("invalid UTF-8 at position %d", i)
+		case unicode.IsSpace(ch):
+			i += size
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokLParen, value: "(", pos: i})
+			i++
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokRParen, value: ")", pos: i})
+			i++
+		case ch == '[':
+			tokens = append(tokens, token{kind: tokLBracket, value: "[", pos: i})
+			i++
+		case ch == ']':
+			tokens = append(tokens, token{kind: tokRBracket, value: "]", pos: i})
+			i++
+		case ch == ',':
+			tokens = append(tokens, token{kind: tokComma, value: ",", pos: i})
+			i++
+		case ch == '"' || ch == '\'':
+			value, next, err := readString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, value: value, pos: i})
+			i = next
+		case isDigit(ch) || (ch == '-' && i+1 < len(src) && isDigit(rune(src[i+1])) && expectsOperand(tokens)):
+			start := i
+			i++
+			for i < len(src) && (isDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, value: src[start:i], pos: start})
+		case unicode.IsLetter(ch) || ch == '_':
+			start := i
+			for i < len(src) {
+				r, n := utf8.DecodeRuneInString(src[i:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+					break
+				}
+				i += n
+			}
+			tokens = append(tokens, token{kind: tokIdent, value: src[start:i], pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokOperator, value: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", ch, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// isDigit only accepts ASCII digits, the ones strconv.ParseFloat reads
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+// expectsOperand reports whether a leading '-' should be read as the sign of a number
+func expectsOperand(tokens []token) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	switch last := tokens[len(tokens)-1]; last.kind {
+	case tokOperator, tokLParen, tokLBracket, tokComma:
+		return true
+	case tokIdent:
+		return isKeyword(last.value)
+	}
+	return false
+}
+
+func readString(src string, start int) (string, int, error) {
+	quote := rune(src[start])
+	var b strings.Builder
+	for i := start + 1; i < len(src); {
+		ch, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case ch == utf8.RuneError && size == 1:
+			return "", 0, fmt.Errorf("invalid UTF-8 at position %d", i)
+		case ch == '\\':
+			i += size
+			if i >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string at position %d", start)
+			}
+			ch, size = utf8.DecodeRuneInString(src[i:])
+			b.WriteRune(ch)
+		case ch == quote:
+			return b.String(), i + size, nil
+		default:
+			b.WriteRune(ch)
+		}
+		i += size
+	}
+	return "", 0, fmt.Errorf("unterminated string at position %d", start)
+}
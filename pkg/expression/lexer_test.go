@@ -0,0 +1,122 @@
+package expression
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		tokens []token
+	}{
+		{
+			name: "comparison",
+			src:  `country == "NG"`,
+			tokens: []token{
+				{kind: tokIdent, value: "country", pos: 0},
+				{kind: tokOperator, value: "==", pos: 8},
+				{kind: tokString, value: "NG", pos: 11},
+				{kind: tokEOF, pos: 15},
+			},
+		},
+		{
+			name: "list and negative number",
+			src:  `x in [-1, 2.5]`,
+			tokens: []token{
+				{kind: tokIdent, value: "x", pos: 0},
+				{kind: tokIdent, value: "in", pos: 2},
+				{kind: tokLBracket, value: "[", pos: 5},
+				{kind: tokNumber, value: "-1", pos: 6},
+				{kind: tokComma, value: ",", pos: 8},
+				{kind: tokNumber, value: "2.5", pos: 10},
+				{kind: tokRBracket, value: "]", pos: 13},
+				{kind: tokEOF, pos: 14},
+			},
+		},
+		{
+			name:   "minus after operand is not a sign",
+			src:    `a -1`,
+			tokens: nil, // '-' alone isn't an operator
+		},
+		{
+			name: "non-ASCII identifier",
+			src:  `prénom == 'Zoë'`,
+			tokens: []token{
+				{kind: tokIdent, value: "prénom", pos: 0},
+				{kind: tokOperator, value: "==", pos: 8},
+				{kind: tokString, value: "Zoë", pos: 11},
+				{kind: tokEOF, pos: 17},
+			},
+		},
+		{
+			name: "CJK identifier and dotted path",
+			src:  `用户.城市 != "東京"`,
+			tokens: []token{
+				{kind: tokIdent, value: "用户.城市", pos: 0},
+				{kind: tokOperator, value: "!=", pos: 14},
+				{kind: tokString, value: "東京", pos: 17},
+				{kind: tokEOF, pos: 25},
+			},
+		},
+		{
+			name: "escapes",
+			src:  `"a\"b\\c\é"`,
+			tokens: []token{
+				{kind: tokString, value: `a"b\cé`, pos: 0},
+				{kind: tokEOF, pos: 12},
+			},
+		},
+		{
+			name: "non-ASCII space",
+			src:  "a &&　b",
+			tokens: []token{
+				{kind: tokIdent, value: "a", pos: 0},
+				{kind: tokOperator, value: "&&", pos: 3},
+				{kind: tokIdent, value: "b", pos: 8},
+				{kind: tokEOF, pos: 9},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.src)
+			if tt.tokens == nil {
+				if err == nil {
+					t.Fatalf("tokenize(%q) = %v, want an error", tt.src, tokens)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tokenize(%q): %v", tt.src, err)
+			}
+			if !reflect.DeepEqual(tokens, tt.tokens) {
+				t.Fatalf("tokenize(%q) =\n%v\nwant\n%v", tt.src, tokens, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{src: `"open`, err: "unterminated string at position 0"},
+		{src: `'ends with \`, err: "unterminated string at position 0"},
+		{src: `a @ b`, err: "unexpected character '@' at position 2"},
+		{src: `a == «b»`, err: "unexpected character '«' at position 5"},
+		{src: "a == \xff", err: "invalid UTF-8 at position 5"},
+		{src: "\"bad \xc3\"", err: "invalid UTF-8 at position 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := tokenize(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("tokenize(%q) error = %v, want %q", tt.src, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// maxDepth bounds the nesting of an expression so a hostile condition
+// cannot blow the stack while parsing or evaluating
+const maxDepth = 32
+
+var keywords = map[string]bool{
+	"and":   true,
+	"or":    true,
+	"not":   true,
+	"in":    true,
+	"true":  true,
+	"false": true,
+	"null":  true,
+}
+
+func isKeyword(s string) bool {
+	return keywords[s]
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(kind tokenKind, values ...string) bool {
+	t := p.peek()
+	if t.kind != kind {
+		return false
+	}
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if t.value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, value string) error {
+	t := p.next()
+	if t.kind != kind {
+		return fmt.Errorf("expected %q at position %d", value, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return fmt.Errorf("expression is nested too deeply (max %d)", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// or := and (("||" | "or") and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tokOperator, "||") || p.is(tokIdent, "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+// and := not (("&&" | "and") not)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tokOperator, "&&") || p.is(tokIdent, "and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+// not := ("!" | "not") not | comparison
+func (p *parser) parseNot() (node, error) {
+	if p.is(tokOperator, "!") || p.is(tokIdent, "not") {
+		p.next()
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: "!", x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+// comparison := primary ((op | "in" | "not" "in") primary)?
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.is(tokOperator, "==", "!=", "<", "<=", ">", ">="):
+		op := p.next().value
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op: op, left: left, right: right}, nil
+	case p.is(tokIdent, "in"):
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op: "in", left: left, right: right}, nil
+	case p.is(tokIdent, "not"):
+		p.next()
+		if !p.is(tokIdent, "in") {
+			return nil, fmt.Errorf("expected \"in\" after \"not\" at position %d", p.peek().pos)
+		}
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: "!", x: binary{op: "in", left: left, right: right}}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return literal{value: t.value}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.value, t.pos)
+		}
+		return literal{value: f}, nil
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(tokRParen, ")")
+	case tokLBracket:
+		var items []node
+		for !p.is(tokRBracket) {
+			item, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if !p.is(tokComma) {
+				break
+			}
+			p.next()
+		}
+		return list{items: items}, p.expect(tokRBracket, "]")
+	case tokIdent:
+		switch t.value {
+		case "true":
+			return literal{value: true}, nil
+		case "false":
+			return literal{value: false}, nil
+		case "null":
+			return literal{value: nil}, nil
+		}
+		if isKeyword(t.value) {
+			return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+		}
+		if p.is(tokLParen) {
+			return p.parseCall(t)
+		}
+		return ident{name: t.value}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.value]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.value, name.pos)
+	}
+	p.next() // (
+	var args []node
+	for !p.is(tokRParen) {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.is(tokComma) {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(tokRParen, ")"); err != nil {
+		return nil, err
+	}
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d", name.value, fn.arity, len(args))
+	}
+	return call{name: name.value, fn: fn.call, args: args}, nil
+}
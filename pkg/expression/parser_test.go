@@ -0,0 +1,55 @@
+package expression
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	valid := []string{
+		``,
+		`verified`,
+		`country == "NG" && plan in ["pro", "team"]`,
+		`days_since(signup_date) < 30 or not verified`,
+		`!(a || b) and c != null`,
+		`x not in [1, 2, 3]`,
+		`lower(name) == "ada"`,
+		`[] == []`,
+		`prénom == "Zoë"`,
+		strings.Repeat("(", maxDepth-1) + "a" + strings.Repeat(")", maxDepth-1),
+	}
+	for _, src := range valid {
+		if _, err := Compile(src); err != nil {
+			t.Errorf("Compile(%q): %v", src, err)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{src: `a ==`, err: "unexpected end of expression"},
+		{src: `(a`, err: `expected ")" at position 2`},
+		{src: `[1, 2`, err: `expected "]" at position 5`},
+		{src: `a b`, err: `unexpected "b" at position 2`},
+		{src: `a not b`, err: `expected "in" after "not" at position 6`},
+		{src: `and == 1`, err: `unexpected "and" at position 0`},
+		{src: `nope(a)`, err: `unknown function "nope" at position 0`},
+		{src: `lower(a, b)`, err: "lower expects 1 argument(s), got 2"},
+		{src: `a == 1.2.3`, err: `invalid number "1.2.3" at position 5`},
+		{src: `a == ==`, err: `unexpected "==" at position 5`},
+		{src: strings.Repeat("(", maxDepth+1) + "a" + strings.Repeat(")", maxDepth+1), err: "nested too deeply"},
+		{src: strings.Repeat("!", maxDepth+1) + "a", err: "nested too deeply"},
+		{src: strings.Repeat("a", MaxLength+1), err: ErrTooLong.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Compile(%q) error = %v, want %q", tt.src, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package netguard keeps outgoing requests to user supplied urls away from
+// the internal network: loopback, private, link-local (which includes the
+// cloud metadata endpoints) and other non-public addresses are refused.
+//
+// Addresses are checked when a url is accepted, with CheckURL, and again when
+// the connection is made by the client of NewClient, so a host that resolves
+// differently the second time can't get through.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrForbiddenAddress = errors.New("url points to a private or reserved address")
+	ErrInvalidURL       = errors.New("url must be an absolute http or https url")
+)
+
+// reserved are the ranges that aren't reachable on the public internet, on
+// top of the ones netip.Addr already classifies
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT, e.g alibaba cloud metadata
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, includes broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, embeds IPv4 addresses
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// Allowed reports whether addr is a public unicast address
+func Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL makes sure raw is an http(s) url whose host only resolves to
+// allowed addresses
+func CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		if !Allowed(addr) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !Allowed(addr) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// control refuses connections to addresses that aren't allowed, it runs after
+// the host was resolved so address is always an ip
+func control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !Allowed(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// NewClient returns an http client that only connects to allowed addresses,
+// doesn't go through the proxy of the environment and doesn't follow
+// redirects, a redirect is returned as the response
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: control,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,97 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // aws, gcp and azure metadata
+		{"100.100.100.200", false}, // alibaba cloud metadata
+		{"fd00:ec2::254", false},   // aws metadata over IPv6
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+	for _, tt := range tests {
+		if got := Allowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url string
+		err error
+	}{
+		{"https://8.8.8.8/hook", nil},
+		{"http://127.0.0.1:8080/", ErrForbiddenAddress},
+		{"http://[::1]/", ErrForbiddenAddress},
+		{"http://169.254.169.254/latest/meta-data/", ErrForbiddenAddress},
+		{"http://localhost/", ErrForbiddenAddress},
+		{"ftp://8.8.8.8/", ErrInvalidURL},
+		{"file:///etc/passwd", ErrInvalidURL},
+		{"/relative", ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		if err := CheckURL(context.Background(), tt.url); !errors.Is(err, tt.err) {
+			t.Errorf("CheckURL(%q) = %v, want %v", tt.url, err, tt.err)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Get(%s) error = %v, want %v", server.URL, err, ErrForbiddenAddress)
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	client := NewClient(time.Second)
+	// allow the test server to be reached, redirects must still be returned as is
+	client.Transport = http.DefaultTransport
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect was followed")
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer server.Close()
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+}
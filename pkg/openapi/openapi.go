@@ -0,0 +1,361 @@
+// Package openapi builds OpenAPI 3 documents from a table of operations, the
+// schemas of request and response bodies are generated from their Go types
+// following the encoding/json rules.
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	Responses       map[string]*Response      `json:"responses,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lowercase method
+type PathItem map[string]*OperationObject
+
+type OperationObject struct {
+	Tags        []string               `json:"tags,omitempty"`
+	Summary     string                 `json:"summary,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	OperationID string                 `json:"operationId,omitempty"`
+	Parameters  []Parameter            `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]*Response   `json:"responses"`
+	Security    *[]map[string][]string `json:"security,omitempty"` // an empty list makes the operation public
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // true or a *Schema
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Example              any                `json:"example,omitempty"`
+}
+
+// Operation describes a route. Request and Response are values of the types
+// of the bodies, nil when there is none.
+type Operation struct {
+	Method      string
+	Path        string // as registered with fiber e.g /api/templates/:id
+	Tag         string
+	Summary     string
+	Description string
+	Public      bool     // no authentication
+	Security    []string // names of the security schemes that can call it, all of them by default
+	Scope       string   // permission the credentials need, added to the description
+	Query       []Parameter
+	Headers     []Parameter
+
+	Request            any
+	RequestContentType string // application/json by default
+	Response           any    // the data of the success envelope
+	// ResponseContentType is set for responses that are not the JSON envelope
+	// e.g application/zip, Response is then the schema of the raw body if any
+	ResponseContentType string
+	Errors              []int // status codes of the error responses besides 401 and 422
+}
+
+// Build creates the document of ops, the operations of every path are in the
+// order of ops
+func Build(info Info, ops []Operation, schemes map[string]SecurityScheme) *Document {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         g.schemas,
+			Responses:       errorResponses(),
+			SecuritySchemes: schemes,
+		},
+	}
+	for name := range schemes {
+		doc.Security = append(doc.Security, map[string][]string{name: {}})
+	}
+	sort.Slice(doc.Security, func(i, j int) bool { return firstKey(doc.Security[i]) < firstKey(doc.Security[j]) })
+	g.schemas["Error"] = errorSchema()
+	g.schemas["ValidationError"] = validationErrorSchema()
+
+	tags := map[string]bool{}
+	for _, op := range ops {
+		path, params := Path(op.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = g.operation(op, params)
+
+		if op.Tag != "" && !tags[op.Tag] {
+			tags[op.Tag] = true
+			doc.Tags = append(doc.Tags, Tag{Name: op.Tag})
+		}
+	}
+	return doc
+}
+
+// Has reports whether the document describes the route, path is as registered with fiber
+func (d *Document) Has(method, path string) bool {
+	openapiPath, _ := Path(path)
+	item, ok := d.Paths[openapiPath]
+	if !ok {
+		return false
+	}
+	_, ok = item[strings.ToLower(method)]
+	return ok
+}
+
+// Path converts a fiber path to an OpenAPI one and returns its parameters,
+// :name becomes {name} and a trailing * becomes {path}
+func Path(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name := strings.TrimSuffix(segment[1:], "?")
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		case segment == "*":
+			params = append(params, "path")
+			segments[i] = "{path}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func (g *generator) operation(op Operation, params []string) *OperationObject {
+	o := &OperationObject{
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: operationID(op),
+		Responses:   map[string]*Response{},
+	}
+	if op.Tag != "" {
+		o.Tags = []string{op.Tag}
+	}
+	if op.Public {
+		o.Security = &[]map[string][]string{}
+	} else if len(op.Security) > 0 {
+		security := make([]map[string][]string, len(op.Security))
+		for i, name := range op.Security {
+			security[i] = map[string][]string{name: {}}
+		}
+		o.Security = &security
+		o.Responses["403"] = &Response{Ref: "#/components/responses/Forbidden"}
+	}
+	if op.Scope != "" {
+		o.Description = strings.TrimSpace(o.Description + " Requires the `" + op.Scope + "` scope.")
+		o.Responses["403"] = &Response{Ref: "#/components/responses/Forbidden"}
+	}
+
+	for _, name := range params {
+		o.Parameters = append(o.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, p := range op.Query {
+		p.In = "query"
+		o.Parameters = append(o.Parameters, p)
+	}
+	for _, p := range op.Headers {
+		p.In = "header"
+		o.Parameters = append(o.Parameters, p)
+	}
+
+	if op.Request != nil {
+		contentType := op.RequestContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		o.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: g.requestSchema(op.Request)}},
+		}
+	}
+
+	if op.ResponseContentType != "" {
+		success := &Response{Description: "OK"}
+		if op.Response != nil {
+			success.Content = map[string]MediaType{op.ResponseContentType: {Schema: g.schemaOf(op.Response)}}
+		} else {
+			success.Content = map[string]MediaType{op.ResponseContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+		}
+		o.Responses["200"] = success
+	} else {
+		o.Responses["200"] = &Response{
+			Description: "OK",
+			Content:     map[string]MediaType{"application/json": {Schema: g.envelope(op.Response)}},
+		}
+	}
+
+	if op.Request != nil || len(op.Query) > 0 || len(params) > 0 {
+		o.Responses["400"] = &Response{Ref: "#/components/responses/BadRequest"}
+	}
+	if !op.Public {
+		o.Responses["401"] = &Response{Ref: "#/components/responses/Unauthorized"}
+	}
+	for _, status := range op.Errors {
+		o.Responses[strconv.Itoa(status)] = &Response{Ref: "#/components/responses/" + strings.ReplaceAll(http.StatusText(status), " ", "")}
+	}
+	o.Responses["422"] = &Response{Ref: "#/components/responses/Unprocessable"}
+	return o
+}
+
+// envelope is the schema of the JSON written by rest.HandleSuccess
+func (g *generator) envelope(data any) *Schema {
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status":  {Type: "boolean", Example: true},
+			"message": {Type: "string"},
+		},
+		Required: []string{"status", "message"},
+	}
+	if data != nil {
+		s.Properties["data"] = g.schemaOf(data)
+	}
+	return s
+}
+
+func errorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status":  {Type: "boolean", Example: false},
+			"message": {Type: "string"},
+		},
+		Required: []string{"status", "message"},
+	}
+}
+
+// validationErrorSchema is the body of 400 responses, failed validations
+// report the error of every field
+func validationErrorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status": {Type: "boolean", Example: false},
+			"message": {
+				Description: "the errors by field name when validation failed, a description of the error otherwise",
+				OneOf: []*Schema{
+					{Type: "string"},
+					{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+				},
+			},
+		},
+		Required: []string{"status", "message"},
+	}
+}
+
+func errorResponses() map[string]*Response {
+	ref := func(name string) map[string]MediaType {
+		return map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/" + name}}}
+	}
+	return map[string]*Response{
+		"BadRequest":         {Description: "The request is malformed or failed validation", Content: ref("ValidationError")},
+		"Unauthorized":       {Description: "The credentials are missing or invalid, the body is empty"},
+		"Forbidden":          {Description: "The request is not allowed", Content: ref("Error")},
+		"NotFound":           {Description: "The resource does not exist"},
+		"Conflict":           {Description: "The resource was changed by another request, reload it and try again", Content: ref("Error")},
+		"PreconditionFailed": {Description: "The resource changed since the If-Match ETag, the data is its current state", Content: ref("Error")},
+		"TooManyRequests":    {Description: "Too many requests, retry after the Retry-After header", Content: ref("Error")},
+		"Unprocessable":      {Description: "The request was understood but could not be completed e.g record not found", Content: ref("Error")},
+	}
+}
+
+// operationID is the method and path in camel case e.g getApiTemplatesId
+func operationID(op Operation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.Method))
+	for _, word := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+func firstKey(m map[string][]string) string {
+	for k := range m {
+		return k
+	}
+	return ""
+}
@@ -0,0 +1,185 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Binary is the type of raw request and response bodies e.g files
+type Binary struct{}
+
+var (
+	binaryType     = reflect.TypeOf(Binary{})
+	timeType       = reflect.TypeOf(time.Time{})
+	deletedAtType  = reflect.TypeOf(gorm.DeletedAt{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// enums holds the values of named string types, see RegisterEnum
+var enums = map[reflect.Type][]any{}
+
+// RegisterEnum documents the possible values of the type of values[0]
+func RegisterEnum[T ~string](values ...T) {
+	if len(values) == 0 {
+		return
+	}
+	enum := make([]any, len(values))
+	for i, v := range values {
+		enum[i] = v
+	}
+	enums[reflect.TypeOf(values[0])] = enum
+}
+
+// requestOnlyFields are set by the server from the session, they are left out
+// of the request bodies
+var requestOnlyFields = map[string]bool{
+	"account_id": true,
+}
+
+// generator creates the schemas of Go types, named structs become components
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+func (g *generator) schemaOf(v any) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+// requestSchema is the schema of a request body, without the fields the
+// server sets itself
+func (g *generator) requestSchema(v any) *Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == binaryType {
+		return g.schema(t)
+	}
+	return g.component(t, true)
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	s := g.build(t)
+	if nullable && s.Ref == "" {
+		s.Nullable = true
+	}
+	return s
+}
+
+func (g *generator) build(t reflect.Type) *Schema {
+	switch t {
+	case binaryType:
+		return &Schema{Type: "string", Format: "binary"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case rawMessageType:
+		return &Schema{}
+	}
+	if enum, ok := enums[t]; ok {
+		return &Schema{Type: "string", Enum: enum}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &Schema{Type: "object", AdditionalProperties: true}
+		}
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.component(t, false)
+	}
+	return &Schema{}
+}
+
+// component references the schema of a named struct, anonymous and generic
+// structs are inlined
+func (g *generator) component(t reflect.Type, request bool) *Schema {
+	if t.Name() == "" || strings.Contains(t.Name(), "[") {
+		return g.object(t, request)
+	}
+	if name, ok := g.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := g.schemas[name]; taken {
+		// e.g shared.Device and entity.Device
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+	g.schemas[name] = &Schema{} // reserved for recursive types
+	*g.schemas[name] = *g.object(t, request)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object lists the properties of a struct the way encoding/json marshals it
+func (g *generator) object(t reflect.Type, request bool) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for key, value := range g.object(embedded, request).Properties {
+					s.Properties[key] = value
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if request && requestOnlyFields[name] {
+			continue
+		}
+		s.Properties[name] = g.schema(field.Type)
+	}
+	return s
+}
@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"context"
+
 	"template-manager/internal/entity"
 	"template-manager/pkg/database"
+
+	"gorm.io/gorm"
 )
 
 type Container struct {
@@ -10,13 +14,46 @@ type Container struct {
 	KeyRepository        KeyRepositoryInterface[entity.Key]
 	TemplateRepository   TemplateRepositoryInterface[entity.Template]
 	CredentialRepository CredentialRepositoryInterface[entity.Credential]
+	ReviewRepository     TemplateReviewRepositoryInterface[entity.TemplateReview]
+	ScheduleRepository   ScheduleRepositoryInterface[entity.TemplateSchedule]
+	WebhookRepository    WebhookRepositoryInterface[entity.Webhook]
+	DeliveryRepository   WebhookDeliveryRepositoryInterface[entity.WebhookDelivery]
+	CaptureRepository    CapturedMessageRepositoryInterface[entity.CapturedMessage]
+	TokenRepository      AccountTokenRepositoryInterface[entity.AccountToken]
+
+	db *gorm.DB
 }
 
 func NewRepositoryContainer(db *database.PostgresClient) Container {
+	return newContainer(db.Client)
+}
+
+func newContainer(db *gorm.DB) Container {
 	return Container{
-		AuthRepository:       NewRepository[entity.Account](db.Client.Table(entity.Account{}.TableName())),
-		KeyRepository:        NewRepository[entity.Key](db.Client.Table(entity.Key{}.TableName())),
-		TemplateRepository:   NewRepository[entity.Template](db.Client.Table(entity.Template{}.TableName())),
-		CredentialRepository: NewRepository[entity.Credential](db.Client.Table(entity.Credential{}.TableName())),
+		AuthRepository:       NewRepository[entity.Account](db.Table(entity.Account{}.TableName())),
+		KeyRepository:        NewRepository[entity.Key](db.Table(entity.Key{}.TableName())),
+		TemplateRepository:   NewRepository[entity.Template](db.Table(entity.Template{}.TableName())),
+		CredentialRepository: NewRepository[entity.Credential](db.Table(entity.Credential{}.TableName())),
+		ReviewRepository:     NewRepository[entity.TemplateReview](db.Table(entity.TemplateReview{}.TableName())),
+		ScheduleRepository:   NewRepository[entity.TemplateSchedule](db.Table(entity.TemplateSchedule{}.TableName())),
+		WebhookRepository:    NewRepository[entity.Webhook](db.Table(entity.Webhook{}.TableName())),
+		DeliveryRepository:   NewRepository[entity.WebhookDelivery](db.Table(entity.WebhookDelivery{}.TableName())),
+		CaptureRepository:    NewRepository[entity.CapturedMessage](db.Table(entity.CapturedMessage{}.TableName())),
+		TokenRepository:      NewRepository[entity.AccountToken](db.Table(entity.AccountToken{}.TableName())),
+
+		db: db,
+	}
+}
+
+// Transaction runs fn with repositories that share one database transaction,
+// it's committed when fn returns nil. A container assembled without a database,
+// like the in-memory ones of tests, has nothing to open a transaction on and
+// passes itself to fn.
+func (c Container) Transaction(ctx context.Context, fn func(tx Container) error) error {
+	if c.db == nil {
+		return fn(c)
 	}
+	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(newContainer(tx))
+	})
 }
@@ -17,7 +17,9 @@ type AccountRepositoryInterface[T entity.Account] interface {
 type KeyRepositoryInterface[T entity.Key] interface {
 	Create(ctx context.Context, t *T) error
 	Find(ctx context.Context, conds ...interface{}) ([]T, error)
+	Get(ctx context.Context, conds ...interface{}) (*T, error)
 	FindManyWithOptions(ctx context.Context, query any, opts ...Opt) ([]T, error)
+	UpdateWhere(ctx context.Context, query any, data any) (int64, error)
 	Delete(ctx context.Context, t *T) error
 }
 
@@ -28,6 +30,42 @@ type TemplateRepositoryInterface[T entity.Template] interface {
 	Update(ctx context.Context, E *T) error
 	Delete(ctx context.Context, t *T) error
 	FindWithPagination(ctx context.Context, query any, opts ...Opt) (*util.PaginationT[[]T], error)
+	FindManyWithOptions(ctx context.Context, query any, opts ...Opt) ([]T, error)
+	UpdateMany(ctx context.Context, query any, data any) error
+	UpdateWhere(ctx context.Context, query any, data any) (int64, error)
+	DeleteByFieldName(ctx context.Context, query any) error
+	Restore(ctx context.Context, query any) (int64, error)
+	Purge(ctx context.Context, query any) (int64, error)
+}
+
+type TemplateReviewRepositoryInterface[T entity.TemplateReview] interface {
+	Create(ctx context.Context, t *T) error
+	Find(ctx context.Context, conds ...interface{}) ([]T, error)
+	FindManyWithOptions(ctx context.Context, query any, opts ...Opt) ([]T, error)
+}
+
+type ScheduleRepositoryInterface[T entity.TemplateSchedule] interface {
+	Create(ctx context.Context, t *T) error
+	Find(ctx context.Context, conds ...interface{}) ([]T, error)
+	Get(ctx context.Context, conds ...interface{}) (*T, error)
+	FindManyWithOptions(ctx context.Context, query any, opts ...Opt) ([]T, error)
+	UpdateWhere(ctx context.Context, query any, data any) (int64, error)
+}
+
+type WebhookRepositoryInterface[T entity.Webhook] interface {
+	Create(ctx context.Context, t *T) error
+	Find(ctx context.Context, conds ...interface{}) ([]T, error)
+	Get(ctx context.Context, conds ...interface{}) (*T, error)
+	UpdateWhere(ctx context.Context, query any, data any) (int64, error)
+	Delete(ctx context.Context, t *T) error
+}
+
+type WebhookDeliveryRepositoryInterface[T entity.WebhookDelivery] interface {
+	Create(ctx context.Context, t *T) error
+	Get(ctx context.Context, conds ...interface{}) (*T, error)
+	FindManyWithOptions(ctx context.Context, query any, opts ...Opt) ([]T, error)
+	FindWithPagination(ctx context.Context, query any, opts ...Opt) (*util.PaginationT[[]T], error)
+	UpdateWhere(ctx context.Context, query any, data any) (int64, error)
 }
 
 type CredentialRepositoryInterface[T entity.Credential] interface {
@@ -38,3 +76,17 @@ type CredentialRepositoryInterface[T entity.Credential] interface {
 	Delete(ctx context.Context, t *T) error
 	FindWithPagination(ctx context.Context, query any, opts ...Opt) (*util.PaginationT[[]T], error)
 }
+
+type CapturedMessageRepositoryInterface[T entity.CapturedMessage] interface {
+	Create(ctx context.Context, t *T) error
+	Get(ctx context.Context, conds ...interface{}) (*T, error)
+	FindWithPagination(ctx context.Context, query any, opts ...Opt) (*util.PaginationT[[]T], error)
+	DeleteByFieldName(ctx context.Context, query any) error
+}
+
+type AccountTokenRepositoryInterface[T entity.AccountToken] interface {
+	Create(ctx context.Context, t *T) error
+	Get(ctx context.Context, conds ...interface{}) (*T, error)
+	FindManyWithOptions(ctx context.Context, query any, opts ...Opt) ([]T, error)
+	UpdateWhere(ctx context.Context, query any, data any) (int64, error)
+}
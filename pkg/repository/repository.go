@@ -27,6 +27,9 @@ type Repository[T any] interface {
 	Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error
 	Update(ctx context.Context, E *T) error
 	UpdateMany(ctx context.Context, query any, data any) error
+	UpdateWhere(ctx context.Context, query any, data any) (int64, error)
+	Restore(ctx context.Context, query any) (int64, error)
+	Purge(ctx context.Context, query any) (int64, error)
 }
 
 type repository[T any] struct {
@@ -34,7 +37,9 @@ type repository[T any] struct {
 }
 
 func NewRepository[T any](db *gorm.DB) *repository[T] {
-	return &repository[T]{db: db}
+	// a session makes every chained call start from a copy of the statement,
+	// otherwise conditions (e.g. Unscoped) would leak into the next query
+	return &repository[T]{db: db.Session(&gorm.Session{})}
 }
 
 var _ Repository[any] = (*repository[any])(nil)
@@ -84,12 +89,17 @@ type findManyOptions struct {
 	Preloads []string
 	OrderBy  string
 	Order    string
+	Deleted  bool
+	Lock     bool
 }
 
 // applyFilter applies the filter to the query
 //
 //	applyFilter(db).Find(&result)
 func (f findManyOptions) applyFilter(tx *gorm.DB) *gorm.DB {
+	if f.Deleted {
+		tx = tx.Unscoped()
+	}
 	if f.isPaginationSet() {
 		tx = tx.Limit(f.PageSize).Offset((f.Page - 1) * f.PageSize)
 	}
@@ -102,6 +112,9 @@ func (f findManyOptions) applyFilter(tx *gorm.DB) *gorm.DB {
 			tx = tx.Preload(preload)
 		}
 	}
+	if f.Lock {
+		tx = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
 	return tx
 }
 
@@ -148,6 +161,25 @@ func WithOrderBy(orderBy string, order string) Opt {
 	}
 }
 
+// WithDeleted includes soft deleted rows in the result
+//
+//	FindWithPagination(ctx, util.Query{Query: "deleted_at IS NOT NULL"}, WithDeleted())
+func WithDeleted() Opt {
+	return func(o *findManyOptions) {
+		o.Deleted = true
+	}
+}
+
+// WithLock locks the rows found until the end of the transaction, concurrent
+// transactions locking them wait for it. Only useful in Container.Transaction.
+//
+//	FindManyWithOptions(ctx, query, WithLock())
+func WithLock() Opt {
+	return func(o *findManyOptions) {
+		o.Lock = true
+	}
+}
+
 type createOptions struct {
 	clause []clause.Expression
 }
@@ -205,7 +237,11 @@ func (r repository[T]) FindWithPagination(ctx context.Context, query any, opts .
 		pgnOpts = append(pgnOpts, util.WithPreload(opt.Preloads...))
 	}
 
-	pgn := util.NewPaginatorT[T](r.db)
+	db := r.db
+	if opt.Deleted {
+		db = db.Unscoped()
+	}
+	pgn := util.NewPaginatorT[T](db)
 	var result util.PaginationT[[]T]
 	if q, ok := query.(util.Query); ok {
 		pgn = pgn.Where(q.Query, q.Args...)
@@ -222,7 +258,7 @@ func (r repository[T]) FindWithPagination(ctx context.Context, query any, opts .
 }
 
 func (r repository[T]) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
-	return r.db.Transaction(fn)
+	return r.db.WithContext(ctx).Transaction(fn)
 }
 
 func (r repository[T]) FindMany(ctx context.Context, query any, page, pageSize int, preloads ...string) ([]T, error) {
@@ -331,13 +367,61 @@ func (r *repository[T]) CreateMany(ctx context.Context, data []T, opts ...Create
 
 func (r *repository[T]) UpdateMany(ctx context.Context, query any, data any) error {
 	var a T
-	err := r.db.Model(a).Where(query).Updates(data).Error
+	db := r.db.WithContext(ctx).Model(a)
+	if q, ok := query.(util.Query); ok {
+		db = db.Where(q.Query, q.Args...)
+	} else {
+		db = db.Where(query)
+	}
+	err := db.Updates(data).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// UpdateWhere is UpdateMany that also reports how many rows were changed,
+// use it for compare-and-swap style updates
+//
+//	UpdateWhere(ctx, util.Query{Query: "id = ? AND status = ?", Args: []any{id, "pending"}}, map[string]any{"status": "running"})
+func (r *repository[T]) UpdateWhere(ctx context.Context, query any, data any) (int64, error) {
+	var a T
+	db := r.db.WithContext(ctx).Model(&a)
+	if q, ok := query.(util.Query); ok {
+		db = db.Where(q.Query, q.Args...)
+	} else {
+		db = db.Where(query)
+	}
+	result := db.Updates(data)
+	return result.RowsAffected, result.Error
+}
+
+// Restore clears deleted_at on the soft deleted rows matching query
+func (r *repository[T]) Restore(ctx context.Context, query any) (int64, error) {
+	var a T
+	db := r.db.WithContext(ctx).Unscoped().Model(&a)
+	if q, ok := query.(util.Query); ok {
+		db = db.Where(q.Query, q.Args...)
+	} else {
+		db = db.Where(query)
+	}
+	result := db.Update("deleted_at", nil)
+	return result.RowsAffected, result.Error
+}
+
+// Purge permanently deletes the rows matching query, including soft deleted ones
+func (r *repository[T]) Purge(ctx context.Context, query any) (int64, error) {
+	var a T
+	db := r.db.WithContext(ctx).Unscoped()
+	if q, ok := query.(util.Query); ok {
+		db = db.Where(q.Query, q.Args...)
+	} else {
+		db = db.Where(query)
+	}
+	result := db.Delete(&a)
+	return result.RowsAffected, result.Error
+}
+
 func (r *repository[T]) DeleteByFieldName(ctx context.Context, query any) error {
 	var a T
 	db := r.db
@@ -0,0 +1,175 @@
+package templatedir
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"time"
+
+	"template-manager/internal/entity"
+	"template-manager/internal/shared"
+)
+
+// FromBundle returns the latest version of every template of a bundle made
+// by the export endpoint. Dir is left empty for the caller to choose.
+func FromBundle(bundle []byte) ([]Template, error) {
+	archive, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		return nil, err
+	}
+	var manifest shared.BundleManifest
+	if err := readJSON(archive, shared.BundleManifestFile, &manifest); err != nil {
+		return nil, err
+	}
+
+	templates := make([]Template, 0, len(manifest.Templates))
+	for _, bundled := range manifest.Templates {
+		if len(bundled.Versions) == 0 {
+			continue
+		}
+		latest := bundled.Versions[0]
+		for _, version := range bundled.Versions[1:] {
+			if version.Version > latest.Version {
+				latest = version
+			}
+		}
+		body, err := readBundleFile(archive, latest.ContentFile)
+		if err != nil {
+			return nil, err
+		}
+		template := Template{
+			Key:         bundled.Key,
+			Name:        latest.Name,
+			Version:     latest.Version,
+			ContentType: latest.ContentType,
+			Engine:      latest.Engine,
+			Vars:        latest.Vars,
+			Body:        body,
+		}
+		for _, block := range latest.Blocks {
+			template.Blocks = append(template.Blocks, Block{Name: block.Name, Condition: block.Condition, Content: block.Content})
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// ToBundle makes a bundle the import endpoint accepts, every template is
+// a single version
+func ToBundle(templates []Template) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	manifest := shared.BundleManifest{
+		FormatVersion: shared.BundleFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		Templates:     make([]shared.BundleTemplate, 0, len(templates)),
+	}
+	for _, template := range templates {
+		version := template.bundleVersion()
+		file, err := archive.Create(version.ContentFile)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(template.Body); err != nil {
+			return nil, err
+		}
+		manifest.Templates = append(manifest.Templates, shared.BundleTemplate{
+			Key:      template.Key,
+			Versions: []shared.BundleVersion{version},
+		})
+	}
+
+	file, err := archive.Create(shared.BundleManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(file).Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t Template) bundleVersion() shared.BundleVersion {
+	blocks := make(entity.ContentBlocks, 0, len(t.Blocks))
+	for _, block := range t.Blocks {
+		blocks = append(blocks, entity.ContentBlock{Name: block.Name, Condition: block.Condition, Content: block.Content})
+	}
+	return shared.BundleVersion{
+		Version:     1, // only used when the key is new to the account
+		Name:        t.Name,
+		ContentType: t.ContentType,
+		Vars:        t.Vars,
+		Blocks:      blocks,
+		ContentFile: path.Join("content", shared.Slugify(t.Key)+Extension(t.ContentType)),
+		ContentHash: hash(t.Body),
+		ContentSize: int64(len(t.Body)),
+	}
+}
+
+// Changed reports whether pushing local would change remote, the template
+// with the same key in the manager. Names are not compared, the manager
+// renames versions.
+func Changed(local, remote Template) bool {
+	if local.ContentType != remote.ContentType || hash(local.Body) != hash(remote.Body) {
+		return true
+	}
+	if len(local.Blocks) != len(remote.Blocks) {
+		return true
+	}
+	for i := range local.Blocks {
+		l, r := local.Blocks[i], remote.Blocks[i]
+		if l.Name != r.Name || l.Condition != r.Condition || l.Content != r.Content {
+			return true
+		}
+	}
+	return !sameVars(local.Vars, remote.Vars)
+}
+
+// sameVars compares vars the way they are stored, yaml and json decode numbers differently
+func sameVars(a, b map[string]any) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	var normalized [2]any
+	for i, vars := range []map[string]any{a, b} {
+		raw, err := json.Marshal(vars)
+		if err != nil {
+			return false
+		}
+		if err := json.Unmarshal(raw, &normalized[i]); err != nil {
+			return false
+		}
+	}
+	return reflect.DeepEqual(normalized[0], normalized[1])
+}
+
+func readJSON(archive *zip.Reader, name string, v any) error {
+	raw, err := readBundleFile(archive, name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func readBundleFile(archive *zip.Reader, name string) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("bundle: %w", err)
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+package templatedir
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"template-manager/internal/shared"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	plain := Template{Key: "reset", Name: "Reset", ContentType: "text/plain", Body: []byte("Reset your password")}
+	bundle, err := ToBundle([]Template{welcome(), plain})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := FromBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 2 {
+		t.Fatalf("FromBundle() returned %d templates, want 2", len(templates))
+	}
+	for i, want := range []Template{welcome(), plain} {
+		// the directory is chosen by the caller
+		want.Dir = ""
+		if got := templates[i]; got.Version != 1 || Changed(want, got) || got.Key != want.Key || got.Name != want.Name {
+			t.Errorf("template %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestFromBundleTakesLatestVersion(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	bundled := shared.BundleTemplate{Key: "welcome"}
+	for _, version := range []uint64{2, 3, 1} {
+		name := fmt.Sprintf("content/welcome/v%d.txt", version)
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+		bundled.Versions = append(bundled.Versions, shared.BundleVersion{Version: version, ContentType: "text/plain", ContentFile: name})
+	}
+	file, err := archive.Create(shared.BundleManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(file).Encode(shared.BundleManifest{Templates: []shared.BundleTemplate{bundled}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := FromBundle(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates[0].Version != 3 || string(templates[0].Body) != "content/welcome/v3.txt" {
+		t.Errorf("FromBundle() = %+v, want version 3", templates)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	change := func(f func(*Template)) Template {
+		template := welcome()
+		f(&template)
+		return template
+	}
+	tests := []struct {
+		name   string
+		remote Template
+		want   bool
+	}{
+		{"same", welcome(), false},
+		{"renamed", change(func(t *Template) { t.Name = "Hello" }), false},
+		{"body", change(func(t *Template) { t.Body = []byte("<p>Hi</p>") }), true},
+		{"content type", change(func(t *Template) { t.ContentType = "text/plain" }), true},
+		{"block content", change(func(t *Template) { t.Blocks[0].Content = "<p>Hi</p>" }), true},
+		{"block condition", change(func(t *Template) { t.Blocks[0].Condition = "" }), true},
+		{"block removed", change(func(t *Template) { t.Blocks = nil }), true},
+		{"var value", change(func(t *Template) { t.Vars = map[string]any{"name": "stranger"} }), true},
+		{"var removed", change(func(t *Template) { t.Vars = nil }), true},
+	}
+	for _, tt := range tests {
+		if got := Changed(welcome(), tt.remote); got != tt.want {
+			t.Errorf("Changed() with %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSameVars(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]any
+		want bool
+	}{
+		{"nil and empty", nil, map[string]any{}, true},
+		{"yaml and json numbers", map[string]any{"count": 3}, map[string]any{"count": float64(3)}, true},
+		{"nested", map[string]any{"user": map[string]any{"age": 30}}, map[string]any{"user": map[string]any{"age": 30.0}}, true},
+		{"different numbers", map[string]any{"count": 3}, map[string]any{"count": 4}, false},
+		{"extra var", map[string]any{"a": "x"}, map[string]any{"a": "x", "b": "y"}, false},
+	}
+	for _, tt := range tests {
+		if got := sameVars(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameVars() with %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package templatedir reads and writes templates as plain files so they can be
+// kept and reviewed in git. Every template is a directory:
+//
+//	welcome-email/
+//	  template.yaml     key, name, content type, vars and blocks
+//	  content.html      the body
+//	  blocks/
+//	    nigeria.html    content of the block named nigeria
+//
+// Only the latest version is kept on disk, history is what git is for. The key
+// in template.yaml is the slug of the template in the manager, the directory
+// can be renamed freely. New templates may leave the key out, the name of the
+// directory is used instead.
+package templatedir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"template-manager/internal/shared"
+)
+
+// MetadataFile is the file that marks a directory as a template
+const MetadataFile = "template.yaml"
+
+// Template is the template.yaml of a template directory and the files it refers to
+type Template struct {
+	Dir string `yaml:"-"` // relative to the root the template was read from
+
+	Key         string         `yaml:"key"`
+	Name        string         `yaml:"name"`
+	Version     uint64         `yaml:"version,omitempty"` // the version last pulled, informational
+	ContentType string         `yaml:"content_type"`
+	Engine      string         `yaml:"engine,omitempty"` // set by pull, informational
+	Content     string         `yaml:"content"`          // relative to the template directory
+	Vars        map[string]any `yaml:"vars,omitempty"`
+	Blocks      []Block        `yaml:"blocks,omitempty"`
+
+	Body []byte `yaml:"-"` // what is in Content
+}
+
+type Block struct {
+	Name      string `yaml:"name"`
+	Condition string `yaml:"condition,omitempty"`
+	File      string `yaml:"file"` // relative to the template directory
+
+	Content string `yaml:"-"`
+}
+
+// Read returns the templates in the directories under root, sorted by directory
+func Read(root string) ([]Template, error) {
+	var templates []Template
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || d.Name() != MetadataFile {
+			return nil
+		}
+		dir, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		template, err := readTemplate(root, dir)
+		if err != nil {
+			return err
+		}
+		templates = append(templates, *template)
+		return filepath.SkipDir // templates do not nest
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Dir < templates[j].Dir })
+
+	keys := make(map[string]string, len(templates))
+	for _, template := range templates {
+		if other, ok := keys[template.Key]; ok {
+			return nil, fmt.Errorf("%s and %s have the same key %q", other, template.Dir, template.Key)
+		}
+		keys[template.Key] = template.Dir
+	}
+	return templates, nil
+}
+
+func readTemplate(root, dir string) (*Template, error) {
+	raw, err := os.ReadFile(filepath.Join(root, dir, MetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var template Template
+	if err := yaml.Unmarshal(raw, &template); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, MetadataFile), err)
+	}
+	template.Dir = filepath.ToSlash(dir)
+	if template.Key == "" {
+		template.Key = filepath.Base(dir)
+	}
+	if template.Name == "" {
+		template.Name = filepath.Base(dir)
+	}
+	if template.Content == "" {
+		return nil, fmt.Errorf("%s: content is required", filepath.Join(dir, MetadataFile))
+	}
+	if template.ContentType == "" {
+		template.ContentType = contentType(template.Content)
+	}
+
+	if template.Body, err = readFile(root, dir, template.Content); err != nil {
+		return nil, err
+	}
+	for i, block := range template.Blocks {
+		if block.Name == "" || block.File == "" {
+			return nil, fmt.Errorf("%s: blocks need a name and a file", filepath.Join(dir, MetadataFile))
+		}
+		content, err := readFile(root, dir, block.File)
+		if err != nil {
+			return nil, err
+		}
+		template.Blocks[i].Content = string(content)
+	}
+	return &template, nil
+}
+
+// readFile reads a file of a template, files outside of the template directory are rejected
+func readFile(root, dir, name string) ([]byte, error) {
+	if !filepath.IsLocal(name) {
+		return nil, fmt.Errorf("%s: %s is outside of the template directory", dir, name)
+	}
+	return os.ReadFile(filepath.Join(root, dir, name))
+}
+
+// Write writes a template into root/template.Dir, files of blocks that are no
+// longer in the template are removed
+func Write(root string, template Template) error {
+	if template.Dir == "" || !filepath.IsLocal(filepath.FromSlash(template.Dir)) {
+		return fmt.Errorf("invalid template directory %q", template.Dir)
+	}
+	dir := filepath.Join(root, filepath.FromSlash(template.Dir))
+	if err := os.RemoveAll(filepath.Join(dir, "blocks")); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	if template.Content == "" {
+		template.Content = "content" + Extension(template.ContentType)
+	}
+	if err := writeFile(dir, template.Content, template.Body); err != nil {
+		return err
+	}
+	for i, block := range template.Blocks {
+		if block.File == "" {
+			block.File = "blocks/" + shared.Slugify(block.Name) + Extension(template.ContentType)
+			template.Blocks[i].File = block.File
+		}
+		if err := writeFile(dir, block.File, []byte(block.Content)); err != nil {
+			return err
+		}
+	}
+
+	raw, err := yaml.Marshal(template)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, MetadataFile), raw, 0o644)
+}
+
+func writeFile(dir, name string, content []byte) error {
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("%s is outside of the template directory", name)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// Extension is the extension of content files of a content type
+func Extension(contentType string) string {
+	if strings.HasPrefix(contentType, "text/html") {
+		return ".html"
+	}
+	return ".txt"
+}
+
+func contentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".html", ".htm":
+		return "text/html"
+	default:
+		return "text/plain"
+	}
+}
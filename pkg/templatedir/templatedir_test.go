@@ -0,0 +1,161 @@
+package templatedir
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func welcome() Template {
+	return Template{
+		Dir:         "emails/welcome",
+		Key:         "welcome-email",
+		Name:        "Welcome",
+		ContentType: "text/html",
+		Vars:        map[string]any{"name": "friend"},
+		Blocks:      []Block{{Name: "Nigeria", Condition: `country == "NG"`, Content: "<p>Hi from Lagos</p>"}},
+		Body:        []byte("<p>Hello {{.name}}</p>"),
+	}
+}
+
+// writeFiles writes files, named by their path under root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	root := t.TempDir()
+	if err := Write(root, welcome()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "emails", "welcome", "blocks", "nigeria.html")); err != nil {
+		t.Errorf("block file: %v", err)
+	}
+
+	templates, err := Read(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 {
+		t.Fatalf("read %d templates, want 1", len(templates))
+	}
+	got, want := templates[0], welcome()
+	want.Content = "content.html"
+	want.Blocks[0].File = "blocks/nigeria.html"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestWriteRemovesOldBlocks(t *testing.T) {
+	root := t.TempDir()
+	template := welcome()
+	if err := Write(root, template); err != nil {
+		t.Fatal(err)
+	}
+	template.Blocks = nil
+	if err := Write(root, template); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "emails", "welcome", "blocks")); !os.IsNotExist(err) {
+		t.Errorf("the files of removed blocks are still there: %v", err)
+	}
+}
+
+func TestReadDefaults(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"reset/template.yaml":      "content: body.htm\n",
+		"reset/body.htm":           "<p>Reset</p>",
+		"order/template.yaml":      "key: order-confirmation\ncontent: content.txt\n",
+		"order/content.txt":        "Thanks",
+		".git/hooks/template.yaml": "content: missing.txt\n",
+	})
+
+	templates, err := Read(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, template := range templates {
+		got = append(got, template.Dir+" "+template.Key+" "+template.Name+" "+template.ContentType)
+	}
+	want := []string{
+		"order order-confirmation order text/plain",
+		"reset reset reset text/html",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %q, want %q", got, want)
+	}
+}
+
+func TestReadRejectsDuplicateKeys(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a/template.yaml": "key: welcome\ncontent: content.txt\n",
+		"a/content.txt":   "a",
+		"b/template.yaml": "key: welcome\ncontent: content.txt\n",
+		"b/content.txt":   "b",
+	})
+	if _, err := Read(root); err == nil || !strings.Contains(err.Error(), "same key") {
+		t.Errorf("Read() err = %v, want the duplicate key", err)
+	}
+}
+
+func TestReadRejectsFilesOutsideTemplate(t *testing.T) {
+	tests := map[string]string{
+		"parent content": "content: ../secret.txt\n",
+		"absolute path":  "content: " + filepath.Join(os.TempDir(), "secret.txt") + "\n",
+		"parent block":   "content: content.txt\nblocks:\n  - name: b\n    file: ../../secret.txt\n",
+	}
+	for name, metadata := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{
+				"secret.txt":               "secret",
+				"welcome/template.yaml":    metadata,
+				"welcome/content.txt":      "hello",
+				"welcome/blocks/block.txt": "block",
+			})
+			_, err := Read(filepath.Join(root, "welcome"))
+			if err == nil || !strings.Contains(err.Error(), "outside of the template directory") {
+				t.Errorf("Read() err = %v, want the file rejected", err)
+			}
+		})
+	}
+}
+
+func TestWriteRejectsPathsOutsideTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template Template
+		outside  string // where the file would end up, relative to the parent of root
+	}{
+		{"no directory", Template{ContentType: "text/plain"}, "templates/template.yaml"},
+		{"parent directory", Template{Dir: "../welcome", ContentType: "text/plain"}, "welcome"},
+		{"parent content", Template{Dir: "welcome", Content: "../content.txt", ContentType: "text/plain"}, "templates/content.txt"},
+		{"parent block", Template{Dir: "welcome", ContentType: "text/plain", Blocks: []Block{{Name: "b", File: "../b.txt"}}}, "templates/b.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			if err := Write(filepath.Join(parent, "templates"), tt.template); err == nil {
+				t.Error("Write() succeeded")
+			}
+			if _, err := os.Stat(filepath.Join(parent, filepath.FromSlash(tt.outside))); err == nil {
+				t.Errorf("Write() created %s", tt.outside)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"template-manager/pkg/uploader"
+)
+
+const (
+	dataExt = ".data"
+	metaExt = ".json"
+)
+
+// Local keeps files on the filesystem. Every file is a directory under Root
+// holding one data and one metadata file per version, the newest version is
+// the current content.
+type Local struct {
+	uploader.Signer
+	Root string
+}
+
+func New(root, baseURL string, key []byte) (*Local, error) {
+	if len(key) == 0 {
+		return nil, errors.New("a signing key is required for the local storage")
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+	return &Local{
+		Signer: uploader.Signer{BaseURL: baseURL, Key: key},
+		Root:   root,
+	}, nil
+}
+
+func (l *Local) UploadFile(ctx context.Context, filename string, buf *bytes.Buffer) (*uploader.UploadOutput, error) {
+	info, err := l.PutFile(ctx, filename, http.DetectContentType(buf.Bytes()), buf)
+	if err != nil {
+		return nil, err
+	}
+	return &uploader.UploadOutput{
+		URL:     l.GetPublicURl(filename),
+		Version: info.Version,
+		Key:     filename,
+	}, nil
+}
+
+// PutFile stores r as the newest version of filename
+func (l *Local) PutFile(ctx context.Context, filename, contentType string, r io.Reader) (*uploader.FileInfo, error) {
+	dir, err := l.dir(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	version := fmt.Sprintf("%020d", now.UnixNano()) // zero padded so versions sort as strings
+	info := uploader.FileInfo{
+		Key:          filename,
+		Size:         size,
+		ContentType:  contentType,
+		ETag:         `"` + hex.EncodeToString(hash.Sum(nil)) + `"`,
+		Version:      version,
+		LastModified: now,
+	}
+	meta, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, version+metaExt), meta, 0o640); err != nil {
+		return nil, err
+	}
+	// the data file is moved in last, a version only exists once it is there
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, version+dataExt)); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (l *Local) UploadPresignedURL(ctx context.Context, filename string, contentType string, expiry time.Duration) (string, error) {
+	if _, err := uploader.CleanFilename(filename); err != nil {
+		return "", err
+	}
+	return l.SignedURL(http.MethodPut, filename, contentType, expiry), nil
+}
+
+func (l *Local) DownloadPresignedURL(ctx context.Context, filename string, expiry time.Duration) (string, error) {
+	if _, err := uploader.CleanFilename(filename); err != nil {
+		return "", err
+	}
+	return l.SignedURL(http.MethodGet, filename, "", expiry), nil
+}
+
+func (l *Local) GetFileDetails(ctx context.Context, filename string) (*uploader.FileInfo, error) {
+	versions, err := l.versions(filename)
+	if err != nil {
+		return nil, err
+	}
+	return l.meta(filename, versions[0])
+}
+
+func (l *Local) GetFile(ctx context.Context, filename string) (io.ReadCloser, *uploader.FileInfo, error) {
+	info, err := l.GetFileDetails(ctx, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, err := l.dir(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(filepath.Join(dir, info.Version+dataExt))
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	return file, info, nil
+}
+
+func (l *Local) GetFileVersions(ctx context.Context, filename string) ([]uploader.FileVersion, error) {
+	versions, err := l.versions(filename)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]uploader.FileVersion, 0, len(versions))
+	for i, version := range versions {
+		info, err := l.meta(filename, version)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, uploader.FileVersion{
+			Version:      info.Version,
+			Size:         info.Size,
+			IsLatest:     i == 0,
+			LastModified: info.LastModified,
+		})
+	}
+	return out, nil
+}
+
+// DeleteFile removes every version of filename
+func (l *Local) DeleteFile(ctx context.Context, filename string) error {
+	versions, err := l.versions(filename)
+	if err != nil {
+		return err
+	}
+	dir, err := l.dir(filename)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if err := os.Remove(filepath.Join(dir, version+dataExt)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := os.Remove(filepath.Join(dir, version+metaExt)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	// only succeeds when no other file lives below this one
+	_ = os.Remove(dir)
+	return nil
+}
+
+func (l *Local) GetPublicURl(filename string) string {
+	return l.URL(filename)
+}
+
+func (l *Local) FilenameFromLocation(location string) (string, error) {
+	return l.Filename(location)
+}
+
+func (l *Local) dir(filename string) (string, error) {
+	filename, err := uploader.CleanFilename(filename)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(filename)), nil
+}
+
+// versions returns the versions of filename, newest first
+func (l *Local) versions(filename string) ([]string, error) {
+	dir, err := l.dir(filename)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	var versions []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, dataExt) {
+			continue
+		}
+		version := strings.TrimSuffix(name, dataExt)
+		if _, err := strconv.ParseInt(version, 10, 64); err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, uploader.ErrNotFound
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	return versions, nil
+}
+
+func (l *Local) meta(filename, version string) (*uploader.FileInfo, error) {
+	dir, err := l.dir(filename)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(dir, version+metaExt))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	var info uploader.FileInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return uploader.ErrNotFound
+	}
+	return err
+}
+
+var _ uploader.Served = (*Local)(nil)
@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"template-manager/pkg/uploader"
+)
+
+// maxFileSize keeps a single row well below what is sensible to hold in memory
+const maxFileSize = 16 << 20 // 16MB
+
+var ErrFileTooLarge = errors.New("file is larger than 16MB")
+
+// File is one version of a stored file
+type File struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement;column:id"`
+	Key         string    `gorm:"column:key;not null;index"`
+	Content     []byte    `gorm:"column:content;type:bytea;not null"`
+	ContentType string    `gorm:"column:content_type;not null"`
+	Size        int64     `gorm:"column:size;not null"`
+	ETag        string    `gorm:"column:etag;not null"`
+	CreatedAt   time.Time `gorm:"column:created_at;type:timestamptz"`
+}
+
+func (File) TableName() string {
+	return "storage_files"
+}
+
+func (f File) info() *uploader.FileInfo {
+	return &uploader.FileInfo{
+		Key:          f.Key,
+		Size:         f.Size,
+		ContentType:  f.ContentType,
+		ETag:         f.ETag,
+		Version:      strconv.FormatUint(f.ID, 10),
+		LastModified: f.CreatedAt,
+	}
+}
+
+// Postgres keeps files in the database, every upload adds a row and the
+// newest row of a key is its current content
+type Postgres struct {
+	uploader.Signer
+	db *gorm.DB
+}
+
+func New(db *gorm.DB, baseURL string, key []byte) (*Postgres, error) {
+	if len(key) == 0 {
+		return nil, errors.New("a signing key is required for the postgres storage")
+	}
+	return &Postgres{
+		Signer: uploader.Signer{BaseURL: baseURL, Key: key},
+		db:     db.Table(File{}.TableName()).Session(&gorm.Session{}),
+	}, nil
+}
+
+func (p *Postgres) UploadFile(ctx context.Context, filename string, buf *bytes.Buffer) (*uploader.UploadOutput, error) {
+	info, err := p.PutFile(ctx, filename, http.DetectContentType(buf.Bytes()), buf)
+	if err != nil {
+		return nil, err
+	}
+	return &uploader.UploadOutput{
+		URL:     p.GetPublicURl(filename),
+		Version: info.Version,
+		Key:     filename,
+	}, nil
+}
+
+func (p *Postgres) PutFile(ctx context.Context, filename, contentType string, r io.Reader) (*uploader.FileInfo, error) {
+	filename, err := uploader.CleanFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(io.LimitReader(r, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	sum := sha256.Sum256(content)
+	file := File{
+		Key:         filename,
+		Content:     content,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		ETag:        `"` + hex.EncodeToString(sum[:]) + `"`,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := p.db.WithContext(ctx).Create(&file).Error; err != nil {
+		return nil, err
+	}
+	return file.info(), nil
+}
+
+func (p *Postgres) UploadPresignedURL(ctx context.Context, filename string, contentType string, expiry time.Duration) (string, error) {
+	if _, err := uploader.CleanFilename(filename); err != nil {
+		return "", err
+	}
+	return p.SignedURL(http.MethodPut, filename, contentType, expiry), nil
+}
+
+func (p *Postgres) DownloadPresignedURL(ctx context.Context, filename string, expiry time.Duration) (string, error) {
+	if _, err := uploader.CleanFilename(filename); err != nil {
+		return "", err
+	}
+	return p.SignedURL(http.MethodGet, filename, "", expiry), nil
+}
+
+func (p *Postgres) GetFileDetails(ctx context.Context, filename string) (*uploader.FileInfo, error) {
+	var file File
+	err := p.db.WithContext(ctx).
+		Select("id", "key", "content_type", "size", "etag", "created_at").
+		Where("key = ?", filename).
+		Order("id DESC").
+		Take(&file).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return file.info(), nil
+}
+
+func (p *Postgres) GetFile(ctx context.Context, filename string) (io.ReadCloser, *uploader.FileInfo, error) {
+	var file File
+	if err := p.db.WithContext(ctx).Where("key = ?", filename).Order("id DESC").Take(&file).Error; err != nil {
+		return nil, nil, notFound(err)
+	}
+	return io.NopCloser(bytes.NewReader(file.Content)), file.info(), nil
+}
+
+func (p *Postgres) GetFileVersions(ctx context.Context, filename string) ([]uploader.FileVersion, error) {
+	var files []File
+	err := p.db.WithContext(ctx).
+		Select("id", "size", "created_at").
+		Where("key = ?", filename).
+		Order("id DESC").
+		Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, uploader.ErrNotFound
+	}
+	versions := make([]uploader.FileVersion, 0, len(files))
+	for i, file := range files {
+		versions = append(versions, uploader.FileVersion{
+			Version:      strconv.FormatUint(file.ID, 10),
+			Size:         file.Size,
+			IsLatest:     i == 0,
+			LastModified: file.CreatedAt,
+		})
+	}
+	return versions, nil
+}
+
+// DeleteFile removes every version of filename
+func (p *Postgres) DeleteFile(ctx context.Context, filename string) error {
+	return p.db.WithContext(ctx).Where("key = ?", filename).Delete(&File{}).Error
+}
+
+func (p *Postgres) GetPublicURl(filename string) string {
+	return p.URL(filename)
+}
+
+func (p *Postgres) FilenameFromLocation(location string) (string, error) {
+	return p.Filename(location)
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uploader.ErrNotFound
+	}
+	return err
+}
+
+var _ uploader.Served = (*Postgres)(nil)
@@ -3,15 +3,24 @@ package s3
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"template-manager/pkg/uploader"
 )
 
 type Uploader interface {
@@ -20,7 +29,10 @@ type Uploader interface {
 
 type S3Actions interface {
 	PutObjectRequest(input *s3.PutObjectInput) (*request.Request, *s3.PutObjectOutput)
+	GetObjectRequest(input *s3.GetObjectInput) (*request.Request, *s3.GetObjectOutput)
 	HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, options ...request.Option) (*s3.HeadObjectOutput, error)
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, options ...request.Option) (*s3.GetObjectOutput, error)
+	DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, options ...request.Option) (*s3.DeleteObjectOutput, error)
 	ListObjectVersionsWithContext(ctx aws.Context, input *s3.ListObjectVersionsInput, options ...request.Option) (*s3.ListObjectVersionsOutput, error)
 }
 
@@ -53,48 +65,101 @@ func NewS3(Bucket string, Region string, ContentType string, Folder string) (*S3
 	}, nil
 }
 
-func (s S3) UploadFile(ctx context.Context, filename string, buf *bytes.Buffer) (*s3manager.UploadOutput, error) {
-	// Upload the file to S3.
-	return s.Uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+func (s S3) UploadFile(ctx context.Context, filename string, buf *bytes.Buffer) (*uploader.UploadOutput, error) {
+	contentType := s.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(buf.Bytes())
+	}
+	// Upload the file to S3. Objects stay private, they are read with presigned
+	// URLs or through the content proxy so buckets can block public access.
+	output, err := s.Uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 		Bucket:          aws.String(s.Bucket),
 		ContentLanguage: aws.String("en"),
-		ContentType:     aws.String(s.ContentType),
+		ContentType:     aws.String(contentType),
 		Tagging:         aws.String(fmt.Sprintf("env=%s", s.ENV)),
 		StorageClass:    aws.String("STANDARD"),
-		Key:             aws.String(fmt.Sprintf("%s/%s", s.Folder, filename)),
+		Key:             aws.String(s.key(filename)),
 		Body:            buf,
-		ACL:             aws.String("public-read"),
 	})
+	if err != nil {
+		return nil, err
+	}
+	return &uploader.UploadOutput{
+		URL:     output.Location,
+		Version: aws.StringValue(output.VersionID),
+		Key:     s.key(filename),
+	}, nil
 }
 
 func (s S3) getFolder() string {
-	return fmt.Sprintf("%s/%s", s.ENV, s.Folder)
+	return strings.Trim(path.Join(s.ENV, s.Folder), "/")
 }
 
-func (s S3) UploadPresignedURL(ctx context.Context, filename string, expiry time.Duration) (string, error) {
-	req, _ := s.SVC.PutObjectRequest(&s3.PutObjectInput{
+func (s S3) key(filename string) string {
+	if folder := s.getFolder(); folder != "" {
+		return folder + "/" + filename
+	}
+	return filename
+}
+
+// UploadPresignedURL returns a URL the client can PUT the file to. When a
+// content type is given the upload must be made with the same Content-Type.
+func (s S3) UploadPresignedURL(ctx context.Context, filename string, contentType string, expiry time.Duration) (string, error) {
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(s.Bucket),
-		Key:    aws.String(fmt.Sprintf("%s/%s", s.getFolder(), filename)),
-	})
+		Key:    aws.String(s.key(filename)),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	req, _ := s.SVC.PutObjectRequest(input)
+	req.SetContext(ctx)
+	return req.Presign(expiry)
+}
 
-	// Create a context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), expiry)
-	defer cancel()
+func (s S3) DownloadPresignedURL(ctx context.Context, filename string, expiry time.Duration) (string, error) {
+	req, _ := s.SVC.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(filename)),
+	})
+	req.SetContext(ctx)
+	return req.Presign(expiry)
+}
 
-	// Presign the request using the context
-	urlStr, _, err := req.PresignRequest(expiry)
+func (s S3) GetFileDetails(ctx context.Context, filename string) (*uploader.FileInfo, error) {
+	output, err := s.SVC.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(filename)),
+	})
 	if err != nil {
-		return "", err
+		return nil, notFound(err)
 	}
-
-	return urlStr, nil
+	return &uploader.FileInfo{
+		Key:          s.key(filename),
+		Size:         aws.Int64Value(output.ContentLength),
+		ContentType:  aws.StringValue(output.ContentType),
+		ETag:         aws.StringValue(output.ETag),
+		Version:      aws.StringValue(output.VersionId),
+		LastModified: aws.TimeValue(output.LastModified),
+	}, nil
 }
 
-func (s S3) GetFileDetails(ctx context.Context, filename string) (*s3.HeadObjectOutput, error) {
-	return s.SVC.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+func (s S3) GetFile(ctx context.Context, filename string) (io.ReadCloser, *uploader.FileInfo, error) {
+	output, err := s.SVC.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.Bucket),
-		Key:    aws.String(fmt.Sprintf("%s/%s", s.getFolder(), filename)),
+		Key:    aws.String(s.key(filename)),
 	})
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	return output.Body, &uploader.FileInfo{
+		Key:          s.key(filename),
+		Size:         aws.Int64Value(output.ContentLength),
+		ContentType:  aws.StringValue(output.ContentType),
+		ETag:         aws.StringValue(output.ETag),
+		Version:      aws.StringValue(output.VersionId),
+		LastModified: aws.TimeValue(output.LastModified),
+	}, nil
 }
 
 func (s S3) VerifyFileExists(ctx context.Context, filename string) (bool, error) {
@@ -108,20 +173,101 @@ func (s S3) VerifyFileExists(ctx context.Context, filename string) (bool, error)
 }
 
 func (s S3) GetPublicURl(filename string) string {
-	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s/%s", s.Bucket, s.Region, s.getFolder(), filename)
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.Region, s.key(filename))
 }
 
-func (s S3) GetFileVersions(ctx context.Context, filename string) ([]*s3.ObjectVersion, error) {
+// FilenameFromLocation returns the name of the file a public URL points to,
+// it fails for URLs outside of the bucket or folder of s
+func (s S3) FilenameFromLocation(location string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "https" || u.Host != fmt.Sprintf("%s.s3.%s.amazonaws.com", s.Bucket, s.Region) {
+		return "", uploader.ErrOutsideFolder
+	}
+	prefix := "/" + s.key("")
+	if !strings.HasPrefix(u.Path, prefix) {
+		return "", uploader.ErrOutsideFolder
+	}
+	return uploader.CleanFilename(strings.TrimPrefix(u.Path, prefix))
+}
+
+func (s S3) GetFileVersions(ctx context.Context, filename string) ([]uploader.FileVersion, error) {
 	result, err := s.SVC.ListObjectVersionsWithContext(ctx, &s3.ListObjectVersionsInput{
 		Bucket: aws.String(s.Bucket),
-		Prefix: aws.String(fmt.Sprintf("%s/%s", s.getFolder(), filename)),
+		Prefix: aws.String(s.key(filename)),
 	})
 	if err != nil {
 		return nil, err
 	}
-	if len(result.Versions) == 0 {
+
+	var versions []uploader.FileVersion
+	for _, version := range result.Versions {
+		// the prefix also matches longer keys
+		if aws.StringValue(version.Key) != s.key(filename) {
+			continue
+		}
+		versions = append(versions, uploader.FileVersion{
+			Version:      aws.StringValue(version.VersionId),
+			Size:         aws.Int64Value(version.Size),
+			IsLatest:     aws.BoolValue(version.IsLatest),
+			LastModified: aws.TimeValue(version.LastModified),
+		})
+	}
+	if len(versions) == 0 {
 		return nil, fmt.Errorf("no versions found for %s", filename)
 	}
 
-	return result.Versions, nil
+	return versions, nil
+}
+
+// DeleteFile removes every version of filename, on a versioned bucket a plain
+// delete would only hide the content behind a delete marker
+func (s S3) DeleteFile(ctx context.Context, filename string) error {
+	key := s.key(filename)
+	result, err := s.SVC.ListObjectVersionsWithContext(ctx, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+
+	var versions []*string
+	for _, version := range result.Versions {
+		// the prefix also matches longer keys
+		if aws.StringValue(version.Key) == key {
+			versions = append(versions, version.VersionId)
+		}
+	}
+	for _, marker := range result.DeleteMarkers {
+		if aws.StringValue(marker.Key) == key {
+			versions = append(versions, marker.VersionId)
+		}
+	}
+	if len(versions) == 0 {
+		// nothing listed, delete the key anyway in case the listing lagged
+		versions = append(versions, nil)
+	}
+	for _, version := range versions {
+		if _, err := s.SVC.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+			Bucket:    aws.String(s.Bucket),
+			Key:       aws.String(key),
+			VersionId: version,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notFound(err error) error {
+	var aerr awserr.RequestFailure
+	if errors.As(err, &aerr) && aerr.StatusCode() == http.StatusNotFound {
+		return uploader.ErrNotFound
+	}
+	return err
 }
+
+var _ uploader.Uploader = S3{}
@@ -0,0 +1,97 @@
+package uploader
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServePath is where the service serves the files of drivers that are not
+// reachable on their own (local filesystem, postgres)
+const ServePath = "/storage/"
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrURLExpired       = errors.New("signed url has expired")
+)
+
+// Served is implemented by drivers whose signed URLs point at this service,
+// the handlers under ServePath verify the signature and read or write the file
+type Served interface {
+	Uploader
+	VerifySignature(method, filename string, query url.Values) error
+	PutFile(ctx context.Context, filename, contentType string, r io.Reader) (*FileInfo, error)
+}
+
+// Signer creates and checks the signed URLs of served drivers
+type Signer struct {
+	BaseURL string // public URL of the service, e.g https://templates.example.com
+	Key     []byte
+}
+
+// SignedURL returns a URL allowing method on filename until expiry. A signed
+// content type has to be used as is by the upload.
+func (s Signer) SignedURL(method, filename, contentType string, expiry time.Duration) string {
+	expires := time.Now().Add(expiry).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	if contentType != "" {
+		query.Set("content_type", contentType)
+	}
+	query.Set("signature", s.signature(method, filename, contentType, expires))
+	return s.URL(filename) + "?" + query.Encode()
+}
+
+func (s Signer) VerifySignature(method, filename string, query url.Values) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	expected := s.signature(method, filename, query.Get("content_type"), expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+// URL returns the unsigned location of a file
+func (s Signer) URL(filename string) string {
+	parts := strings.Split(filename, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.TrimRight(s.BaseURL, "/") + ServePath + strings.Join(parts, "/")
+}
+
+// Filename is the reverse of URL
+func (s Signer) Filename(location string) (string, error) {
+	prefix := strings.TrimRight(s.BaseURL, "/") + ServePath
+	if !strings.HasPrefix(location, prefix) {
+		return "", ErrOutsideFolder
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(prefix)
+	if err != nil {
+		return "", err
+	}
+	return CleanFilename(strings.TrimPrefix(u.Path, base.Path))
+}
+
+func (s Signer) signature(method, filename, contentType string, expires int64) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(strings.Join([]string{method, filename, contentType, strconv.FormatInt(expires, 10)}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
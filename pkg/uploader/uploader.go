@@ -3,6 +3,16 @@ package uploader
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("file not found")
+	ErrOutsideFolder = errors.New("location is outside of the upload folder")
 )
 
 // UploadOutput represents a response from the Upload() call.
@@ -12,6 +22,54 @@ type UploadOutput struct {
 	Key     string
 }
 
+// FileInfo describes a stored file
+type FileInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	Version      string
+	LastModified time.Time
+}
+
+// FileVersion is one of the versions kept for a file, newest first
+type FileVersion struct {
+	Version      string
+	Size         int64
+	IsLatest     bool
+	LastModified time.Time
+}
+
+// Uploader stores template content. Filenames are keys relative to the
+// folder of the driver and may contain slashes.
 type Uploader interface {
 	UploadFile(ctx context.Context, filename string, buf *bytes.Buffer) (*UploadOutput, error)
+	// UploadPresignedURL returns a URL the client can PUT the file to with the given Content-Type
+	UploadPresignedURL(ctx context.Context, filename string, contentType string, expiry time.Duration) (string, error)
+	// DownloadPresignedURL returns a URL the file can be read from until it expires
+	DownloadPresignedURL(ctx context.Context, filename string, expiry time.Duration) (string, error)
+	GetFileDetails(ctx context.Context, filename string) (*FileInfo, error)
+	// GetFile returns the content of the file, the caller closes it
+	GetFile(ctx context.Context, filename string) (io.ReadCloser, *FileInfo, error)
+	GetFileVersions(ctx context.Context, filename string) ([]FileVersion, error)
+	DeleteFile(ctx context.Context, filename string) error
+	// GetPublicURl returns the location of a file, it is what templates store
+	GetPublicURl(filename string) string
+	// FilenameFromLocation is the reverse of GetPublicURl, it fails for
+	// locations that do not belong to this storage
+	FilenameFromLocation(location string) (string, error)
+}
+
+// CleanFilename rejects filenames that are empty or would escape the folder
+// they are resolved in
+func CleanFilename(filename string) (string, error) {
+	if filename == "" || strings.HasPrefix(filename, "/") || path.Clean(filename) != filename {
+		return "", ErrOutsideFolder
+	}
+	for _, part := range strings.Split(filename, "/") {
+		if part == ".." || part == "." {
+			return "", ErrOutsideFolder
+		}
+	}
+	return filename, nil
 }